@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"restaurant/model"
+	"time"
+)
+
+// FormatVersion is written into every archive. Restore accepts archives up to
+// this version. Version 2 added the categories, ingredients, price history
+// and menu versions of the product service, version 3 its exchange rates,
+// stock history, sales and menu drafts.
+const FormatVersion = 3
+
+// Catalog is the product service's part of an archive, served by its
+// /admin/export and accepted by its /admin/restore.
+type Catalog struct {
+	Products         []model.Product         `json:"products"`
+	Categories       []model.Category        `json:"categories"`
+	Ingredients      []model.Ingredient      `json:"ingredients"`
+	PriceChanges     []model.PriceChange     `json:"price_changes"`
+	MenuVersions     []model.MenuVersion     `json:"menu_versions"`
+	ExchangeRates    []model.ExchangeRate    `json:"exchange_rates"`
+	StockAdjustments []model.StockAdjustment `json:"stock_adjustments"`
+	Sales            []model.Sale            `json:"sales"`
+	MenuDrafts       []model.DraftChange     `json:"menu_drafts"`
+}
+
+// Len returns the number of records in the catalog.
+func (c Catalog) Len() int {
+	return len(c.Products) + len(c.Categories) + len(c.Ingredients) + len(c.PriceChanges) + len(c.MenuVersions) +
+		len(c.ExchangeRates) + len(c.StockAdjustments) + len(c.Sales) + len(c.MenuDrafts)
+}
+
+// Archive is a point-in-time copy of all service data.
+type Archive struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Users     []model.User `json:"users"`
+	Catalog
+	Orders []model.Order `json:"orders"`
+}
+
+func New(users []model.User, catalog Catalog, orders []model.Order) *Archive {
+	return &Archive{
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Users:     users,
+		Catalog:   catalog,
+		Orders:    orders,
+	}
+}
+
+// Validate checks that the archive can be imported: the version is supported,
+// IDs are unique, and every reference between records points at a record of
+// the same restaurant in the archive. That covers the users and products of
+// orders, the categories, recipe ingredients and bundle components of
+// products, and the products of price changes, stock adjustments, sales and
+// menu drafts. Drafts that create a product reference no existing product.
+// All problems are reported together.
+func (a *Archive) Validate() error {
+	var errs []error
+
+	if a.Version < 1 || a.Version > FormatVersion {
+		errs = append(errs, fmt.Errorf("unsupported archive version %d", a.Version))
+	}
+
+	// The maps hold the restaurant each ID belongs to.
+	userIDs := make(map[int]int, len(a.Users))
+	for _, user := range a.Users {
+		if _, exists := userIDs[user.ID]; exists {
+			errs = append(errs, fmt.Errorf("duplicate user ID %d", user.ID))
+		}
+		userIDs[user.ID] = user.RestaurantID
+	}
+
+	categoryIDs := make(map[int]int, len(a.Categories))
+	for _, category := range a.Categories {
+		if _, exists := categoryIDs[category.ID]; exists {
+			errs = append(errs, fmt.Errorf("duplicate category ID %d", category.ID))
+		}
+		categoryIDs[category.ID] = category.RestaurantID
+	}
+
+	ingredientIDs := make(map[int]int, len(a.Ingredients))
+	for _, ingredient := range a.Ingredients {
+		if _, exists := ingredientIDs[ingredient.ID]; exists {
+			errs = append(errs, fmt.Errorf("duplicate ingredient ID %d", ingredient.ID))
+		}
+		ingredientIDs[ingredient.ID] = ingredient.RestaurantID
+	}
+
+	productIDs := make(map[int]int, len(a.Products))
+	for _, product := range a.Products {
+		if _, exists := productIDs[product.ID]; exists {
+			errs = append(errs, fmt.Errorf("duplicate product ID %d", product.ID))
+		}
+		productIDs[product.ID] = product.RestaurantID
+	}
+
+	// check reports a reference from one record to another that is missing
+	// or belongs to another restaurant.
+	check := func(from string, restaurantID int, kind string, ids map[int]int, id int) {
+		if owner, exists := ids[id]; !exists {
+			errs = append(errs, fmt.Errorf("%s references missing %s %d", from, kind, id))
+		} else if owner != restaurantID {
+			errs = append(errs, fmt.Errorf("%s references %s %d of another restaurant", from, kind, id))
+		}
+	}
+
+	for _, product := range a.Products {
+		from := fmt.Sprintf("product %d", product.ID)
+		for _, categoryID := range product.CategoryIDs {
+			check(from, product.RestaurantID, "category", categoryIDs, categoryID)
+		}
+		for _, item := range product.Recipe {
+			check(from, product.RestaurantID, "ingredient", ingredientIDs, item.IngredientID)
+		}
+		if product.Bundle != nil {
+			for _, slot := range product.Bundle.Slots {
+				for _, componentID := range slot.ProductIDs {
+					check(from, product.RestaurantID, "product", productIDs, componentID)
+				}
+			}
+		}
+	}
+
+	priceChangeIDs := make(map[int]bool, len(a.PriceChanges))
+	for _, change := range a.PriceChanges {
+		if priceChangeIDs[change.ID] {
+			errs = append(errs, fmt.Errorf("duplicate price change ID %d", change.ID))
+		}
+		priceChangeIDs[change.ID] = true
+		check(fmt.Sprintf("price change %d", change.ID), change.RestaurantID, "product", productIDs, change.ProductID)
+	}
+
+	menuVersions := make(map[[2]int]bool, len(a.MenuVersions))
+	for _, version := range a.MenuVersions {
+		key := [2]int{version.RestaurantID, version.Version}
+		if menuVersions[key] {
+			errs = append(errs, fmt.Errorf("duplicate menu version %d of restaurant %d", version.Version, version.RestaurantID))
+		}
+		menuVersions[key] = true
+	}
+
+	type rateKey struct {
+		restaurantID   int
+		base, currency string
+	}
+	rates := make(map[rateKey]bool, len(a.ExchangeRates))
+	for _, rate := range a.ExchangeRates {
+		key := rateKey{rate.RestaurantID, rate.Base, rate.Currency}
+		if rates[key] {
+			errs = append(errs, fmt.Errorf("duplicate exchange rate %s->%s of restaurant %d", rate.Base, rate.Currency, rate.RestaurantID))
+		}
+		rates[key] = true
+	}
+
+	adjustmentIDs := make(map[int]bool, len(a.StockAdjustments))
+	for _, adjustment := range a.StockAdjustments {
+		if adjustmentIDs[adjustment.ID] {
+			errs = append(errs, fmt.Errorf("duplicate stock adjustment ID %d", adjustment.ID))
+		}
+		adjustmentIDs[adjustment.ID] = true
+		check(fmt.Sprintf("stock adjustment %d", adjustment.ID), adjustment.RestaurantID, "product", productIDs, adjustment.ProductID)
+	}
+
+	type saleKey struct {
+		restaurantID int
+		id           string
+	}
+	saleIDs := make(map[saleKey]bool, len(a.Sales))
+	for _, sale := range a.Sales {
+		key := saleKey{sale.RestaurantID, sale.ID}
+		if saleIDs[key] {
+			errs = append(errs, fmt.Errorf("duplicate sale ID %q of restaurant %d", sale.ID, sale.RestaurantID))
+		}
+		saleIDs[key] = true
+
+		from := fmt.Sprintf("sale %q", sale.ID)
+		for _, item := range sale.Items {
+			check(from, sale.RestaurantID, "product", productIDs, item.ProductID)
+		}
+		for _, item := range sale.Ingredients {
+			check(from, sale.RestaurantID, "ingredient", ingredientIDs, item.IngredientID)
+		}
+	}
+
+	drafts := make(map[[2]int]bool, len(a.MenuDrafts))
+	for _, draft := range a.MenuDrafts {
+		key := [2]int{draft.RestaurantID, draft.ProductID}
+		if drafts[key] {
+			errs = append(errs, fmt.Errorf("duplicate menu draft of product %d of restaurant %d", draft.ProductID, draft.RestaurantID))
+		}
+		drafts[key] = true
+		if draft.Action != model.DraftCreate {
+			check(fmt.Sprintf("menu draft of product %d", draft.ProductID), draft.RestaurantID, "product", productIDs, draft.ProductID)
+		}
+	}
+
+	orderIDs := make(map[int]bool, len(a.Orders))
+	for _, order := range a.Orders {
+		if orderIDs[order.ID] {
+			errs = append(errs, fmt.Errorf("duplicate order ID %d", order.ID))
+		}
+		orderIDs[order.ID] = true
+
+		from := fmt.Sprintf("order %d", order.ID)
+		check(from, order.RestaurantID, "user", userIDs, order.UserID)
+		check(from, order.RestaurantID, "product", productIDs, order.ProductID)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Write encodes the archive as gzip-compressed JSON.
+func Write(w io.Writer, a *Archive) error {
+	zw := gzip.NewWriter(w)
+	encoder := json.NewEncoder(zw)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(a); err != nil {
+		zw.Close()
+		return fmt.Errorf("encode archive: %w", err)
+	}
+	return zw.Close()
+}
+
+// Read decodes an archive produced by Write.
+func Read(r io.Reader) (*Archive, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+
+	var archive Archive
+	if err := json.NewDecoder(zr).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("decode archive: %w", err)
+	}
+	return &archive, nil
+}
@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"bytes"
+	"restaurant/model"
+	"restaurant/money"
+	"strings"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	rate, err := money.ParseRate("0.92")
+	if err != nil {
+		t.Fatalf("Error parsing rate: %v", err)
+	}
+	archive := New(
+		[]model.User{{ID: 1, Username: "admin", PasswordHash: "admin123"}},
+		Catalog{
+			Products:         []model.Product{{ID: 1, Name: "Burger", Description: "Delicious beef burger", Price: money.MustParse("15.99", "USD"), CategoryIDs: []int{1}, Recipe: []model.RecipeItem{{IngredientID: 1, Quantity: 1}}}},
+			Categories:       []model.Category{{ID: 1, Name: "Mains"}},
+			Ingredients:      []model.Ingredient{{ID: 1, Name: "Beef patty", Unit: "piece"}},
+			PriceChanges:     []model.PriceChange{{ID: 1, ProductID: 1, NewPrice: money.MustParse("15.99", "USD")}},
+			MenuVersions:     []model.MenuVersion{{Version: 1}},
+			ExchangeRates:    []model.ExchangeRate{{Base: "USD", Currency: "EUR", Rate: rate}},
+			StockAdjustments: []model.StockAdjustment{{ID: 1, ProductID: 1, Change: 10, Reason: model.StockReasonDelivery, StockAfter: 10}},
+			Sales:            []model.Sale{{ID: "order-1", Items: []model.SaleItem{{ProductID: 1, Quantity: 2}}}},
+			MenuDrafts:       []model.DraftChange{{ProductID: 1, Action: model.DraftArchive}},
+		},
+		[]model.Order{{ID: 1, UserID: 1, ProductID: 1, Quantity: 2, TotalPrice: money.MustParse("31.98", "USD")}},
+	)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, archive); err != nil {
+		t.Fatalf("Error writing archive: %v", err)
+	}
+
+	restored, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Error reading archive: %v", err)
+	}
+
+	if restored.Version != FormatVersion {
+		t.Errorf("Expected version %d, but got %d", FormatVersion, restored.Version)
+	}
+	if len(restored.Users) != 1 || len(restored.Products) != 1 || len(restored.Orders) != 1 {
+		t.Errorf("Expected 1 user, product and order, but got %+v", restored)
+	}
+	if len(restored.Categories) != 1 || len(restored.Ingredients) != 1 || len(restored.PriceChanges) != 1 || len(restored.MenuVersions) != 1 {
+		t.Errorf("Expected 1 category, ingredient, price change and menu version, but got %+v", restored.Catalog)
+	}
+	if len(restored.ExchangeRates) != 1 || len(restored.StockAdjustments) != 1 || len(restored.Sales) != 1 || len(restored.MenuDrafts) != 1 {
+		t.Errorf("Expected 1 exchange rate, stock adjustment, sale and menu draft, but got %+v", restored.Catalog)
+	}
+	if len(restored.ExchangeRates) == 1 && restored.ExchangeRates[0].Rate.String() != "0.92" {
+		t.Errorf("Expected rate 0.92, but got %s", restored.ExchangeRates[0].Rate)
+	}
+	if err := restored.Validate(); err != nil {
+		t.Errorf("Expected valid archive, but got %v", err)
+	}
+}
+
+func TestArchiveValidateReferentialIntegrity(t *testing.T) {
+	archive := New(
+		[]model.User{{ID: 1, Username: "admin"}},
+		Catalog{Products: []model.Product{{ID: 1, Name: "Burger"}, {ID: 1, Name: "Pizza"}}},
+		[]model.Order{
+			{ID: 1, UserID: 1, ProductID: 1},
+			{ID: 2, UserID: 7, ProductID: 1},
+			{ID: 3, UserID: 1, ProductID: 9},
+		},
+	)
+
+	err := archive.Validate()
+	if err == nil {
+		t.Fatalf("Expected validation errors, but got none")
+	}
+
+	for _, expected := range []string{
+		"duplicate product ID 1",
+		"order 2 references missing user 7",
+		"order 3 references missing product 9",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %q, but got %v", expected, err)
+		}
+	}
+}
+
+func TestArchiveValidateCatalogReferences(t *testing.T) {
+	archive := New(
+		nil,
+		Catalog{
+			Products: []model.Product{
+				{ID: 1, RestaurantID: 1, Name: "Burger", CategoryIDs: []int{1, 5}, Recipe: []model.RecipeItem{{IngredientID: 2, Quantity: 1}}},
+				{ID: 2, RestaurantID: 1, Name: "Combo", Bundle: &model.Bundle{Slots: []model.BundleSlot{{ID: 1, Name: "Main", ProductIDs: []int{1, 8}}}}},
+			},
+			Categories:   []model.Category{{ID: 1, RestaurantID: 2, Name: "Mains"}},
+			Ingredients:  []model.Ingredient{{ID: 1, RestaurantID: 1, Name: "Beef patty", Unit: "piece"}},
+			PriceChanges: []model.PriceChange{{ID: 1, RestaurantID: 1, ProductID: 3}},
+			MenuVersions: []model.MenuVersion{{RestaurantID: 1, Version: 1}, {RestaurantID: 1, Version: 1}},
+			ExchangeRates: []model.ExchangeRate{
+				{RestaurantID: 1, Base: "USD", Currency: "EUR"},
+				{RestaurantID: 1, Base: "USD", Currency: "EUR"},
+			},
+			StockAdjustments: []model.StockAdjustment{{ID: 1, RestaurantID: 2, ProductID: 1}},
+			Sales:            []model.Sale{{ID: "order-7", RestaurantID: 1, Items: []model.SaleItem{{ProductID: 4, Quantity: 1}}}},
+			MenuDrafts: []model.DraftChange{
+				{RestaurantID: 1, ProductID: 6, Action: model.DraftUpdate},
+				{RestaurantID: 1, ProductID: 9, Action: model.DraftCreate},
+			},
+		},
+		nil,
+	)
+
+	err := archive.Validate()
+	if err == nil {
+		t.Fatalf("Expected validation errors, but got none")
+	}
+
+	for _, expected := range []string{
+		"product 1 references category 1 of another restaurant",
+		"product 1 references missing category 5",
+		"product 1 references missing ingredient 2",
+		"product 2 references missing product 8",
+		"price change 1 references missing product 3",
+		"duplicate menu version 1 of restaurant 1",
+		"duplicate exchange rate USD->EUR of restaurant 1",
+		"stock adjustment 1 references product 1 of another restaurant",
+		`sale "order-7" references missing product 4`,
+		"menu draft of product 6 references missing product 6",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %q, but got %v", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "product 9") {
+		t.Errorf("Expected a draft creating product 9 not to need it in the archive, but got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package blob stores binary objects such as product images under
+// slash-separated keys.
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store is implemented by every blob backend.
+type Store interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// DiskStore keeps blobs as files below Root.
+type DiskStore struct {
+	Root string
+}
+
+func NewDiskStore(root string) (*DiskStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create blob directory: %w", err)
+	}
+	return &DiskStore{Root: root}, nil
+}
+
+// Put writes the blob to a temporary file first so readers never see a
+// partially written object.
+func (s *DiskStore) Put(key string, r io.Reader) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("create blob directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("create blob: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("write blob: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write blob: %w", err)
+	}
+	return os.Rename(file.Name(), name)
+}
+
+func (s *DiskStore) Get(key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *DiskStore) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// path maps a key to a file below Root, refusing keys that would escape it.
+func (s *DiskStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
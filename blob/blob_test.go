@@ -0,0 +1,49 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDiskStore(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+
+	if err := store.Put("products/1/image.png", strings.NewReader("png bytes")); err != nil {
+		t.Fatalf("Error putting blob: %v", err)
+	}
+
+	reader, err := store.Get("products/1/image.png")
+	if err != nil {
+		t.Fatalf("Error getting blob: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "png bytes" {
+		t.Errorf("Expected stored bytes, but got %q", data)
+	}
+
+	if err := store.Delete("products/1/image.png"); err != nil {
+		t.Fatalf("Error deleting blob: %v", err)
+	}
+	if _, err := store.Get("products/1/image.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v, but got %v", ErrNotFound, err)
+	}
+}
+
+func TestDiskStoreRejectsEscapingKeys(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+
+	for _, key := range []string{"", "../secret", "/etc/passwd", "products/../../secret", "products//1"} {
+		if err := store.Put(key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: expected %v, but got %v", key, ErrInvalidKey, err)
+		}
+	}
+}
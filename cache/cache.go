@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores opaque values under string keys for a limited time. Set with a
+// ttl of zero or less stores nothing.
+type Cache interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// Fenced wraps a Cache so that a value read from the source before its key
+// was deleted cannot be stored after the deletion. Callers take the key's
+// Generation before reading the source and store the result with
+// SetIfCurrent, which drops it if a Delete came in between. Generations are
+// kept in process, so this covers invalidations received by this process.
+type Fenced struct {
+	Cache
+
+	mu          sync.Mutex
+	generations map[string]uint64
+}
+
+func NewFenced(c Cache) *Fenced {
+	return &Fenced{Cache: c, generations: make(map[string]uint64)}
+}
+
+// Generation returns the number of times key has been deleted.
+func (f *Fenced) Generation(key string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.generations[key]
+}
+
+// SetIfCurrent stores value unless key was deleted since generation was
+// taken, and reports whether it did.
+func (f *Fenced) SetIfCurrent(key string, value []byte, ttl time.Duration, generation uint64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.generations[key] != generation {
+		return false, nil
+	}
+	return true, f.Cache.Set(key, value, ttl)
+}
+
+func (f *Fenced) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.generations[key]++
+	return f.Cache.Delete(key)
+}
+
+// Stats counts lookups served from a cache.
+type Stats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// Metered wraps a Cache and counts hits, misses and deletions. Backend errors
+// on Get are counted as misses.
+type Metered struct {
+	Cache
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+func NewMetered(c Cache) *Metered {
+	return &Metered{Cache: c}
+}
+
+func (m *Metered) Get(key string) ([]byte, bool, error) {
+	value, found, err := m.Cache.Get(key)
+	if err != nil || !found {
+		m.misses.Add(1)
+		return value, found, err
+	}
+	m.hits.Add(1)
+	return value, true, nil
+}
+
+func (m *Metered) Delete(key string) error {
+	m.invalidations.Add(1)
+	return m.Cache.Delete(key)
+}
+
+func (m *Metered) Stats() Stats {
+	return Stats{
+		Hits:          m.hits.Load(),
+		Misses:        m.misses.Load(),
+		Invalidations: m.invalidations.Load(),
+	}
+}
+
+// MemoryCache is an in-process Cache. Expired entries are dropped when they
+// are read or when Set finds them.
+type MemoryCache struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryItem)}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, found := c.items[key]
+	if !found {
+		return nil, false, nil
+	}
+	if time.Now().After(item.expiresAt) {
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	return item.value, true, nil
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, item := range c.items {
+		if now.After(item.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+
+	c.items[key] = memoryItem{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (c *MemoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+	return nil
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheExpires(t *testing.T) {
+	c := NewMemoryCache()
+
+	if err := c.Set("product:1:1", []byte("burger"), 20*time.Millisecond); err != nil {
+		t.Fatalf("Error setting value: %v", err)
+	}
+
+	if value, found, _ := c.Get("product:1:1"); !found || string(value) != "burger" {
+		t.Errorf("Expected cached value burger, but got %q (found=%v)", value, found)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, found, _ := c.Get("product:1:1"); found {
+		t.Errorf("Expected value to expire")
+	}
+}
+
+func TestMeteredCountsHitsAndMisses(t *testing.T) {
+	c := NewMetered(NewMemoryCache())
+
+	c.Get("product:1:1")
+	c.Set("product:1:1", []byte("burger"), time.Minute)
+	c.Get("product:1:1")
+	c.Get("product:1:1")
+	c.Delete("product:1:1")
+	c.Get("product:1:1")
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Invalidations != 1 {
+		t.Errorf("Expected 2 hits, 2 misses and 1 invalidation, but got %+v", stats)
+	}
+}
+
+func TestFencedDropsValueReadBeforeDelete(t *testing.T) {
+	c := NewFenced(NewMemoryCache())
+
+	generation := c.Generation("product:1:1")
+	if err := c.Delete("product:1:1"); err != nil {
+		t.Fatalf("Error deleting value: %v", err)
+	}
+
+	stored, err := c.SetIfCurrent("product:1:1", []byte("stale burger"), time.Minute, generation)
+	if err != nil || stored {
+		t.Errorf("Expected a value read before the delete to be dropped, but got stored=%v err=%v", stored, err)
+	}
+	if _, found, _ := c.Get("product:1:1"); found {
+		t.Errorf("Expected no cached value after the delete")
+	}
+
+	stored, err = c.SetIfCurrent("product:1:1", []byte("burger"), time.Minute, c.Generation("product:1:1"))
+	if err != nil || !stored {
+		t.Errorf("Expected a value read after the delete to be stored, but got stored=%v err=%v", stored, err)
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	addr := startFakeRedis(t)
+	c := NewRedisCache(addr)
+
+	if _, found, err := c.Get("product:1:1"); err != nil || found {
+		t.Fatalf("Expected miss, but got found=%v err=%v", found, err)
+	}
+
+	if err := c.Set("product:1:1", []byte(`{"id":1}`), time.Minute); err != nil {
+		t.Fatalf("Error setting value: %v", err)
+	}
+
+	value, found, err := c.Get("product:1:1")
+	if err != nil || !found || string(value) != `{"id":1}` {
+		t.Errorf("Expected cached value, but got %q (found=%v err=%v)", value, found, err)
+	}
+
+	if err := c.Delete("product:1:1"); err != nil {
+		t.Fatalf("Error deleting value: %v", err)
+	}
+	if _, found, _ := c.Get("product:1:1"); found {
+		t.Errorf("Expected value to be deleted")
+	}
+
+	if err := c.Set("product:1:2", []byte(`{"id":2}`), 500*time.Microsecond); err != nil {
+		t.Errorf("Expected a sub-millisecond TTL to be rounded up, but got %v", err)
+	}
+	if err := c.Set("product:1:3", []byte(`{"id":3}`), 0); err != nil {
+		t.Errorf("Expected a zero TTL to store nothing, but got %v", err)
+	}
+	if _, found, _ := c.Get("product:1:3"); found {
+		t.Errorf("Expected a zero TTL to store nothing")
+	}
+}
+
+// startFakeRedis serves GET, SET and DEL over RESP from a map. Like Redis it
+// refuses SET with PX 0.
+func startFakeRedis(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting fake redis: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	data := make(map[string]string)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readCommand(reader)
+					if err != nil {
+						return
+					}
+
+					mu.Lock()
+					switch strings.ToUpper(args[0]) {
+					case "GET":
+						if value, found := data[args[1]]; found {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+						} else {
+							fmt.Fprint(conn, "$-1\r\n")
+						}
+					case "SET":
+						if len(args) == 5 && args[4] == "0" {
+							fmt.Fprint(conn, "-ERR invalid expire time in 'set' command\r\n")
+							break
+						}
+						data[args[1]] = args[2]
+						fmt.Fprint(conn, "+OK\r\n")
+					case "DEL":
+						delete(data, args[1])
+						fmt.Fprint(conn, ":1\r\n")
+					default:
+						fmt.Fprint(conn, "-ERR unknown command\r\n")
+					}
+					mu.Unlock()
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisCache talks the RESP protocol to a Redis-compatible server over a
+// single connection, reconnecting after network errors.
+type RedisCache struct {
+	addr    string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewRedisCache(addr string) *RedisCache {
+	return &RedisCache{addr: addr, timeout: 2 * time.Second}
+}
+
+func (c *RedisCache) Get(key string) ([]byte, bool, error) {
+	reply, err := c.do("GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis GET: unexpected reply %v", reply)
+	}
+	return value, true, nil
+}
+
+// Set stores value for ttl, rounded down to whole milliseconds but at least
+// one, as Redis refuses a PX of zero. A ttl of zero or less stores nothing.
+func (c *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	_, err := c.do("SET", key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	return err
+}
+
+func (c *RedisCache) Delete(key string) error {
+	_, err := c.do("DEL", key)
+	return err
+}
+
+func (c *RedisCache) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("redis connect: %w", err)
+		}
+		c.conn = conn
+		c.reader = bufio.NewReader(conn)
+	}
+
+	reply, err := c.roundTrip(args)
+	if err != nil {
+		var redisErr redisError
+		if !errors.As(err, &redisErr) {
+			c.conn.Close()
+			c.conn = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *RedisCache) roundTrip(args []string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	command := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		command += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(command)); err != nil {
+		return nil, fmt.Errorf("redis write: %w", err)
+	}
+
+	return readReply(c.reader)
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readReply decodes a single RESP reply. Bulk strings are returned as []byte,
+// a nil bulk string as nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read: %w", err)
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis read: short reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis read: bad bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis read: %w", err)
+		}
+		return buf[:size], nil
+	default:
+		return nil, fmt.Errorf("redis read: unsupported reply %q", line)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"restaurant/backup"
+	"restaurant/model"
+	"restaurant/token"
+	"strings"
+)
+
+const usage = `usage:
+  backup export [-o file] [service flags]
+  backup restore -i file [service flags]
+
+Service flags:
+  -auth-url, -product-url, -order-url   base URLs of the running services
+
+The services only expose /admin/export and /admin/restore when started
+with ADMIN_ENDPOINTS=true. Calls are signed with a service token, so the
+tool needs the services' TOKEN_SECRET.
+`
+
+type services struct {
+	authURL    string
+	productURL string
+	orderURL   string
+}
+
+func (s *services) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.authURL, "auth-url", "http://localhost:8081", "authentication service base URL")
+	fs.StringVar(&s.productURL, "product-url", "http://localhost:8082", "product service base URL")
+	fs.StringVar(&s.orderURL, "order-url", "http://localhost:8080", "order service base URL")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runExport(args []string) error {
+	var svc services
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "restaurant-backup.json.gz", "archive file to write")
+	svc.register(fs)
+	fs.Parse(args)
+
+	var (
+		users   []model.User
+		catalog backup.Catalog
+		orders  []model.Order
+	)
+	if err := fetch(svc.authURL+"/admin/export", &users); err != nil {
+		return fmt.Errorf("export users: %w", err)
+	}
+	if err := fetch(svc.productURL+"/admin/export", &catalog); err != nil {
+		return fmt.Errorf("export products: %w", err)
+	}
+	if err := fetch(svc.orderURL+"/admin/export", &orders); err != nil {
+		return fmt.Errorf("export orders: %w", err)
+	}
+
+	archive := backup.New(users, catalog, orders)
+	if err := archive.Validate(); err != nil {
+		log.Printf("Warning: exported data has integrity problems:\n%v", err)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer file.Close()
+
+	if err := backup.Write(file, archive); err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Exported %d users, %d products (with %d categories, %d ingredients, %d price changes, %d menu versions, %d menu drafts, %d exchange rates, %d stock adjustments and %d sales) and %d orders to %s\n",
+		len(users),
+		len(catalog.Products),
+		len(catalog.Categories),
+		len(catalog.Ingredients),
+		len(catalog.PriceChanges),
+		len(catalog.MenuVersions),
+		len(catalog.MenuDrafts),
+		len(catalog.ExchangeRates),
+		len(catalog.StockAdjustments),
+		len(catalog.Sales),
+		len(orders),
+		*output,
+	)
+	return nil
+}
+
+func runRestore(args []string) error {
+	var svc services
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := fs.String("i", "", "archive file to restore")
+	svc.register(fs)
+	fs.Parse(args)
+
+	if *input == "" {
+		return fmt.Errorf("restore: -i is required")
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer file.Close()
+
+	archive, err := backup.Read(file)
+	if err != nil {
+		return err
+	}
+
+	if err := archive.Validate(); err != nil {
+		return fmt.Errorf("archive failed validation, nothing was imported:\n%w", err)
+	}
+
+	// Check every store before importing anything so a non-empty service
+	// does not leave the others half restored.
+	targets := []struct {
+		name string
+		list string
+		url  string
+		data interface{}
+	}{
+		{"users", svc.authURL + "/admin/export", svc.authURL + "/admin/restore", archive.Users},
+		{"products", svc.productURL + "/admin/export", svc.productURL + "/admin/restore", archive.Catalog},
+		{"orders", svc.orderURL + "/admin/export", svc.orderURL + "/admin/restore", archive.Orders},
+	}
+
+	for _, target := range targets {
+		count, err := countExisting(target.list)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", target.name, err)
+		}
+		if count > 0 {
+			return fmt.Errorf("restore requires empty stores, but %d %s records already exist", count, strings.TrimSuffix(target.name, "s"))
+		}
+	}
+
+	for _, target := range targets {
+		if err := post(target.url, target.data); err != nil {
+			return fmt.Errorf("restore %s: %w", target.name, err)
+		}
+	}
+
+	fmt.Printf(
+		"Restored %d users, %d products (with %d categories, %d ingredients, %d price changes, %d menu versions, %d menu drafts, %d exchange rates, %d stock adjustments and %d sales) and %d orders from %s (archive version %d, created %s)\n",
+		len(archive.Users),
+		len(archive.Products),
+		len(archive.Categories),
+		len(archive.Ingredients),
+		len(archive.PriceChanges),
+		len(archive.MenuVersions),
+		len(archive.MenuDrafts),
+		len(archive.ExchangeRates),
+		len(archive.StockAdjustments),
+		len(archive.Sales),
+		len(archive.Orders),
+		*input,
+		archive.Version,
+		archive.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	return nil
+}
+
+// countExisting counts the records a service exports: the items of a list,
+// or of every list in the product service's catalog.
+func countExisting(url string) (int, error) {
+	var existing json.RawMessage
+	if err := fetch(url, &existing); err != nil {
+		return 0, err
+	}
+	if len(existing) == 0 {
+		return 0, nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(existing, &list); err == nil {
+		return len(list), nil
+	}
+
+	var catalog backup.Catalog
+	if err := json.Unmarshal(existing, &catalog); err != nil {
+		return 0, err
+	}
+	return catalog.Len(), nil
+}
+
+func fetch(url string, v interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("GET %s: status %d: %s", url, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+func post(url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("POST %s: status %d: %s", url, response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return nil
+}
+
+// do sends a request to an admin endpoint, which only accepts service tokens.
+func do(request *http.Request) (*http.Response, error) {
+	issued, err := token.IssueService()
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+issued)
+	return http.DefaultClient.Do(request)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const usage = `usage:
+  menu import -f file [-dry-run] [service flags]
+  menu export [-format csv|json] [-o file] [service flags]
+
+Import reads CSV or JSON, chosen by the file extension. Rows are matched to
+existing products by SKU.
+
+Service flags:
+  -url          product service base URL
+  -token        bearer token from POST /login (default $RESTAURANT_TOKEN);
+                it names the restaurant and the user recorded for price changes
+`
+
+type service struct {
+	url   string
+	token string
+}
+
+func (s *service) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.url, "url", "http://localhost:8082", "product service base URL")
+	fs.StringVar(&s.token, "token", os.Getenv("RESTAURANT_TOKEN"), "bearer token")
+}
+
+func (s *service) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	request, err := http.NewRequest(method, s.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	request.Header.Set("Authorization", "Bearer "+s.token)
+	return http.DefaultClient.Do(request)
+}
+
+type importReport struct {
+	DryRun  bool `json:"dry_run"`
+	Created int  `json:"created"`
+	Updated int  `json:"updated"`
+	Errors  []struct {
+		Row    int      `json:"row"`
+		SKU    string   `json:"sku"`
+		Errors []string `json:"errors"`
+	} `json:"errors"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runImport(args []string) error {
+	var svc service
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	input := fs.String("f", "", "CSV or JSON file to import")
+	dryRun := fs.Bool("dry-run", false, "validate the file without importing it")
+	svc.register(fs)
+	fs.Parse(args)
+
+	if *input == "" {
+		return fmt.Errorf("import: -f is required")
+	}
+
+	contentType := "application/json"
+	switch strings.ToLower(filepath.Ext(*input)) {
+	case ".csv":
+		contentType = "text/csv"
+	case ".json":
+	default:
+		return fmt.Errorf("import: %s must be a .csv or .json file", *input)
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		return fmt.Errorf("open import file: %w", err)
+	}
+	defer file.Close()
+
+	response, err := svc.do(http.MethodPost, "/product/import?dry_run="+strconv.FormatBool(*dryRun), contentType, file)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusUnprocessableEntity {
+		body, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("import: status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var report importReport
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		return fmt.Errorf("decode import report: %w", err)
+	}
+
+	if len(report.Errors) > 0 {
+		for _, row := range report.Errors {
+			fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", row.Row, row.SKU, strings.Join(row.Errors, "; "))
+		}
+		return fmt.Errorf("%d rows failed validation, nothing was imported", len(report.Errors))
+	}
+
+	if report.DryRun {
+		fmt.Printf("Dry run: %s would create %d and update %d products\n", *input, report.Created, report.Updated)
+	} else {
+		fmt.Printf("Imported %s: created %d and updated %d products\n", *input, report.Created, report.Updated)
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	var svc service
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "json", "export format, csv or json")
+	output := fs.String("o", "", "file to write, standard output when empty")
+	svc.register(fs)
+	fs.Parse(args)
+
+	response, err := svc.do(http.MethodGet, "/product/export?format="+*format, "", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("export: status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if *output == "" {
+		_, err = io.Copy(os.Stdout, response.Body)
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("create export file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, response.Body); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported menu to %s\n", *output)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"restaurant/token"
+	"strings"
+)
+
+// seed resets running services to the fixtures of their environment by
+// calling each service's POST /admin/seed endpoint. Services only expose that
+// endpoint when started with ADMIN_ENDPOINTS=true, and only accept calls
+// signed with a service token, so seed needs their TOKEN_SECRET.
+func main() {
+	authURL := flag.String("auth-url", "http://localhost:8081", "authentication service base URL")
+	productURL := flag.String("product-url", "http://localhost:8082", "product service base URL")
+	orderURL := flag.String("order-url", "http://localhost:8080", "order service base URL")
+	flag.Parse()
+
+	services := []struct {
+		name string
+		url  string
+	}{
+		{"auth-service", *authURL},
+		{"product-service", *productURL},
+		{"order-service", *orderURL},
+	}
+
+	issued, err := token.IssueService()
+	if err != nil {
+		log.Fatalf("Error issuing service token: %v", err)
+	}
+
+	failed := false
+	for _, service := range services {
+		request, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(service.url, "/")+"/admin/seed", nil)
+		if err != nil {
+			log.Printf("Error seeding %s: %v", service.name, err)
+			failed = true
+			continue
+		}
+		request.Header.Set("Authorization", "Bearer "+issued)
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			log.Printf("Error seeding %s: %v", service.name, err)
+			failed = true
+			continue
+		}
+
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			log.Printf("Error seeding %s: status %d: %s", service.name, response.StatusCode, strings.TrimSpace(string(body)))
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%s\t%s", service.name, body)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
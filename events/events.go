@@ -0,0 +1,99 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"restaurant/token"
+	"strings"
+	"time"
+)
+
+const (
+	ProductUpdated = "product.updated"
+	ProductDeleted = "product.deleted"
+)
+
+// ProductEvent tells subscribers that a product changed.
+type ProductEvent struct {
+	Type         string    `json:"type"`
+	RestaurantID int       `json:"restaurant_id"`
+	ProductID    int       `json:"product_id"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// Publisher delivers events to subscriber URLs with an HTTP POST carrying a
+// service token. Delivery is asynchronous and best effort: failures are
+// logged and not retried.
+type Publisher struct {
+	subscribers []string
+	client      *http.Client
+}
+
+func NewPublisher(subscribers ...string) *Publisher {
+	return &Publisher{
+		subscribers: subscribers,
+		client:      &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// NewPublisherFromEnv reads a comma separated subscriber list from the given
+// environment variable, falling back to defaults when it is unset.
+func NewPublisherFromEnv(name string, defaults ...string) *Publisher {
+	value, set := os.LookupEnv(name)
+	if !set {
+		return NewPublisher(defaults...)
+	}
+
+	var subscribers []string
+	for _, url := range strings.Split(value, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			subscribers = append(subscribers, url)
+		}
+	}
+	return NewPublisher(subscribers...)
+}
+
+func (p *Publisher) PublishProduct(eventType string, restaurantID, productID int) {
+	p.publish(ProductEvent{
+		Type:         eventType,
+		RestaurantID: restaurantID,
+		ProductID:    productID,
+		OccurredAt:   time.Now().UTC(),
+	})
+}
+
+func (p *Publisher) publish(event interface{}) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding event: %v", err)
+		return
+	}
+
+	for _, url := range p.subscribers {
+		go func(url string) {
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+			if err != nil {
+				log.Printf("Error creating event request for %s: %v", url, err)
+				return
+			}
+			request.Header.Set("Content-Type", "application/json")
+			if issued, err := token.IssueService(); err == nil {
+				request.Header.Set("Authorization", "Bearer "+issued)
+			}
+
+			response, err := p.client.Do(request)
+			if err != nil {
+				log.Printf("Error delivering event to %s: %v", url, err)
+				return
+			}
+			response.Body.Close()
+
+			if response.StatusCode >= http.StatusMultipleChoices {
+				log.Printf("Event delivery to %s failed with status %d", url, response.StatusCode)
+			}
+		}(url)
+	}
+}
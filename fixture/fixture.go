@@ -0,0 +1,136 @@
+package fixture
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"restaurant/model"
+	"restaurant/password"
+	"strconv"
+)
+
+// Fixtures shipped with the binary live in data/<environment>/. Setting
+// FIXTURES_DIR points the loader at a directory with the same layout instead.
+//
+//go:embed data
+var embedded embed.FS
+
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// Environment returns the value of APP_ENV, defaulting to development.
+func Environment() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return EnvDevelopment
+}
+
+// Local reports whether the services run in development or tests, the only
+// environments where built-in development secrets are acceptable.
+func Local() bool {
+	env := Environment()
+	return env == EnvDevelopment || env == EnvTest
+}
+
+// SeedingEnabled reports whether services should load fixtures on startup.
+// SEED_DATA=true|false wins; otherwise seeding is on everywhere but production.
+func SeedingEnabled() bool {
+	if value := os.Getenv("SEED_DATA"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err == nil {
+			return enabled
+		}
+	}
+	return Environment() != EnvProduction
+}
+
+// AdminEnabled reports whether services expose their /admin endpoints, which
+// seed, export and restore every restaurant. They are off unless
+// ADMIN_ENDPOINTS=true.
+func AdminEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("ADMIN_ENDPOINTS"))
+	return err == nil && enabled
+}
+
+// LoadUsers reads users whose fixtures give a plain "password" and hashes it,
+// so stored users look the same as registered ones.
+func LoadUsers(env string) ([]model.User, error) {
+	var fixtures []struct {
+		model.User
+		Password string `json:"password"`
+	}
+	if err := load(env, "users.json", &fixtures); err != nil {
+		return nil, err
+	}
+
+	users := make([]model.User, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		hash, err := password.Hash(fixture.Password)
+		if err != nil {
+			return nil, fmt.Errorf("hash password of fixture user %d: %w", fixture.ID, err)
+		}
+		fixture.User.PasswordHash = hash
+		users = append(users, fixture.User)
+	}
+	return users, nil
+}
+
+func LoadProducts(env string) ([]model.Product, error) {
+	var products []model.Product
+	if err := load(env, "products.json", &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func LoadCategories(env string) ([]model.Category, error) {
+	var categories []model.Category
+	if err := load(env, "categories.json", &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func LoadIngredients(env string) ([]model.Ingredient, error) {
+	var ingredients []model.Ingredient
+	if err := load(env, "ingredients.json", &ingredients); err != nil {
+		return nil, err
+	}
+	return ingredients, nil
+}
+
+func LoadOrders(env string) ([]model.Order, error) {
+	var orders []model.Order
+	if err := load(env, "orders.json", &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func load(env, name string, v interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if dir := os.Getenv("FIXTURES_DIR"); dir != "" {
+		data, err = os.ReadFile(filepath.Join(dir, env, name))
+	} else {
+		data, err = fs.ReadFile(embedded, "data/"+env+"/"+name)
+	}
+	if err != nil {
+		return fmt.Errorf("read %s fixtures for %q: %w", name, env, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s fixtures for %q: %w", name, env, err)
+	}
+	return nil
+}
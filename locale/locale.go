@@ -0,0 +1,155 @@
+// Package locale picks the language of a response by negotiating the
+// Accept-Language header (RFC 9110) against the languages the menu is
+// translated into.
+package locale
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language of the text stored on products and categories
+// themselves; translations hold every other language.
+const Default = "en"
+
+// Supported returns the languages listed in SUPPORTED_LANGUAGES (comma
+// separated, default "en,id"). Default is always supported and comes first.
+func Supported() []string {
+	value := os.Getenv("SUPPORTED_LANGUAGES")
+	if value == "" {
+		value = "id"
+	}
+
+	languages := []string{Default}
+	for _, item := range strings.Split(value, ",") {
+		language, err := Normalize(item)
+		if err != nil || contains(languages, language) {
+			continue
+		}
+		languages = append(languages, language)
+	}
+	return languages
+}
+
+// Normalize checks a language tag such as "id" or "en-US" and returns it in
+// lower case with hyphens.
+func Normalize(tag string) (string, error) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	subtags := strings.Split(tag, "-")
+	if len(subtags[0]) < 2 || len(subtags[0]) > 3 || !isLetters(subtags[0]) {
+		return "", fmt.Errorf("invalid language %q", tag)
+	}
+	for _, subtag := range subtags[1:] {
+		if len(subtag) < 1 || len(subtag) > 8 || !isAlphanumeric(subtag) {
+			return "", fmt.Errorf("invalid language %q", tag)
+		}
+	}
+	return tag, nil
+}
+
+// Negotiate returns the supported language the header prefers, trying each
+// range in order of its q-value. A range matches a language equal to it, a
+// more specific language ("en" matches "en-gb") or its own primary language
+// ("id-ID" matches "id"). Without a match the first supported language is
+// returned.
+func Negotiate(header string, supported []string) string {
+	for _, accepted := range parse(header) {
+		if accepted == "*" {
+			break
+		}
+		for _, language := range supported {
+			if language == accepted || strings.HasPrefix(language, accepted+"-") {
+				return language
+			}
+		}
+		primary, _, _ := strings.Cut(accepted, "-")
+		if contains(supported, primary) {
+			return primary
+		}
+	}
+	if len(supported) == 0 {
+		return Default
+	}
+	return supported[0]
+}
+
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// parse returns the ranges of an Accept-Language header with a non-zero
+// q-value, most preferred first.
+func parse(header string) []string {
+	ranges := make([]languageRange, 0)
+	for _, item := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if tag != "*" {
+			normalized, err := Normalize(tag)
+			if err != nil {
+				continue
+			}
+			tag = normalized
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			ranges = append(ranges, languageRange{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	tags := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		tags = append(tags, r.tag)
+	}
+	return tags
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isLetters(s string) bool {
+	for _, c := range s {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package locale
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"en", "id", "pt-br"}
+	tests := map[string]string{
+		"":                          "en",
+		"id":                        "id",
+		"id-ID,id;q=0.9,en;q=0.8":   "id",
+		"fr-FR, id;q=0.5, en;q=0.4": "id",
+		"en-US,id;q=0.5":            "en",
+		"pt":                        "pt-br",
+		"de, *;q=0.5":               "en",
+		"id;q=0, en;q=0.1":          "en",
+		"id;q=0.2, en;q=0.8":        "en",
+		"invalid!!, id":             "id",
+	}
+	for header, expected := range tests {
+		if language := Negotiate(header, supported); language != expected {
+			t.Errorf("%q: expected %s, but got %s", header, expected, language)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	t.Setenv("SUPPORTED_LANGUAGES", "id, ms_MY ,en,invalid!")
+	supported := Supported()
+	expected := []string{"en", "id", "ms-my"}
+	if len(supported) != len(expected) {
+		t.Fatalf("Expected %v, but got %v", expected, supported)
+	}
+	for i := range expected {
+		if supported[i] != expected[i] {
+			t.Errorf("Expected %v, but got %v", expected, supported)
+		}
+	}
+}
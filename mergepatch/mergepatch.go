@@ -0,0 +1,62 @@
+// Package mergepatch implements JSON Merge Patch as defined by RFC 7396.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ContentType is the media type of merge patch documents.
+const ContentType = "application/merge-patch+json"
+
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply merges patch into the JSON document doc and returns the result.
+// Members set to null in the patch are removed, objects are merged
+// recursively and every other value replaces the original.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	var changes interface{}
+	if err := decode(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if _, ok := changes.(map[string]interface{}); !ok {
+		return nil, ErrNotObject
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// decode keeps numbers as written so large integers survive the round trip.
+func decode(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,50 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{name: "replace member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{name: "remove member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{name: "replace array", doc: `{"a":["b"]}`, patch: `{"a":["c","d"]}`, expected: `{"a":["c","d"]}`},
+		{name: "merge nested object", doc: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"d":null,"f":1}}`, expected: `{"a":{"b":"c","f":1}}`},
+		{name: "object replaces scalar", doc: `{"a":"b"}`, patch: `{"a":{"c":null,"d":1}}`, expected: `{"a":{"d":1}}`},
+		{name: "keep large integers", doc: `{"id":9007199254740993}`, patch: `{}`, expected: `{"id":9007199254740993}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+
+			var got, expected interface{}
+			json.Unmarshal(result, &got)
+			json.Unmarshal([]byte(tt.expected), &expected)
+			if !reflect.DeepEqual(got, expected) && string(result) != tt.expected {
+				t.Errorf("Expected %s, but got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestApplyRejectsNonObjectPatch(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":"b"}`), []byte(`["a"]`)); !errors.Is(err, ErrNotObject) {
+		t.Errorf("Expected %v, but got %v", ErrNotObject, err)
+	}
+	if _, err := Apply([]byte(`{"a":"b"}`), []byte(`{"a":`)); err == nil {
+		t.Errorf("Expected an error for malformed JSON")
+	}
+}
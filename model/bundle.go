@@ -0,0 +1,188 @@
+package model
+
+import (
+	"fmt"
+	"restaurant/money"
+)
+
+// Bundle pricing rules. A fixed bundle sells at the bundle product's own
+// price; the discount rules take the summed prices of the chosen components
+// and reduce them by a percentage or an amount.
+const (
+	BundlePricingFixed      = "fixed"
+	BundlePricingPercentOff = "percent_off"
+	BundlePricingAmountOff  = "amount_off"
+)
+
+// MaxPercentOff is a discount of 100% in basis points.
+const MaxPercentOff = 10000
+
+// Bundle turns a product into a combo of other products. Each slot is filled
+// by one of its ProductIDs; a slot with a single product is fixed.
+// PercentOff is the discount of percent_off bundles in basis points, so 1500
+// takes 15% off; AmountOff is the discount of amount_off bundles in the
+// bundle's currency.
+type Bundle struct {
+	Slots      []BundleSlot `json:"slots"`
+	Pricing    string       `json:"pricing"`
+	PercentOff int          `json:"percent_off_bps,omitempty" validate:"min=0"`
+	AmountOff  *money.Money `json:"amount_off,omitempty"`
+}
+
+type BundleSlot struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name" validate:"required,max=100"`
+	ProductIDs []int  `json:"product_ids" validate:"required"`
+	Quantity   int    `json:"quantity" validate:"min=0"`
+}
+
+// BundleChoice picks the product for a slot in an order request.
+type BundleChoice struct {
+	SlotID    int `json:"slot_id" validate:"gt=0"`
+	ProductID int `json:"product_id" validate:"gt=0"`
+}
+
+// BundleComponent is a product an ordered bundle expanded into. Quantity is
+// the number of units for the whole order line.
+type BundleComponent struct {
+	SlotID      int         `json:"slot_id"`
+	SlotName    string      `json:"slot_name"`
+	ProductID   int         `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	Quantity    int         `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+}
+
+// Prepare assigns IDs to slots that have none, defaults slot quantities to
+// one and checks the pricing rule.
+func (b *Bundle) Prepare() error {
+	if len(b.Slots) == 0 {
+		return fmt.Errorf("a bundle needs at least one slot")
+	}
+
+	nextSlotID := 1
+	for _, slot := range b.Slots {
+		if slot.ID >= nextSlotID {
+			nextSlotID = slot.ID + 1
+		}
+	}
+
+	slotIDs := make(map[int]bool, len(b.Slots))
+	for i := range b.Slots {
+		slot := &b.Slots[i]
+		if slot.ID == 0 {
+			slot.ID = nextSlotID
+			nextSlotID++
+		}
+		if slotIDs[slot.ID] {
+			return fmt.Errorf("bundle slot %d is listed twice", slot.ID)
+		}
+		slotIDs[slot.ID] = true
+
+		if slot.Name == "" {
+			return fmt.Errorf("bundle slot %d needs a name", slot.ID)
+		}
+		if len(slot.ProductIDs) == 0 {
+			return fmt.Errorf("bundle slot %q needs at least one product", slot.Name)
+		}
+		if slot.Quantity == 0 {
+			slot.Quantity = 1
+		}
+		if slot.Quantity < 0 {
+			return fmt.Errorf("bundle slot %q has a negative quantity", slot.Name)
+		}
+	}
+
+	switch b.Pricing {
+	case "", BundlePricingFixed:
+		b.Pricing = BundlePricingFixed
+		if b.PercentOff != 0 || b.AmountOff != nil {
+			return fmt.Errorf("fixed bundles take neither percent_off_bps nor amount_off")
+		}
+	case BundlePricingPercentOff:
+		if b.PercentOff <= 0 || b.PercentOff > MaxPercentOff {
+			return fmt.Errorf("percent_off bundles need percent_off_bps between 1 and %d", MaxPercentOff)
+		}
+		if b.AmountOff != nil {
+			return fmt.Errorf("percent_off bundles take no amount_off")
+		}
+	case BundlePricingAmountOff:
+		if b.AmountOff == nil || b.AmountOff.Sign() <= 0 {
+			return fmt.Errorf("amount_off bundles need a positive amount_off")
+		}
+		if b.PercentOff != 0 {
+			return fmt.Errorf("amount_off bundles take no percent_off_bps")
+		}
+	default:
+		return fmt.Errorf("pricing must be one of fixed, percent_off, amount_off")
+	}
+	return nil
+}
+
+// Choose resolves the product of every slot. Slots offering more than one
+// product need a choice; fixed slots take their only product.
+func (b Bundle) Choose(choices []BundleChoice) ([]BundleChoice, error) {
+	chosen := make(map[int]int, len(choices))
+	for _, choice := range choices {
+		if _, exists := chosen[choice.SlotID]; exists {
+			return nil, fmt.Errorf("bundle slot %d is chosen twice", choice.SlotID)
+		}
+		chosen[choice.SlotID] = choice.ProductID
+	}
+
+	resolved := make([]BundleChoice, 0, len(b.Slots))
+	for _, slot := range b.Slots {
+		productID, picked := chosen[slot.ID]
+		delete(chosen, slot.ID)
+
+		switch {
+		case !picked && len(slot.ProductIDs) == 1:
+			productID = slot.ProductIDs[0]
+		case !picked:
+			return nil, fmt.Errorf("bundle slot %q needs a choice", slot.Name)
+		case !slot.Offers(productID):
+			return nil, fmt.Errorf("product %d is not offered in bundle slot %q", productID, slot.Name)
+		}
+
+		resolved = append(resolved, BundleChoice{SlotID: slot.ID, ProductID: productID})
+	}
+
+	for slotID := range chosen {
+		return nil, fmt.Errorf("bundle slot %d does not exist", slotID)
+	}
+	return resolved, nil
+}
+
+// Price applies the pricing rule. listPrice is the bundle product's own
+// price and componentTotal the summed prices of the chosen components. A
+// percentage discount is rounded half to even to the currency's minor unit.
+// An amount_off in another currency than the components fails.
+func (b Bundle) Price(listPrice, componentTotal money.Money) (money.Money, error) {
+	var discount money.Money
+	switch {
+	case b.Pricing == BundlePricingPercentOff:
+		discount = componentTotal.BasisPoints(b.PercentOff)
+	case b.Pricing == BundlePricingAmountOff && b.AmountOff != nil:
+		discount = *b.AmountOff
+	default:
+		return listPrice, nil
+	}
+
+	price, err := componentTotal.Sub(discount)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if price.Sign() < 0 {
+		return money.New(0, componentTotal.Currency()), nil
+	}
+	return price, nil
+}
+
+func (s BundleSlot) Offers(productID int) bool {
+	for _, id := range s.ProductIDs {
+		if id == productID {
+			return true
+		}
+	}
+	return false
+}
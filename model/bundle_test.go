@@ -0,0 +1,102 @@
+package model
+
+import (
+	"restaurant/money"
+	"testing"
+)
+
+func meal() Bundle {
+	return Bundle{
+		Pricing:    BundlePricingPercentOff,
+		PercentOff: 1000,
+		Slots: []BundleSlot{
+			{ID: 1, Name: "Burger", ProductIDs: []int{1}, Quantity: 1},
+			{ID: 2, Name: "Drink", ProductIDs: []int{6, 7}, Quantity: 2},
+		},
+	}
+}
+
+func TestBundleChoose(t *testing.T) {
+	bundle := meal()
+
+	resolved, err := bundle.Choose([]BundleChoice{{SlotID: 2, ProductID: 7}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(resolved) != 2 || resolved[0].ProductID != 1 || resolved[1].ProductID != 7 {
+		t.Errorf("Expected burger and lemonade, but got %+v", resolved)
+	}
+
+	tests := map[string][]BundleChoice{
+		"missing choice":    nil,
+		"not offered":       {{SlotID: 2, ProductID: 3}},
+		"unknown slot":      {{SlotID: 2, ProductID: 6}, {SlotID: 9, ProductID: 6}},
+		"slot chosen twice": {{SlotID: 2, ProductID: 6}, {SlotID: 2, ProductID: 7}},
+	}
+	for name, choices := range tests {
+		if _, err := bundle.Choose(choices); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBundlePrice(t *testing.T) {
+	listPrice := money.MustParse("20.00", "USD")
+	componentTotal := money.MustParse("25.00", "USD")
+
+	bundle := meal()
+	if price, err := bundle.Price(listPrice, componentTotal); err != nil || price.String() != "22.50 USD" {
+		t.Errorf("Expected 10%% off 25 to be 22.50 USD, but got %s and error %v", price, err)
+	}
+
+	// 15% off 27.49 is 23.3665, which rounds to the cent.
+	bundle.PercentOff = 1500
+	if price, err := bundle.Price(listPrice, money.MustParse("27.49", "USD")); err != nil || price.String() != "23.37 USD" {
+		t.Errorf("Expected 15%% off 27.49 to be 23.37 USD, but got %s and error %v", price, err)
+	}
+
+	amountOff := money.MustParse("2.50", "USD")
+	bundle = Bundle{Pricing: BundlePricingAmountOff, AmountOff: &amountOff}
+	if price, err := bundle.Price(listPrice, componentTotal); err != nil || price.String() != "22.50 USD" {
+		t.Errorf("Expected 2.50 off 25 to be 22.50 USD, but got %s and error %v", price, err)
+	}
+
+	amountOff = money.MustParse("30.00", "USD")
+	if price, err := bundle.Price(listPrice, componentTotal); err != nil || !price.IsZero() {
+		t.Errorf("Expected price not to go below zero, but got %s and error %v", price, err)
+	}
+
+	amountOff = money.MustParse("2.50", "EUR")
+	if _, err := bundle.Price(listPrice, componentTotal); err == nil {
+		t.Errorf("Expected an amount off in another currency to fail")
+	}
+
+	bundle = Bundle{Pricing: BundlePricingFixed}
+	if price, err := bundle.Price(listPrice, componentTotal); err != nil || !price.Equal(listPrice) {
+		t.Errorf("Expected the list price 20.00 USD, but got %s and error %v", price, err)
+	}
+}
+
+func TestBundlePrepareChecksDiscount(t *testing.T) {
+	amountOff := money.MustParse("2.50", "USD")
+	slots := meal().Slots
+
+	invalid := map[string]Bundle{
+		"percent over 100":          {Pricing: BundlePricingPercentOff, PercentOff: MaxPercentOff + 1, Slots: slots},
+		"percent without discount":  {Pricing: BundlePricingPercentOff, Slots: slots},
+		"percent with amount":       {Pricing: BundlePricingPercentOff, PercentOff: 1000, AmountOff: &amountOff, Slots: slots},
+		"amount without amount_off": {Pricing: BundlePricingAmountOff, Slots: slots},
+		"amount with percent":       {Pricing: BundlePricingAmountOff, PercentOff: 1000, AmountOff: &amountOff, Slots: slots},
+		"fixed with discount":       {Pricing: BundlePricingFixed, PercentOff: 1000, Slots: slots},
+	}
+	for name, bundle := range invalid {
+		if err := bundle.Prepare(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	valid := Bundle{Pricing: BundlePricingAmountOff, AmountOff: &amountOff, Slots: slots}
+	if err := valid.Prepare(); err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+}
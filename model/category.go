@@ -0,0 +1,27 @@
+package model
+
+type Category struct {
+	ID           int    `json:"id"`
+	RestaurantID int    `json:"restaurant_id"`
+	Name         string `json:"name" validate:"required,max=100"`
+	Description  string `json:"description" validate:"max=1000"`
+	Position     int    `json:"position" validate:"min=0"`
+
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	Translations map[string]Translation `json:"translations,omitempty"`
+}
+
+// MenuSection is a category together with the products assigned to it.
+type MenuSection struct {
+	Category
+	Products []Product `json:"products"`
+}
+
+// Menu is the menu as guests see it. Version is the published menu version
+// it corresponds to, if the menu has been published.
+type Menu struct {
+	Version       int           `json:"version,omitempty"`
+	Sections      []MenuSection `json:"sections"`
+	Uncategorized []Product     `json:"uncategorized,omitempty"`
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"restaurant/money"
+	"time"
+)
+
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+// ExchangeRate is a restaurant's rate from a base currency, the currency its
+// products are priced in, to a currency guests may pay in: one unit of Base
+// is worth Rate units of Currency.
+type ExchangeRate struct {
+	RestaurantID int        `json:"restaurant_id"`
+	Base         string     `json:"base"`
+	Currency     string     `json:"currency"`
+	Rate         money.Rate `json:"rate"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type ExchangeRateRequest struct {
+	Rate money.Rate `json:"rate" validate:"required"`
+}
+
+// ConvertPrice returns amount in currency. Amounts already in currency and
+// zero amounts need no rate; anything else is converted at rate, which must
+// be the rate from the amount's currency.
+func ConvertPrice(amount money.Money, currency string, rate *ExchangeRate) (money.Money, error) {
+	if amount.Currency() == currency {
+		return amount, nil
+	}
+	if amount.IsZero() {
+		return money.New(0, currency), nil
+	}
+	if rate == nil || rate.Base != amount.Currency() || rate.Currency != currency {
+		return money.Money{}, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, amount.Currency(), currency)
+	}
+	return amount.Convert(currency, rate.Rate), nil
+}
+
+// PreparePrices checks the explicit prices in other currencies: one per
+// currency, none in the product's own currency and none negative.
+func (p *Product) PreparePrices() error {
+	seen := make(map[string]bool, len(p.Prices))
+	for _, price := range p.Prices {
+		switch {
+		case price.Currency() == p.Price.Currency():
+			return fmt.Errorf("prices must not repeat the %s price", price.Currency())
+		case seen[price.Currency()]:
+			return fmt.Errorf("prices lists %s twice", price.Currency())
+		case price.Sign() < 0:
+			return fmt.Errorf("price in %s must not be negative", price.Currency())
+		}
+		seen[price.Currency()] = true
+	}
+	return nil
+}
+
+// PriceIn returns the product's own price in currency: the explicit entry of
+// Prices when there is one, otherwise Price converted at rate.
+func (p Product) PriceIn(currency string, rate *ExchangeRate) (money.Money, error) {
+	for _, price := range p.Prices {
+		if price.Currency() == currency {
+			return price, nil
+		}
+	}
+	return ConvertPrice(p.Price, currency, rate)
+}
+
+// InCurrency returns a copy of the product with its price and option price
+// deltas shown in currency.
+func (p Product) InCurrency(currency string, rate *ExchangeRate) (Product, error) {
+	price, err := p.PriceIn(currency, rate)
+	if err != nil {
+		return Product{}, err
+	}
+	p.Price = price
+
+	groups := make([]OptionGroup, len(p.OptionGroups))
+	for i, group := range p.OptionGroups {
+		group.Options = append([]Option(nil), group.Options...)
+		for j := range group.Options {
+			if group.Options[j].PriceDelta, err = ConvertPrice(group.Options[j].PriceDelta, currency, rate); err != nil {
+				return Product{}, err
+			}
+		}
+		groups[i] = group
+	}
+	p.OptionGroups = groups
+	return p, nil
+}
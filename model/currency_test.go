@@ -0,0 +1,62 @@
+package model
+
+import (
+	"errors"
+	"restaurant/money"
+	"testing"
+)
+
+func TestPriceInCurrency(t *testing.T) {
+	rate, err := money.ParseRate("0.90")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	toEUR := &ExchangeRate{Base: "USD", Currency: "EUR", Rate: rate}
+
+	product := pizza()
+	product.Prices = []money.Money{money.MustParse("10.00", "GBP")}
+	if err := product.PreparePrices(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if price, err := product.PriceIn("GBP", nil); err != nil || price.String() != "10.00 GBP" {
+		t.Errorf("Expected the explicit GBP price, but got %s (%v)", price, err)
+	}
+	if price, err := product.PriceIn("EUR", toEUR); err != nil || price.String() != "11.25 EUR" {
+		t.Errorf("Expected 12.50 USD converted to 11.25 EUR, but got %s (%v)", price, err)
+	}
+	if _, err := product.PriceIn("EUR", nil); !errors.Is(err, ErrNoExchangeRate) {
+		t.Errorf("Expected %v, but got %v", ErrNoExchangeRate, err)
+	}
+
+	selected, _ := product.SelectOptions([]OptionSelection{{GroupID: 1, OptionID: 2}})
+	if price, err := product.UnitPriceIn("EUR", toEUR, selected); err != nil || price.String() != "14.85 EUR" {
+		t.Errorf("Expected 11.25 + 3.60 EUR, but got %s (%v)", price, err)
+	}
+
+	shown, err := product.InCurrency("EUR", toEUR)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if shown.OptionGroups[0].Options[1].PriceDelta.String() != "3.60 EUR" {
+		t.Errorf("Expected the option delta in EUR, but got %s", shown.OptionGroups[0].Options[1].PriceDelta)
+	}
+	if product.OptionGroups[0].Options[1].PriceDelta.String() != "4.00 USD" {
+		t.Errorf("Expected the original product to stay in USD, but got %s", product.OptionGroups[0].Options[1].PriceDelta)
+	}
+}
+
+func TestPreparePricesRejectsDuplicates(t *testing.T) {
+	tests := map[string][]money.Money{
+		"own currency":    {money.MustParse("1.00", "USD")},
+		"listed twice":    {money.MustParse("1.00", "EUR"), money.MustParse("2.00", "EUR")},
+		"negative amount": {money.MustParse("-1.00", "EUR")},
+	}
+	for name, prices := range tests {
+		product := pizza()
+		product.Prices = prices
+		if err := product.PreparePrices(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Allergens are the fourteen allergens that must be declared under EU food
+// information rules.
+var Allergens = []string{
+	"celery",
+	"crustaceans",
+	"dairy",
+	"eggs",
+	"fish",
+	"gluten",
+	"lupin",
+	"molluscs",
+	"mustard",
+	"nuts",
+	"peanuts",
+	"sesame",
+	"soy",
+	"sulphites",
+}
+
+var DietaryLabels = []string{
+	"dairy_free",
+	"gluten_free",
+	"halal",
+	"kosher",
+	"vegan",
+	"vegetarian",
+}
+
+// dietaryConflicts lists allergens a product with the label cannot contain.
+var dietaryConflicts = map[string][]string{
+	"vegan":       {"dairy", "eggs", "fish", "crustaceans", "molluscs"},
+	"vegetarian":  {"fish", "crustaceans", "molluscs"},
+	"gluten_free": {"gluten"},
+	"dairy_free":  {"dairy"},
+}
+
+// Nutrition facts per serving. Weights are in grams.
+type Nutrition struct {
+	ServingSize   string  `json:"serving_size,omitempty" validate:"max=100"`
+	Calories      int     `json:"calories" validate:"min=0"`
+	Protein       float64 `json:"protein" validate:"min=0"`
+	Carbohydrates float64 `json:"carbohydrates" validate:"min=0"`
+	Sugar         float64 `json:"sugar" validate:"min=0"`
+	Fat           float64 `json:"fat" validate:"min=0"`
+	SaturatedFat  float64 `json:"saturated_fat" validate:"min=0"`
+	Fiber         float64 `json:"fiber" validate:"min=0"`
+	Salt          float64 `json:"salt" validate:"min=0"`
+}
+
+// AllergensDeclared reports whether the product lists its allergens. An
+// empty list declares that it contains none; a missing one (null) declares
+// nothing.
+func (p Product) AllergensDeclared() bool {
+	return p.Allergens != nil
+}
+
+func (p Product) ContainsAllergen(allergen string) bool {
+	for _, a := range p.Allergens {
+		if strings.EqualFold(a, allergen) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Product) HasDietaryLabel(label string) bool {
+	for _, l := range p.DietaryLabels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareDietary lower-cases allergens and dietary labels, drops duplicates
+// and checks them against the known lists. A label that contradicts a
+// declared allergen, such as vegan with dairy, is rejected.
+func (p *Product) PrepareDietary() error {
+	var err error
+	if p.Allergens, err = normalizeList(p.Allergens, Allergens, "allergen"); err != nil {
+		return err
+	}
+	if p.DietaryLabels, err = normalizeList(p.DietaryLabels, DietaryLabels, "dietary label"); err != nil {
+		return err
+	}
+
+	for _, label := range p.DietaryLabels {
+		for _, allergen := range dietaryConflicts[label] {
+			if p.ContainsAllergen(allergen) {
+				return fmt.Errorf("a %s product cannot contain %s", label, allergen)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseAllergens splits a comma separated list and checks every entry.
+func ParseAllergens(value string) ([]string, error) {
+	return normalizeList(strings.Split(value, ","), Allergens, "allergen")
+}
+
+// ParseDietaryLabels splits a comma separated list and checks every entry.
+func ParseDietaryLabels(value string) ([]string, error) {
+	return normalizeList(strings.Split(value, ","), DietaryLabels, "dietary label")
+}
+
+// normalizeList keeps a nil list nil, so an undeclared list stays apart
+// from an empty one.
+func normalizeList(values, known []string, kind string) ([]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+		if !contains(known, value) {
+			return nil, fmt.Errorf("unknown %s %q, expected one of %s", kind, value, strings.Join(known, ", "))
+		}
+		seen[value] = true
+		normalized = append(normalized, value)
+	}
+	return normalized, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"restaurant/money"
+	"restaurant/validate"
+	"testing"
+)
+
+func TestPrepareDietary(t *testing.T) {
+	product := Product{
+		Allergens:     []string{"Gluten", " nuts", "gluten"},
+		DietaryLabels: []string{"Vegetarian"},
+	}
+
+	if err := product.PrepareDietary(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(product.Allergens) != 2 || product.Allergens[0] != "gluten" || product.Allergens[1] != "nuts" {
+		t.Errorf("Expected normalized allergens, but got %v", product.Allergens)
+	}
+
+	undeclared := Product{}
+	if err := undeclared.PrepareDietary(); err != nil || undeclared.AllergensDeclared() {
+		t.Errorf("Expected missing allergens to stay undeclared, but got %v and error %v", undeclared.Allergens, err)
+	}
+	none := Product{Allergens: []string{}}
+	if err := none.PrepareDietary(); err != nil || !none.AllergensDeclared() {
+		t.Errorf("Expected an empty allergen list to stay declared, but got %v and error %v", none.Allergens, err)
+	}
+
+	invalid := map[string]Product{
+		"unknown allergen": {Allergens: []string{"chocolate"}},
+		"unknown label":    {DietaryLabels: []string{"paleo"}},
+		"vegan with dairy": {Allergens: []string{"dairy"}, DietaryLabels: []string{"vegan"}},
+	}
+	for name, product := range invalid {
+		if err := product.PrepareDietary(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseAllergens(t *testing.T) {
+	allergens, err := ParseAllergens("nuts, Dairy,")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(allergens) != 2 || allergens[0] != "nuts" || allergens[1] != "dairy" {
+		t.Errorf("Expected [nuts dairy], but got %v", allergens)
+	}
+
+	if _, err := ParseAllergens("nuts,wood"); err == nil {
+		t.Errorf("Expected an error for an unknown allergen")
+	}
+}
+
+func TestNutritionValidation(t *testing.T) {
+	product := Product{
+		Name:      "Salad",
+		Price:     money.MustParse("8.99", "USD"),
+		Nutrition: &Nutrition{Calories: -10, Salt: 0.6},
+	}
+
+	errs := validate.Struct(product)
+	if len(errs) != 1 || errs[0].Field != "nutrition.calories" || errs[0].Rule != "min" {
+		t.Errorf("Expected negative calories to be rejected, but got %v", errs)
+	}
+}
@@ -0,0 +1,20 @@
+package model
+
+// ProductImage points at an uploaded product image and its thumbnails.
+// URLs are paths served by the product service.
+type ProductImage struct {
+	URL         string            `json:"url"`
+	ContentType string            `json:"content_type"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+}
+
+// URLs returns the image URL followed by the thumbnail URLs.
+func (i ProductImage) URLs() []string {
+	urls := []string{i.URL}
+	for _, url := range i.Thumbnails {
+		urls = append(urls, url)
+	}
+	return urls
+}
@@ -0,0 +1,27 @@
+package model
+
+// Ingredient is a stock item shared between product recipes. Stock is kept in
+// the ingredient's own unit (grams, millilitres, pieces).
+type Ingredient struct {
+	ID                int     `json:"id"`
+	RestaurantID      int     `json:"restaurant_id"`
+	Name              string  `json:"name" validate:"required,max=100"`
+	Unit              string  `json:"unit" validate:"required,max=20"`
+	Stock             float64 `json:"stock" validate:"min=0"`
+	LowStockThreshold float64 `json:"low_stock_threshold" validate:"min=0"`
+}
+
+func (i Ingredient) LowStock() bool {
+	return i.Stock <= i.LowStockThreshold
+}
+
+// RecipeItem is the quantity of an ingredient used to make one product.
+type RecipeItem struct {
+	IngredientID int     `json:"ingredient_id" validate:"gt=0"`
+	Quantity     float64 `json:"quantity" validate:"gt=0"`
+}
+
+type IngredientStockRequest struct {
+	Change float64 `json:"change"`
+	Reason string  `json:"reason" validate:"required"`
+}
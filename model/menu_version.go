@@ -0,0 +1,92 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	DraftCreate  = "create"
+	DraftUpdate  = "update"
+	DraftArchive = "archive"
+)
+
+// DraftChange is a product change staged for the next menu publish instead
+// of going live at once. Product is the product as it will be published; it
+// is nil for DraftArchive. BaseVersion is the version of the live product the
+// change was made against, so publishing can detect edits made since.
+type DraftChange struct {
+	RestaurantID int       `json:"restaurant_id"`
+	ProductID    int       `json:"product_id"`
+	Action       string    `json:"action"`
+	Product      *Product  `json:"product,omitempty"`
+	BaseVersion  int       `json:"base_version,omitempty"`
+	StagedBy     int       `json:"staged_by,omitempty"`
+	StagedAt     time.Time `json:"staged_at"`
+}
+
+// MenuVersion is a published state of a restaurant's menu: every product,
+// archived ones included, as it was right after the publish. Rolling back to
+// a version publishes its products again as a new version.
+type MenuVersion struct {
+	RestaurantID   int       `json:"restaurant_id"`
+	Version        int       `json:"version"`
+	Note           string    `json:"note,omitempty"`
+	Changes        int       `json:"changes"`
+	RolledBackFrom int       `json:"rolled_back_from,omitempty"`
+	PublishedBy    int       `json:"published_by,omitempty"`
+	PublishedAt    time.Time `json:"published_at"`
+	ProductCount   int       `json:"product_count"`
+	Products       []Product `json:"products,omitempty"`
+}
+
+type PublishRequest struct {
+	Note string `json:"note" validate:"max=500"`
+}
+
+// Summary returns the version without its products.
+func (v MenuVersion) Summary() MenuVersion {
+	v.Products = nil
+	return v
+}
+
+// ApplyDrafts returns the products that would be on the menu once drafts are
+// published, ordered by ID. Archived products are left out.
+func ApplyDrafts(products []Product, drafts []DraftChange) []Product {
+	byID := make(map[int]Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, draft := range drafts {
+		switch draft.Action {
+		case DraftCreate, DraftUpdate:
+			product := *draft.Product
+			// Like an update, a publish keeps what is managed on the live
+			// product itself.
+			if live, exists := byID[draft.ProductID]; exists {
+				product.Popularity = live.Popularity
+				product.Stock = live.Stock
+				product.Recipe = live.Recipe
+				product.Image = live.Image
+				product.Translations = live.Translations
+				product.IngredientShortage = live.IngredientShortage
+				product.RefreshSoldOut()
+			}
+			byID[draft.ProductID] = product
+		case DraftArchive:
+			delete(byID, draft.ProductID)
+		}
+	}
+
+	menu := make([]Product, 0, len(byID))
+	for _, product := range byID {
+		if !product.Archived() {
+			menu = append(menu, product)
+		}
+	}
+	sort.Slice(menu, func(i, j int) bool {
+		return menu[i].ID < menu[j].ID
+	})
+	return menu
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"restaurant/money"
+	"testing"
+)
+
+func TestApplyDrafts(t *testing.T) {
+	live := []Product{
+		{ID: 1, Name: "Soto", Price: money.MustParse("3.00", "USD"), Stock: 7, Popularity: 4},
+		{ID: 2, Name: "Bakso", Price: money.MustParse("2.50", "USD")},
+	}
+	updated := Product{ID: 1, Name: "Soto Ayam", Price: money.MustParse("3.50", "USD")}
+	created := Product{ID: 3, Name: "Es Cendol", Price: money.MustParse("1.25", "USD")}
+
+	menu := ApplyDrafts(live, []DraftChange{
+		{ProductID: 1, Action: DraftUpdate, Product: &updated},
+		{ProductID: 2, Action: DraftArchive},
+		{ProductID: 3, Action: DraftCreate, Product: &created},
+	})
+
+	if len(menu) != 2 || menu[0].ID != 1 || menu[1].ID != 3 {
+		t.Fatalf("Expected products 1 and 3, but got %+v", menu)
+	}
+	if menu[0].Name != "Soto Ayam" || menu[0].Stock != 7 || menu[0].Popularity != 4 {
+		t.Errorf("Expected the staged update to keep live stock and popularity, but got %+v", menu[0])
+	}
+	if live[0].Name != "Soto" {
+		t.Errorf("Expected the live products to stay unchanged")
+	}
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"fmt"
+	"restaurant/money"
+)
+
+// OptionGroup is a set of choices offered with a product, such as sizes or
+// toppings. MinSelections and MaxSelections bound how many options an order
+// picks from the group; a MaxSelections of zero means no upper limit.
+type OptionGroup struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name" validate:"required,max=100"`
+	Required      bool     `json:"required"`
+	MinSelections int      `json:"min_selections" validate:"min=0"`
+	MaxSelections int      `json:"max_selections" validate:"min=0"`
+	Options       []Option `json:"options" validate:"required"`
+}
+
+// Option is one choice of a group. PriceDelta is added to the product price
+// for every unit ordered and may be negative.
+type Option struct {
+	ID         int         `json:"id"`
+	Name       string      `json:"name" validate:"required,max=100"`
+	PriceDelta money.Money `json:"price_delta"`
+}
+
+// OptionSelection is an option picked in an order request.
+type OptionSelection struct {
+	GroupID  int `json:"group_id" validate:"gt=0"`
+	OptionID int `json:"option_id" validate:"gt=0"`
+}
+
+// SelectedOption is an option as stored on an order. Names and price are
+// copied so the order keeps its meaning when the product changes later.
+type SelectedOption struct {
+	GroupID    int         `json:"group_id"`
+	GroupName  string      `json:"group_name"`
+	OptionID   int         `json:"option_id"`
+	OptionName string      `json:"option_name"`
+	PriceDelta money.Money `json:"price_delta"`
+}
+
+// minSelections is the effective lower bound; a required group needs at
+// least one option.
+func (g OptionGroup) minSelections() int {
+	if g.Required && g.MinSelections < 1 {
+		return 1
+	}
+	return g.MinSelections
+}
+
+// PrepareOptionGroups assigns IDs to groups and options that have none and
+// checks that the selection bounds can be satisfied.
+func (p *Product) PrepareOptionGroups() error {
+	nextGroupID := 1
+	for _, group := range p.OptionGroups {
+		if group.ID >= nextGroupID {
+			nextGroupID = group.ID + 1
+		}
+	}
+
+	groupIDs := make(map[int]bool, len(p.OptionGroups))
+	for i := range p.OptionGroups {
+		group := &p.OptionGroups[i]
+		if group.ID == 0 {
+			group.ID = nextGroupID
+			nextGroupID++
+		}
+		if groupIDs[group.ID] {
+			return fmt.Errorf("option group %d is listed twice", group.ID)
+		}
+		groupIDs[group.ID] = true
+
+		if group.Name == "" {
+			return fmt.Errorf("option group %d needs a name", group.ID)
+		}
+		if group.MinSelections < 0 || group.MaxSelections < 0 {
+			return fmt.Errorf("option group %q has negative selection bounds", group.Name)
+		}
+		if group.MaxSelections > 0 && group.minSelections() > group.MaxSelections {
+			return fmt.Errorf("option group %q requires more selections than it allows", group.Name)
+		}
+		if group.minSelections() > len(group.Options) {
+			return fmt.Errorf("option group %q requires more selections than it has options", group.Name)
+		}
+
+		nextOptionID := 1
+		for _, option := range group.Options {
+			if option.ID >= nextOptionID {
+				nextOptionID = option.ID + 1
+			}
+		}
+
+		optionIDs := make(map[int]bool, len(group.Options))
+		for j := range group.Options {
+			option := &group.Options[j]
+			if option.ID == 0 {
+				option.ID = nextOptionID
+				nextOptionID++
+			}
+			if optionIDs[option.ID] {
+				return fmt.Errorf("option %d of group %q is listed twice", option.ID, group.Name)
+			}
+			optionIDs[option.ID] = true
+
+			if option.Name == "" {
+				return fmt.Errorf("option %d of group %q needs a name", option.ID, group.Name)
+			}
+			if _, err := p.Price.Add(option.PriceDelta); err != nil {
+				return fmt.Errorf("option %q of group %q is not priced in %s", option.Name, group.Name, p.Price.Currency())
+			}
+		}
+	}
+	return nil
+}
+
+// SelectOptions validates an order's option selections against the product's
+// option groups and resolves them. Every group must end up within its
+// selection bounds, and an option can be picked only once.
+func (p Product) SelectOptions(selections []OptionSelection) ([]SelectedOption, error) {
+	selected := make([]SelectedOption, 0, len(selections))
+	counts := make(map[int]int, len(p.OptionGroups))
+	seen := make(map[OptionSelection]bool, len(selections))
+
+	for _, selection := range selections {
+		if seen[selection] {
+			return nil, fmt.Errorf("option %d of group %d is selected twice", selection.OptionID, selection.GroupID)
+		}
+		seen[selection] = true
+
+		group, option, found := p.findOption(selection)
+		if !found {
+			return nil, fmt.Errorf("option %d of group %d does not exist", selection.OptionID, selection.GroupID)
+		}
+
+		counts[group.ID]++
+		selected = append(selected, SelectedOption{
+			GroupID:    group.ID,
+			GroupName:  group.Name,
+			OptionID:   option.ID,
+			OptionName: option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+
+	for _, group := range p.OptionGroups {
+		count := counts[group.ID]
+		if count < group.minSelections() {
+			return nil, fmt.Errorf("option group %q needs at least %d selection(s)", group.Name, group.minSelections())
+		}
+		if group.MaxSelections > 0 && count > group.MaxSelections {
+			return nil, fmt.Errorf("option group %q allows at most %d selection(s)", group.Name, group.MaxSelections)
+		}
+	}
+
+	return selected, nil
+}
+
+// UnitPrice is the price of one unit with the selected options applied.
+func (p Product) UnitPrice(selected []SelectedOption) (money.Money, error) {
+	return p.UnitPriceIn(p.Price.Currency(), nil, selected)
+}
+
+// UnitPriceIn is UnitPrice in currency; see PriceIn and ConvertPrice for how
+// the product price and option deltas are converted.
+func (p Product) UnitPriceIn(currency string, rate *ExchangeRate, selected []SelectedOption) (money.Money, error) {
+	price, err := p.PriceIn(currency, rate)
+	if err != nil {
+		return money.Money{}, err
+	}
+	for _, option := range selected {
+		delta, err := ConvertPrice(option.PriceDelta, currency, rate)
+		if err == nil {
+			price, err = price.Add(delta)
+		}
+		if err != nil {
+			return money.Money{}, fmt.Errorf("option %q: %w", option.OptionName, err)
+		}
+	}
+	return price, nil
+}
+
+func (p Product) findOption(selection OptionSelection) (OptionGroup, Option, bool) {
+	for _, group := range p.OptionGroups {
+		if group.ID != selection.GroupID {
+			continue
+		}
+		for _, option := range group.Options {
+			if option.ID == selection.OptionID {
+				return group, option, true
+			}
+		}
+	}
+	return OptionGroup{}, Option{}, false
+}
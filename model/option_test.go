@@ -0,0 +1,81 @@
+package model
+
+import (
+	"restaurant/money"
+	"testing"
+)
+
+func pizza() Product {
+	return Product{
+		Name:  "Pizza",
+		Price: money.MustParse("12.50", "USD"),
+		OptionGroups: []OptionGroup{
+			{ID: 1, Name: "Size", Required: true, MaxSelections: 1, Options: []Option{
+				{ID: 1, Name: "Regular"},
+				{ID: 2, Name: "Large", PriceDelta: money.MustParse("4.00", "USD")},
+			}},
+			{ID: 2, Name: "Extra toppings", MaxSelections: 2, Options: []Option{
+				{ID: 1, Name: "Extra cheese", PriceDelta: money.MustParse("1.50", "USD")},
+				{ID: 2, Name: "Mushrooms", PriceDelta: money.MustParse("1.00", "USD")},
+				{ID: 3, Name: "Olives", PriceDelta: money.MustParse("1.00", "USD")},
+			}},
+		},
+	}
+}
+
+func TestSelectOptions(t *testing.T) {
+	product := pizza()
+
+	selected, err := product.SelectOptions([]OptionSelection{{GroupID: 1, OptionID: 2}, {GroupID: 2, OptionID: 1}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if price, err := product.UnitPrice(selected); err != nil || price.String() != "18.00 USD" {
+		t.Errorf("Expected unit price 18.00 USD, but got %s (%v)", price, err)
+	}
+	if selected[0].GroupName != "Size" || selected[0].OptionName != "Large" {
+		t.Errorf("Expected names to be copied, but got %+v", selected[0])
+	}
+}
+
+func TestSelectOptionsRejectsInvalidSelections(t *testing.T) {
+	product := pizza()
+
+	tests := map[string][]OptionSelection{
+		"missing required group": {{GroupID: 2, OptionID: 1}},
+		"too many in group":      {{GroupID: 1, OptionID: 1}, {GroupID: 1, OptionID: 2}},
+		"unknown option":         {{GroupID: 1, OptionID: 9}},
+		"selected twice":         {{GroupID: 1, OptionID: 1}, {GroupID: 2, OptionID: 1}, {GroupID: 2, OptionID: 1}},
+	}
+
+	for name, selections := range tests {
+		if _, err := product.SelectOptions(selections); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPrepareOptionGroups(t *testing.T) {
+	product := Product{OptionGroups: []OptionGroup{
+		{Name: "Sauce", Options: []Option{{Name: "Ketchup"}, {ID: 5, Name: "Mayo"}}},
+	}}
+
+	if err := product.PrepareOptionGroups(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if group := product.OptionGroups[0]; group.ID != 1 || group.Options[0].ID != 6 || group.Options[1].ID != 5 {
+		t.Errorf("Expected IDs to be assigned, but got %+v", group)
+	}
+
+	product.OptionGroups[0].Required = true
+	product.OptionGroups[0].MinSelections = 3
+	if err := product.PrepareOptionGroups(); err == nil {
+		t.Errorf("Expected an error for a group that cannot be satisfied")
+	}
+
+	product = pizza()
+	product.OptionGroups[1].Options[0].PriceDelta = money.MustParse("1.50", "EUR")
+	if err := product.PrepareOptionGroups(); err == nil {
+		t.Errorf("Expected an error for an option priced in another currency")
+	}
+}
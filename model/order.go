@@ -1,16 +1,68 @@
 package model
 
+import (
+	"restaurant/money"
+	"time"
+)
+
 type Order struct {
-	ID         int     `json:"id"`
-	UserID     int     `json:"user_id"`
-	ProductID  int     `json:"product_id"`
-	Quantity   int     `json:"quantity"`
-	TotalPrice float64 `json:"total_price"`
+	ID           int         `json:"id"`
+	RestaurantID int         `json:"restaurant_id"`
+	UserID       int         `json:"user_id"`
+	ProductID    int         `json:"product_id"`
+	Quantity     int         `json:"quantity"`
+	TotalPrice   money.Money `json:"total_price"`
+	CreatedAt    time.Time   `json:"created_at,omitzero"`
+	// SaleID identifies the sale recorded with the product service for the
+	// order's stock.
+	SaleID string `json:"sale_id,omitempty"`
+
+	// Currency is what the order was charged in. ExchangeRate is the rate
+	// from the product's currency that priced it, if one was needed.
+	Currency     string        `json:"currency,omitempty"`
+	ExchangeRate *ExchangeRate `json:"exchange_rate,omitempty"`
+
+	ProductName string            `json:"product_name,omitempty"`
+	Options     []SelectedOption  `json:"options,omitempty"`
+	Components  []BundleComponent `json:"components,omitempty"`
+}
+
+// PendingSale is a sale the order service reported to the product service
+// that has not yet become an order. CancelRequested marks one that will not,
+// so the stock it took has to be put back.
+type PendingSale struct {
+	SaleID          string    `json:"sale_id"`
+	RestaurantID    int       `json:"restaurant_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	CancelRequested bool      `json:"cancel_requested"`
+	Attempts        int       `json:"attempts"`
 }
 
 type OrderRequest struct {
-	UserID     int     `json:"user_id"`
-	ProductID  int     `json:"product_id"`
-	Quantity   int     `json:"quantity"`
-	TotalPrice float64 `json:"total_price"`
+	UserID     int         `json:"user_id" validate:"gt=0"`
+	ProductID  int         `json:"product_id" validate:"gt=0"`
+	Quantity   int         `json:"quantity" validate:"gt=0,max=100000"`
+	TotalPrice money.Money `json:"total_price"`
+	Currency   string      `json:"currency"`
+
+	Options       []OptionSelection `json:"options"`
+	BundleChoices []BundleChoice    `json:"bundle_choices"`
+}
+
+// KitchenTicket is what the kitchen prepares for an order. Bundles are
+// expanded so every line is a single product.
+type KitchenTicket struct {
+	OrderID      int          `json:"order_id"`
+	RestaurantID int          `json:"restaurant_id"`
+	Product      string       `json:"product"`
+	Quantity     int          `json:"quantity"`
+	Options      []string     `json:"options,omitempty"`
+	Lines        []TicketLine `json:"lines"`
+}
+
+type TicketLine struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	Slot      string `json:"slot,omitempty"`
 }
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"restaurant/money"
+	"time"
+)
+
+const (
+	PriceChangePending   = "pending"
+	PriceChangeApplied   = "applied"
+	PriceChangeCancelled = "cancelled"
+)
+
+const (
+	PriceSourceUpdate    = "update"
+	PriceSourceImport    = "import"
+	PriceSourceScheduled = "scheduled"
+	PriceSourcePublish   = "publish"
+	PriceSourceRollback  = "rollback"
+)
+
+// PriceChange records a change to a product's price. Applied changes form the
+// price history. A scheduled change stays pending until EffectiveAt; once
+// applied, EffectiveAt is the moment the new price actually took effect and
+// ScheduledFor keeps the time originally requested.
+type PriceChange struct {
+	ID           int         `json:"id"`
+	RestaurantID int         `json:"restaurant_id"`
+	ProductID    int         `json:"product_id"`
+	OldPrice     money.Money `json:"old_price"`
+	NewPrice     money.Money `json:"new_price"`
+	Status       string      `json:"status"`
+	Source       string      `json:"source"`
+	Note         string      `json:"note,omitempty"`
+	ChangedBy    int         `json:"changed_by,omitempty"`
+	EffectiveAt  time.Time   `json:"effective_at"`
+	ScheduledFor *time.Time  `json:"scheduled_for,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type PriceChangeRequest struct {
+	Price       money.Money `json:"price" validate:"min=0"`
+	EffectiveAt time.Time   `json:"effective_at" validate:"required"`
+	Note        string      `json:"note" validate:"max=500"`
+}
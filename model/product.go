@@ -1,8 +1,102 @@
 package model
 
+import (
+	"restaurant/money"
+	"strings"
+	"time"
+)
+
 type Product struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	ID           int         `json:"id"`
+	RestaurantID int         `json:"restaurant_id"`
+	SKU          string      `json:"sku" validate:"max=64"`
+	Name         string      `json:"name" validate:"required,max=100"`
+	Description  string      `json:"description" validate:"max=1000"`
+	Price        money.Money `json:"price" validate:"min=0"`
+	CategoryIDs  []int       `json:"category_ids"`
+	Tags         []string    `json:"tags"`
+	Available    bool        `json:"available"`
+	Popularity   int         `json:"popularity"`
+
+	// Prices fixes the price in other currencies instead of converting Price
+	// with the restaurant's exchange rates.
+	Prices []money.Money `json:"prices,omitempty"`
+
+	// Translations holds the name and description in other languages, keyed
+	// by language tag such as "id".
+	Translations map[string]Translation `json:"translations,omitempty"`
+
+	// ArchivedAt is set while the product is archived: it is off the menu
+	// and cannot be ordered, but lookups by ID still return it.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// Version increases with every change to the product and backs its ETag.
+	Version int `json:"version"`
+
+	OptionGroups []OptionGroup `json:"option_groups"`
+	Bundle       *Bundle       `json:"bundle,omitempty"`
+	Schedule     *Schedule     `json:"schedule,omitempty"`
+
+	// Allergens is null until declared; an empty list declares none.
+	Allergens     []string   `json:"allergens"`
+	DietaryLabels []string   `json:"dietary_labels"`
+	Nutrition     *Nutrition `json:"nutrition,omitempty"`
+
+	Image *ProductImage `json:"image,omitempty"`
+
+	// Stock is only enforced when TrackStock is set. SoldOut is maintained by
+	// the product service and becomes true when tracked stock reaches zero or
+	// an ingredient of the recipe runs out.
+	TrackStock         bool         `json:"track_stock"`
+	Stock              int          `json:"stock" validate:"min=0"`
+	LowStockThreshold  int          `json:"low_stock_threshold" validate:"min=0"`
+	Recipe             []RecipeItem `json:"recipe"`
+	IngredientShortage bool         `json:"ingredient_shortage"`
+	SoldOut            bool         `json:"sold_out"`
+}
+
+// RefreshSoldOut recomputes SoldOut from stock and ingredient shortage.
+func (p *Product) RefreshSoldOut() {
+	p.SoldOut = (p.TrackStock && p.Stock <= 0) || p.IngredientShortage
+}
+
+func (p Product) UsesIngredient(ingredientID int) bool {
+	for _, item := range p.Recipe {
+		if item.IngredientID == ingredientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Orderable reports whether the product can currently be ordered.
+func (p Product) Orderable() bool {
+	return p.Available && !p.SoldOut && !p.Archived()
+}
+
+func (p Product) Archived() bool {
+	return p.ArchivedAt != nil
+}
+
+// LowStock reports whether tracked stock is at or below the threshold.
+func (p Product) LowStock() bool {
+	return p.TrackStock && p.Stock <= p.LowStockThreshold
+}
+
+func (p Product) InCategory(categoryID int) bool {
+	for _, id := range p.CategoryIDs {
+		if id == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Product) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
 }
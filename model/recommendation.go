@@ -0,0 +1,72 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	RecommendationOrderedTogether = "ordered_together"
+	RecommendationSharedTags      = "shared_tags"
+)
+
+// Recommendation suggests a product to offer alongside another one. Score is
+// the number of visits that ordered both products, or the number of tags they
+// share when Reason is RecommendationSharedTags.
+type Recommendation struct {
+	ProductID int      `json:"product_id"`
+	Reason    string   `json:"reason"`
+	Score     int      `json:"score"`
+	Product   *Product `json:"product,omitempty"`
+}
+
+// OrderedTogether counts, for every other product, the visits in which it
+// was ordered together with productID, most frequent first. A visit is one
+// user's orders placed no more than gap apart; orders without a time, such
+// as fixtures, form one visit per user.
+func OrderedTogether(orders []Order, productID int, gap time.Duration) []Recommendation {
+	byUser := make(map[int][]Order)
+	for _, order := range orders {
+		byUser[order.UserID] = append(byUser[order.UserID], order)
+	}
+
+	counts := make(map[int]int)
+	for _, userOrders := range byUser {
+		sort.SliceStable(userOrders, func(i, j int) bool {
+			return userOrders[i].CreatedAt.Before(userOrders[j].CreatedAt)
+		})
+
+		visit := make(map[int]bool)
+		for i, order := range userOrders {
+			if i > 0 && order.CreatedAt.Sub(userOrders[i-1].CreatedAt) > gap {
+				countVisit(visit, productID, counts)
+				visit = make(map[int]bool)
+			}
+			visit[order.ProductID] = true
+		}
+		countVisit(visit, productID, counts)
+	}
+
+	recommendations := make([]Recommendation, 0, len(counts))
+	for id, count := range counts {
+		recommendations = append(recommendations, Recommendation{ProductID: id, Reason: RecommendationOrderedTogether, Score: count})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].ProductID < recommendations[j].ProductID
+	})
+	return recommendations
+}
+
+func countVisit(visit map[int]bool, productID int, counts map[int]int) {
+	if !visit[productID] {
+		return
+	}
+	for id := range visit {
+		if id != productID {
+			counts[id]++
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderedTogether(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	orders := []Order{
+		{UserID: 1, ProductID: 1, CreatedAt: start},
+		{UserID: 1, ProductID: 2, CreatedAt: start.Add(10 * time.Minute)},
+		{UserID: 1, ProductID: 3, CreatedAt: start.Add(5 * time.Hour)},
+		{UserID: 2, ProductID: 2, CreatedAt: start},
+		{UserID: 2, ProductID: 1, CreatedAt: start.Add(time.Hour)},
+		{UserID: 2, ProductID: 4, CreatedAt: start.Add(90 * time.Minute)},
+		{UserID: 3, ProductID: 4},
+		{UserID: 3, ProductID: 5},
+	}
+
+	recommendations := OrderedTogether(orders, 1, 2*time.Hour)
+	if len(recommendations) != 2 {
+		t.Fatalf("Expected 2 recommendations, but got %+v", recommendations)
+	}
+	if recommendations[0].ProductID != 2 || recommendations[0].Score != 2 {
+		t.Errorf("Expected product 2 ordered together twice, but got %+v", recommendations[0])
+	}
+	if recommendations[1].ProductID != 4 || recommendations[1].Score != 1 {
+		t.Errorf("Expected product 4 ordered together once, but got %+v", recommendations[1])
+	}
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Schedules name IANA time zones; the services run on images without a
+	// zoneinfo database.
+	_ "time/tzdata"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// TimeWindow is a daily opening period in "HH:MM" local time. Days holds
+// weekday abbreviations (mon, tue, ...); an empty list means every day. A
+// window whose end is before its start runs past midnight, and equal start
+// and end cover the whole day.
+type TimeWindow struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// HolidayOverride replaces the regular windows on one date (YYYY-MM-DD in
+// the schedule's time zone). A closed override makes the whole date
+// unavailable.
+type HolidayOverride struct {
+	Date    string       `json:"date"`
+	Name    string       `json:"name,omitempty"`
+	Closed  bool         `json:"closed"`
+	Windows []TimeWindow `json:"windows,omitempty"`
+}
+
+// Schedule limits when a product or category can be ordered. Timezone is an
+// IANA name and defaults to UTC.
+type Schedule struct {
+	Timezone  string            `json:"timezone"`
+	Windows   []TimeWindow      `json:"windows"`
+	Overrides []HolidayOverride `json:"overrides,omitempty"`
+}
+
+func (s Schedule) Validate() error {
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf("invalid timezone %q", s.Timezone)
+	}
+
+	// A schedule without windows would never be open; a product that
+	// should never be ordered is made unavailable instead.
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("schedule needs at least one window")
+	}
+	if err := validateWindows(s.Windows); err != nil {
+		return err
+	}
+
+	dates := make(map[string]bool, len(s.Overrides))
+	for _, override := range s.Overrides {
+		if _, err := time.Parse(time.DateOnly, override.Date); err != nil {
+			return fmt.Errorf("invalid override date %q, expected YYYY-MM-DD", override.Date)
+		}
+		if dates[override.Date] {
+			return fmt.Errorf("override date %s is listed twice", override.Date)
+		}
+		dates[override.Date] = true
+
+		if !override.Closed && len(override.Windows) == 0 {
+			return fmt.Errorf("override on %s needs windows unless it is closed", override.Date)
+		}
+		if err := validateWindows(override.Windows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AvailableAt reports whether t falls into one of the schedule's windows,
+// taking holiday overrides into account. A window running past midnight
+// belongs to the date it starts on, so its early hours follow that date's
+// override rather than the next one's.
+func (s Schedule) AvailableAt(t time.Time) bool {
+	location, err := s.location()
+	if err != nil {
+		return false
+	}
+	local := t.In(location)
+
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+
+	for _, window := range s.windowsOn(local) {
+		start, _ := parseClock(window.Start)
+		end, _ := parseClock(window.End)
+
+		switch {
+		case start == end:
+			if window.on(today) {
+				return true
+			}
+		case start < end:
+			if window.on(today) && minute >= start && minute < end {
+				return true
+			}
+		default:
+			if window.on(today) && minute >= start {
+				return true
+			}
+		}
+	}
+
+	previous := local.AddDate(0, 0, -1)
+	for _, window := range s.windowsOn(previous) {
+		start, _ := parseClock(window.Start)
+		end, _ := parseClock(window.End)
+
+		if start > end && window.on(previous.Weekday()) && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+// windowsOn returns the windows starting on the date of local time t. A date
+// closed by an override has none, which leaves the night before it alone.
+func (s Schedule) windowsOn(t time.Time) []TimeWindow {
+	date := t.Format(time.DateOnly)
+	for _, override := range s.Overrides {
+		if override.Date == date {
+			if override.Closed {
+				return nil
+			}
+			return override.Windows
+		}
+	}
+	return s.Windows
+}
+
+func (s Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+func (w TimeWindow) on(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+func validateWindows(windows []TimeWindow) error {
+	for _, window := range windows {
+		if _, err := parseClock(window.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(window.End); err != nil {
+			return err
+		}
+		for _, name := range window.Days {
+			if _, known := weekdays[strings.ToLower(name)]; !known {
+				return fmt.Errorf("invalid day %q, expected one of mon, tue, wed, thu, fri, sat, sun", name)
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock converts "HH:MM" to minutes after midnight.
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// AvailableAt applies the product's schedule, or when it has none, the
+// schedules of its categories: the product can be ordered if any of its
+// categories is open, and categories without a schedule are always open.
+func (p Product) AvailableAt(t time.Time, categories []Category) bool {
+	if p.Schedule != nil {
+		return p.Schedule.AvailableAt(t)
+	}
+
+	scheduled := false
+	for _, category := range categories {
+		if !p.InCategory(category.ID) {
+			continue
+		}
+		if category.Schedule == nil || category.Schedule.AvailableAt(t) {
+			return true
+		}
+		scheduled = true
+	}
+	return !scheduled
+}
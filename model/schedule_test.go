@@ -0,0 +1,119 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleAvailableAt(t *testing.T) {
+	schedule := Schedule{
+		Timezone: "Asia/Jakarta",
+		Windows: []TimeWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "06:00", End: "10:00"},
+			{Days: []string{"sat"}, Start: "22:00", End: "02:00"},
+		},
+		Overrides: []HolidayOverride{
+			{Date: "2026-08-17", Name: "Independence Day", Closed: true},
+		},
+	}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("Expected a valid schedule, but got %v", err)
+	}
+
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"weekday morning", time.Date(2026, 8, 18, 7, 30, 0, 0, jakarta), true},
+		{"weekday evening", time.Date(2026, 8, 18, 19, 0, 0, 0, jakarta), false},
+		{"same instant in UTC", time.Date(2026, 8, 18, 0, 30, 0, 0, time.UTC), true},
+		{"holiday", time.Date(2026, 8, 17, 7, 30, 0, 0, jakarta), false},
+		{"saturday night", time.Date(2026, 8, 22, 23, 0, 0, 0, jakarta), true},
+		{"past midnight into sunday", time.Date(2026, 8, 23, 1, 0, 0, 0, jakarta), true},
+		{"sunday morning", time.Date(2026, 8, 23, 7, 0, 0, 0, jakarta), false},
+	}
+
+	for _, test := range tests {
+		if got := schedule.AvailableAt(test.at); got != test.want {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	invalid := map[string]Schedule{
+		"timezone":   {Timezone: "Mars/Olympus", Windows: []TimeWindow{{Start: "08:00", End: "10:00"}}},
+		"clock":      {Windows: []TimeWindow{{Start: "8am", End: "10:00"}}},
+		"day":        {Windows: []TimeWindow{{Days: []string{"funday"}, Start: "08:00", End: "10:00"}}},
+		"date":       {Windows: []TimeWindow{{Start: "08:00", End: "10:00"}}, Overrides: []HolidayOverride{{Date: "25/12/2026", Closed: true}}},
+		"no windows": {},
+		"open override without windows": {
+			Windows:   []TimeWindow{{Start: "08:00", End: "10:00"}},
+			Overrides: []HolidayOverride{{Date: "2026-12-25"}},
+		},
+	}
+
+	for name, schedule := range invalid {
+		if err := schedule.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestScheduleOvernightWindowFollowsItsStartDate(t *testing.T) {
+	schedule := Schedule{
+		Windows: []TimeWindow{{Start: "20:00", End: "02:00"}},
+		Overrides: []HolidayOverride{
+			{Date: "2026-12-24", Name: "Christmas Eve", Windows: []TimeWindow{{Start: "12:00", End: "18:00"}}},
+			{Date: "2026-12-31", Name: "New Year's Eve", Closed: true},
+		},
+	}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("Expected a valid schedule, but got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"regular night", time.Date(2026, 12, 23, 1, 0, 0, 0, time.UTC), true},
+		{"after a shortened evening", time.Date(2026, 12, 25, 1, 0, 0, 0, time.UTC), false},
+		{"override hours", time.Date(2026, 12, 24, 13, 0, 0, 0, time.UTC), true},
+		{"night before the override", time.Date(2026, 12, 24, 1, 0, 0, 0, time.UTC), true},
+		{"night before a closed day", time.Date(2026, 12, 31, 1, 0, 0, 0, time.UTC), true},
+		{"closed day", time.Date(2026, 12, 31, 21, 0, 0, 0, time.UTC), false},
+		{"after a closed day", time.Date(2027, 1, 1, 1, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		if got := schedule.AvailableAt(test.at); got != test.want {
+			t.Errorf("%s: expected %v, but got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestProductAvailableAtUsesCategories(t *testing.T) {
+	breakfast := Category{ID: 1, Schedule: &Schedule{Windows: []TimeWindow{{Start: "07:00", End: "11:00"}}}}
+	drinks := Category{ID: 2}
+
+	morning := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2026, 1, 5, 20, 0, 0, 0, time.UTC)
+
+	pancakes := Product{CategoryIDs: []int{1}}
+	if !pancakes.AvailableAt(morning, []Category{breakfast, drinks}) || pancakes.AvailableAt(evening, []Category{breakfast, drinks}) {
+		t.Errorf("Expected pancakes to follow the breakfast schedule")
+	}
+
+	coffee := Product{CategoryIDs: []int{1, 2}}
+	if !coffee.AvailableAt(evening, []Category{breakfast, drinks}) {
+		t.Errorf("Expected coffee to stay available through its unscheduled category")
+	}
+
+	pancakes.Schedule = &Schedule{Windows: []TimeWindow{{Start: "00:00", End: "00:00"}}}
+	if !pancakes.AvailableAt(evening, []Category{breakfast}) {
+		t.Errorf("Expected the product schedule to win over the category schedule")
+	}
+}
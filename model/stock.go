@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+const (
+	StockReasonDelivery   = "delivery"
+	StockReasonWaste      = "waste"
+	StockReasonCorrection = "correction"
+	StockReasonSale       = "sale"
+	// StockReasonSaleCancelled puts back the units of a cancelled sale.
+	StockReasonSaleCancelled = "sale_cancelled"
+)
+
+// StockAdjustment records a single change to a product's stock level.
+type StockAdjustment struct {
+	ID           int       `json:"id"`
+	RestaurantID int       `json:"restaurant_id"`
+	ProductID    int       `json:"product_id"`
+	Change       int       `json:"change"`
+	Reason       string    `json:"reason"`
+	Note         string    `json:"note,omitempty"`
+	StockAfter   int       `json:"stock_after"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SaleItem is one product of a sale reported to the product service.
+type SaleItem struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// Sale is a sale recorded under an ID chosen by the caller. Reporting the same
+// ID again does not deduct anything twice, and the sale can be cancelled,
+// which puts back what it took.
+type Sale struct {
+	ID           string     `json:"id"`
+	RestaurantID int        `json:"restaurant_id"`
+	Items        []SaleItem `json:"items"`
+	// Restocked lists the units taken from tracked stock and Ingredients
+	// the ingredients consumed, which is what a cancellation gives back.
+	Restocked   []SaleItem   `json:"restocked,omitempty"`
+	Ingredients []RecipeItem `json:"ingredients,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CancelledAt time.Time    `json:"cancelled_at,omitzero"`
+}
+
+func (s Sale) Cancelled() bool {
+	return !s.CancelledAt.IsZero()
+}
+
+// SaleRequest reports sold units. SaleID is optional; with it the request
+// can be retried safely and the sale cancelled later.
+type SaleRequest struct {
+	SaleID string     `json:"sale_id" validate:"max=100"`
+	Items  []SaleItem `json:"items"`
+}
+
+type StockAdjustmentRequest struct {
+	Change int    `json:"change"`
+	Reason string `json:"reason" validate:"required"`
+	Note   string `json:"note" validate:"max=500"`
+}
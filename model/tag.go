@@ -0,0 +1,46 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 30
+)
+
+// PrepareTags trims tags, collapses inner whitespace and drops empty tags and
+// duplicates. Tags are free-form and compared without regard to case; the
+// first spelling of a tag is kept.
+func (p *Product) PrepareTags() error {
+	tags := make([]string, 0, len(p.Tags))
+	seen := make(map[string]bool, len(p.Tags))
+	for _, tag := range p.Tags {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		if len([]rune(tag)) > maxTagLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return fmt.Errorf("a product can have at most %d tags", maxTags)
+	}
+	p.Tags = tags
+	return nil
+}
+
+// SharedTags counts the tags both products have.
+func (p Product) SharedTags(other Product) int {
+	shared := 0
+	for _, tag := range p.Tags {
+		if other.HasTag(tag) {
+			shared++
+		}
+	}
+	return shared
+}
@@ -0,0 +1,18 @@
+package model
+
+import "testing"
+
+func TestPrepareTags(t *testing.T) {
+	product := Product{Tags: []string{" Spicy ", "spicy", "", "street  food"}}
+	if err := product.PrepareTags(); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if len(product.Tags) != 2 || product.Tags[0] != "Spicy" || product.Tags[1] != "street food" {
+		t.Errorf("Unexpected tags %q", product.Tags)
+	}
+
+	product.Tags = []string{"a tag that is far too long to be useful"}
+	if err := product.PrepareTags(); err == nil {
+		t.Errorf("Expected an error for a long tag")
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+// Translation is the name and description of a product or category in a
+// language other than the default one. An empty Description falls back to
+// the untranslated description.
+type Translation struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+// Localized returns the product with its name and description in language.
+// Without a translation for language the product is returned unchanged.
+func (p Product) Localized(language string) Product {
+	if translation, exists := p.Translations[language]; exists {
+		p.Name, p.Description = translation.apply(p.Name, p.Description)
+	}
+	return p
+}
+
+// Localized returns the category with its name and description in language,
+// like Product.Localized.
+func (c Category) Localized(language string) Category {
+	if translation, exists := c.Translations[language]; exists {
+		c.Name, c.Description = translation.apply(c.Name, c.Description)
+	}
+	return c
+}
+
+func (t Translation) apply(name, description string) (string, string) {
+	if t.Name != "" {
+		name = t.Name
+	}
+	if t.Description != "" {
+		description = t.Description
+	}
+	return name, description
+}
+
+// WithTranslation returns a copy of translations with language set, so
+// copies of a product or category taken earlier keep their own map.
+func WithTranslation(translations map[string]Translation, language string, translation Translation) map[string]Translation {
+	updated := make(map[string]Translation, len(translations)+1)
+	for l, t := range translations {
+		updated[l] = t
+	}
+	updated[language] = translation
+	return updated
+}
+
+// WithoutTranslation returns a copy of translations without language.
+func WithoutTranslation(translations map[string]Translation, language string) map[string]Translation {
+	updated := make(map[string]Translation, len(translations))
+	for l, t := range translations {
+		if l != language {
+			updated[l] = t
+		}
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+	return updated
+}
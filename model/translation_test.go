@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestLocalized(t *testing.T) {
+	product := pizza()
+	product.Description = "Tomato and mozzarella"
+	product.Translations = WithTranslation(nil, "id", Translation{Name: "Piza Margherita"})
+
+	localized := product.Localized("id")
+	if localized.Name != "Piza Margherita" || localized.Description != "Tomato and mozzarella" {
+		t.Errorf("Expected the Indonesian name and the default description, but got %q and %q", localized.Name, localized.Description)
+	}
+	if same := product.Localized("fr"); same.Name != product.Name {
+		t.Errorf("Expected the default name without a translation, but got %q", same.Name)
+	}
+
+	original := product.Translations
+	product.Translations = WithoutTranslation(product.Translations, "id")
+	if product.Translations != nil || len(original) != 1 {
+		t.Errorf("Expected the translation removed from a copy, but got %v and %v", product.Translations, original)
+	}
+}
@@ -1,17 +1,20 @@
 package model
 
+// User is a registered account. Only a hash of the password is kept, see
+// package password; it is left out of API responses and included in backups.
 type User struct {
-	ID       int    `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	ID           int    `json:"id"`
+	RestaurantID int    `json:"restaurant_id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash,omitempty"`
 }
 
 type UserLoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 type UserRegisterRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
 }
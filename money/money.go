@@ -0,0 +1,346 @@
+// Package money represents amounts of money as a whole number of minor units
+// (cents) together with an ISO 4217 currency code, so sums and products never
+// drift the way float64 prices do. Rounding only happens where a fraction
+// appears, such as percentage discounts, and rounds half to even.
+//
+// Amounts encode to JSON as {"amount": "15.99", "currency": "USD"}. A bare
+// JSON number or string such as 15.99 or "15.99" decodes in DefaultCurrency,
+// which keeps older clients and fixtures working.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for amounts given without a currency.
+const DefaultCurrency = "USD"
+
+var (
+	ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+	// ErrOverflow is returned when a result does not fit in the minor units
+	// an amount can hold.
+	ErrOverflow = errors.New("amount is out of range")
+)
+
+// minorDigits lists currencies whose minor unit is not a hundredth.
+var minorDigits = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"VND": 0,
+}
+
+// Money is an amount in the minor unit of its currency. The zero value is
+// zero in DefaultCurrency. A zero amount combines with any currency.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// New returns amount minor units of currency, e.g. New(1599, "USD") is 15.99.
+func New(amount int64, currency string) Money {
+	return Money{amount: amount, currency: strings.ToUpper(currency)}
+}
+
+// Parse reads a decimal amount such as "15.99" or "-0.50" in currency. More
+// decimal places than the currency has are rejected rather than rounded.
+func Parse(amount, currency string) (Money, error) {
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	digits := Digits(currency)
+
+	text := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+
+	whole, fraction, hasPoint := strings.Cut(text, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	if len(fraction) > digits {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, digits, currency)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", digits-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{amount: minor, currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on error. It is meant for fixed values
+// in code and tests.
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// ParseString reads the String form "15.99 USD". The currency may be left
+// out, in which case DefaultCurrency is used.
+func ParseString(s string) (Money, error) {
+	amount, currency, found := strings.Cut(strings.TrimSpace(s), " ")
+	if !found {
+		currency = DefaultCurrency
+	}
+	return Parse(amount, strings.TrimSpace(currency))
+}
+
+// Digits returns the number of decimal places of currency.
+func Digits(currency string) int {
+	if digits, ok := minorDigits[strings.ToUpper(currency)]; ok {
+		return digits
+	}
+	return 2
+}
+
+// Minor returns the amount in minor units.
+func (m Money) Minor() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() string {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount.
+func (m Money) Sign() int {
+	switch {
+	case m.amount < 0:
+		return -1
+	case m.amount > 0:
+		return 1
+	}
+	return 0
+}
+
+// Equal reports whether both amounts are the same. Zero equals zero in any
+// currency.
+func (m Money) Equal(other Money) bool {
+	return m.amount == other.amount && (m.amount == 0 || m.Currency() == other.Currency())
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := m.common(other)
+	if err != nil {
+		return Money{}, err
+	}
+	sum := m.amount + other.amount
+	if (other.amount > 0 && sum < m.amount) || (other.amount < 0 && sum > m.amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrOverflow, m, other)
+	}
+	return Money{amount: sum, currency: currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	currency, err := m.common(other)
+	if err != nil {
+		return Money{}, err
+	}
+	difference := m.amount - other.amount
+	if (other.amount > 0 && difference > m.amount) || (other.amount < 0 && difference < m.amount) {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrOverflow, m, other)
+	}
+	return Money{amount: difference, currency: currency}, nil
+}
+
+// Mul returns the amount multiplied by a quantity.
+func (m Money) Mul(quantity int) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(int64(quantity)))
+	if !product.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s x %d", ErrOverflow, m, quantity)
+	}
+	return Money{amount: product.Int64(), currency: m.currency}, nil
+}
+
+// BasisPoints returns a share of the amount given in hundredths of a percent,
+// rounded half to even to the minor unit. BasisPoints(1500) of 10.00 is 1.50.
+func (m Money) BasisPoints(basisPoints int) Money {
+	value := new(big.Rat).Mul(big.NewRat(m.amount, 10000), big.NewRat(int64(basisPoints), 1))
+	return Money{amount: roundHalfEven(value), currency: m.currency}
+}
+
+// Cmp compares two amounts of the same currency and returns -1, 0 or +1.
+func (m Money) Cmp(other Money) (int, error) {
+	if _, err := m.common(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.amount < other.amount:
+		return -1, nil
+	case m.amount > other.amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Compare orders amounts by currency code first and then by amount, which
+// gives a total order for sorting mixed currencies.
+func Compare(a, b Money) int {
+	if a.Currency() != b.Currency() {
+		return strings.Compare(a.Currency(), b.Currency())
+	}
+	c, _ := a.Cmp(b)
+	return c
+}
+
+// Float64 returns the amount in major units. It is meant for display and
+// validation only; do arithmetic on Money itself.
+func (m Money) Float64() float64 {
+	value, _ := new(big.Rat).SetFrac(big.NewInt(m.amount), pow10(Digits(m.Currency()))).Float64()
+	return value
+}
+
+// Amount returns the amount in major units as a decimal string, e.g. "15.99".
+func (m Money) Amount() string {
+	digits := Digits(m.Currency())
+	amount := m.amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	text := strconv.FormatInt(amount, 10)
+	if digits == 0 {
+		return sign + text
+	}
+	if len(text) <= digits {
+		text = strings.Repeat("0", digits-len(text)+1) + text
+	}
+	return sign + text[:len(text)-digits] + "." + text[len(text)-digits:]
+}
+
+// String returns the amount followed by its currency, e.g. "15.99 USD".
+func (m Money) String() string {
+	return m.Amount() + " " + m.Currency()
+}
+
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount, err := json.Marshal(m.Amount())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonMoney{Amount: amount, Currency: m.Currency()})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = []byte(strings.TrimSpace(string(data)))
+	if string(data) == "null" {
+		return nil
+	}
+
+	currency := DefaultCurrency
+	if len(data) > 0 && data[0] == '{' {
+		var value jsonMoney
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		if len(value.Amount) == 0 {
+			return fmt.Errorf("money needs an amount")
+		}
+		if value.Currency != "" {
+			currency = value.Currency
+		}
+		data = value.Amount
+	}
+
+	var amount string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+	} else {
+		amount = string(data)
+	}
+
+	parsed, err := Parse(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) common(other Money) (string, error) {
+	switch {
+	case m.Currency() == other.Currency(), other.amount == 0:
+		return m.currency, nil
+	case m.amount == 0:
+		return other.currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency(), other.Currency())
+}
+
+// ParseCurrency checks and upper-cases a three-letter currency code.
+func ParseCurrency(currency string) (string, error) {
+	return normalizeCurrency(currency)
+}
+
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return "", fmt.Errorf("invalid currency %q", currency)
+	}
+	for _, letter := range currency {
+		if letter < 'A' || letter > 'Z' {
+			return "", fmt.Errorf("invalid currency %q", currency)
+		}
+	}
+	return currency, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundHalfEven rounds value to the nearest integer, ties to the even one.
+func roundHalfEven(value *big.Rat) int64 {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	if c := twice.Cmp(value.Denom()); c > 0 || (c == 0 && quotient.Bit(0) == 1) {
+		quotient.Add(quotient, big.NewInt(int64(value.Num().Sign())))
+	}
+	return quotient.Int64()
+}
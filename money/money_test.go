@@ -0,0 +1,170 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		minor    int64
+		text     string
+	}{
+		{"15.99", "USD", 1599, "15.99 USD"},
+		{"15.9", "usd", 1590, "15.90 USD"},
+		{"7", "EUR", 700, "7.00 EUR"},
+		{"-0.05", "USD", -5, "-0.05 USD"},
+		{"1500", "JPY", 1500, "1500 JPY"},
+		{"1.234", "KWD", 1234, "1.234 KWD"},
+	}
+	for _, test := range tests {
+		m, err := Parse(test.amount, test.currency)
+		if err != nil {
+			t.Errorf("%s %s: expected no error, but got %v", test.amount, test.currency, err)
+			continue
+		}
+		if m.Minor() != test.minor || m.String() != test.text {
+			t.Errorf("%s %s: expected %d minor units and %q, but got %d and %q", test.amount, test.currency, test.minor, test.text, m.Minor(), m.String())
+		}
+	}
+
+	for _, invalid := range [][2]string{{"15.999", "USD"}, {"1.5", "JPY"}, {"abc", "USD"}, {"1.", "USD"}, {"1e3", "USD"}, {"1", "US"}} {
+		if _, err := Parse(invalid[0], invalid[1]); err == nil {
+			t.Errorf("%s %s: expected an error", invalid[0], invalid[1])
+		}
+	}
+}
+
+func TestArithmeticDoesNotDrift(t *testing.T) {
+	price := MustParse("15.99", "USD")
+	if total, err := price.Mul(2); err != nil || total.Amount() != "31.98" {
+		t.Errorf("Expected 2 x 15.99 to be 31.98, but got %s and %v", total, err)
+	}
+
+	total := New(0, "USD")
+	for i := 0; i < 10; i++ {
+		total, _ = total.Add(MustParse("0.10", "USD"))
+	}
+	if !total.Equal(MustParse("1.00", "USD")) {
+		t.Errorf("Expected ten times 0.10 to be 1.00, but got %s", total)
+	}
+
+	if _, err := price.Add(MustParse("1.00", "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected a currency mismatch, but got %v", err)
+	}
+	if sum, err := MustParse("1.00", "EUR").Add(Money{}); err != nil || sum.String() != "1.00 EUR" {
+		t.Errorf("Expected zero to add to any currency, but got %s and %v", sum, err)
+	}
+}
+
+func TestArithmeticReportsOverflow(t *testing.T) {
+	largest := New(math.MaxInt64, "USD")
+	smallest := New(math.MinInt64, "USD")
+	cent := New(1, "USD")
+
+	if _, err := largest.Add(cent); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected adding to the largest amount to overflow, but got %v", err)
+	}
+	if _, err := smallest.Sub(cent); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected subtracting from the smallest amount to overflow, but got %v", err)
+	}
+	if _, err := MustParse("7.68", "USD").Mul(40000000000000000); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected a huge quantity to overflow, but got %v", err)
+	}
+	if _, err := smallest.Mul(-1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected negating the smallest amount to overflow, but got %v", err)
+	}
+
+	if sum, err := largest.Add(New(-1, "USD")); err != nil || sum.Minor() != math.MaxInt64-1 {
+		t.Errorf("Expected %d, but got %d and %v", int64(math.MaxInt64-1), sum.Minor(), err)
+	}
+	if total, err := New(math.MaxInt64/2, "USD").Mul(2); err != nil || total.Minor() != math.MaxInt64-1 {
+		t.Errorf("Expected %d, but got %d and %v", int64(math.MaxInt64-1), total.Minor(), err)
+	}
+}
+
+func TestBasisPointsRoundHalfToEven(t *testing.T) {
+	tests := []struct {
+		amount      string
+		basisPoints int
+		result      string
+	}{
+		{"10.00", 1500, "1.50"},
+		{"0.25", 1000, "0.02"},
+		{"0.35", 1000, "0.04"},
+		{"27.48", 1500, "4.12"},
+		{"-0.25", 1000, "-0.02"},
+		{"9.99", 3330, "3.33"},
+	}
+	for _, test := range tests {
+		if result := MustParse(test.amount, "USD").BasisPoints(test.basisPoints); result.Amount() != test.result {
+			t.Errorf("Expected %d basis points of %s to be %s, but got %s", test.basisPoints, test.amount, test.result, result.Amount())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	encoded, err := json.Marshal(MustParse("6.50", "EUR"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if string(encoded) != `{"amount":"6.50","currency":"EUR"}` {
+		t.Errorf("Unexpected encoding %s", encoded)
+	}
+
+	tests := map[string]string{
+		`{"amount":"6.50","currency":"EUR"}`: "6.50 EUR",
+		`{"amount":6.5}`:                     "6.50 USD",
+		`15.99`:                              "15.99 USD",
+		`"2"`:                                "2.00 USD",
+	}
+	for input, expected := range tests {
+		var m Money
+		if err := json.Unmarshal([]byte(input), &m); err != nil {
+			t.Errorf("%s: expected no error, but got %v", input, err)
+			continue
+		}
+		if m.String() != expected {
+			t.Errorf("%s: expected %s, but got %s", input, expected, m)
+		}
+	}
+
+	for _, invalid := range []string{`"invalid"`, `15.999`, `{"currency":"USD"}`, `true`} {
+		var m Money
+		if err := json.Unmarshal([]byte(invalid), &m); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rate, err := ParseRate("0.92")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if converted := MustParse("15.99", "USD").Convert("EUR", rate); converted.String() != "14.71 EUR" {
+		t.Errorf("Expected 15.99 USD to be 14.71 EUR, but got %s", converted)
+	}
+
+	rate, _ = ParseRate("15800")
+	if converted := MustParse("6.50", "USD").Convert("JPY", rate); converted.String() != "102700 JPY" {
+		t.Errorf("Expected 6.50 USD to be 102700 JPY, but got %s", converted)
+	}
+
+	for _, invalid := range []string{"0", "-1", "abc", "1.00000000001"} {
+		if _, err := ParseRate(invalid); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+
+	var decoded struct {
+		Rate Rate `json:"rate"`
+	}
+	if err := json.Unmarshal([]byte(`{"rate": "1.250"}`), &decoded); err != nil || decoded.Rate.String() != "1.25" {
+		t.Errorf("Expected rate 1.25, but got %s (%v)", decoded.Rate, err)
+	}
+}
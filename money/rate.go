@@ -0,0 +1,105 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// Header selects the currency prices are shown and charged in. The currency
+// query parameter takes precedence where both are accepted.
+const Header = "X-Currency"
+
+// maxRateDigits bounds the decimal places of an exchange rate.
+const maxRateDigits = 10
+
+// Rate is an exchange rate: one unit of a base currency is worth Rate units
+// of another. It is kept as an exact fraction and encodes to JSON as a
+// decimal string such as "0.92".
+type Rate struct {
+	value *big.Rat
+}
+
+// ParseRate reads a positive decimal exchange rate such as "0.92" or "15800".
+func ParseRate(s string) (Rate, error) {
+	text := strings.TrimSpace(s)
+	whole, fraction, hasPoint := strings.Cut(text, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Rate{}, fmt.Errorf("invalid exchange rate %q", s)
+	}
+	if len(fraction) > maxRateDigits {
+		return Rate{}, fmt.Errorf("exchange rate %q has more than %d decimal places", s, maxRateDigits)
+	}
+
+	value, ok := new(big.Rat).SetString(text)
+	if !ok || value.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("exchange rate %q must be positive", s)
+	}
+	return Rate{value: value}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.value == nil || r.value.Sign() == 0
+}
+
+// String returns the rate as a decimal without trailing zeros.
+func (r Rate) String() string {
+	if r.value == nil {
+		return "0"
+	}
+	text := r.value.FloatString(maxRateDigits)
+	text = strings.TrimRight(text, "0")
+	return strings.TrimSuffix(text, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseRate(text)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Convert returns the amount in currency at rate, rounded half to even to
+// the minor unit of currency.
+func (m Money) Convert(currency string, rate Rate) Money {
+	currency = strings.ToUpper(currency)
+	if rate.value == nil {
+		return Money{currency: currency}
+	}
+
+	value := new(big.Rat).SetFrac(big.NewInt(m.amount), pow10(Digits(m.Currency())))
+	value.Mul(value, rate.value)
+	value.Mul(value, new(big.Rat).SetInt(pow10(Digits(currency))))
+	return Money{amount: roundHalfEven(value), currency: currency}
+}
+
+// RequestedCurrency returns the currency asked for by the currency query
+// parameter or the X-Currency header, or "" when the request names none.
+func RequestedCurrency(r *http.Request) (string, error) {
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		currency = r.Header.Get(Header)
+	}
+	if currency == "" {
+		return "", nil
+	}
+	return ParseCurrency(currency)
+}
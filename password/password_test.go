@@ -0,0 +1,37 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashAndCheck(t *testing.T) {
+	hash, err := Hash("warung123")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+	if strings.Contains(hash, "warung123") {
+		t.Fatalf("Expected the hash not to contain the password, but got %s", hash)
+	}
+
+	if ok, err := Check(hash, "warung123"); err != nil || !ok {
+		t.Errorf("Expected the password to match, got %v, %v", ok, err)
+	}
+	if ok, err := Check(hash, "warung124"); err != nil || ok {
+		t.Errorf("Expected a wrong password not to match, got %v, %v", ok, err)
+	}
+
+	again, _ := Hash("warung123")
+	if again == hash {
+		t.Errorf("Expected two hashes of the same password to use different salts")
+	}
+}
+
+func TestCheckMalformed(t *testing.T) {
+	for _, encoded := range []string{"", "admin123", "md5$1$c2FsdA$a2V5", "pbkdf2-sha256$x$c2FsdA$a2V5", "pbkdf2-sha256$1$c2FsdA$"} {
+		if _, err := Check(encoded, "admin123"); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Check(%q): expected ErrMalformed, got %v", encoded, err)
+		}
+	}
+}
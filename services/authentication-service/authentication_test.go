@@ -259,3 +259,51 @@ func TestInvalidLoginCredentials(t *testing.T) {
 	t.Logf("Response status code: %d", loginResponse.StatusCode)
 	t.Logf("Response body: %s", string(bodyBytes))
 }
+
+func TestRegisterUserInvalidData(t *testing.T) {
+	body := model.UserRegisterRequest{
+		Username: "ab",
+		Password: "short",
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/register", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+
+	var result struct {
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if len(result.Errors) != 2 || result.Errors[0].Field != "username" || result.Errors[1].Field != "password" {
+		t.Errorf("Expected errors for username and password, but got %+v", result.Errors)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Errors: %+v", result.Errors)
+}
@@ -4,22 +4,58 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"restaurant/fixture"
 	"restaurant/model"
+	"restaurant/password"
 	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/token"
+	"restaurant/validate"
 	"strconv"
+	"sync"
 )
 
 func main() {
+	if err := token.RequireSecret(); err != nil && !fixture.Local() {
+		log.Fatalf("Refusing to start in %s: %v", fixture.Environment(), err)
+	}
+
 	userDB := storage.NewUserStorage()
+	// lock guards userDB. Handlers hold it only while touching the store, so
+	// the deliberately slow password hashing runs without it.
+	var lock sync.RWMutex
+
+	seedUsers := func() error {
+		users, err := fixture.LoadUsers(fixture.Environment())
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
 
-	if userDB.GetUserCount() == 0 {
-		userDB.AddUser(model.User{ID: 1, Username: "admin", Password: "admin123"})
-		userDB.AddUser(model.User{ID: 2, Username: "user1", Password: "password123"})
+		userDB.Reset()
+		for _, user := range users {
+			userDB.AddUser(user)
+		}
+		return nil
+	}
+
+	if fixture.SeedingEnabled() {
+		if err := seedUsers(); err != nil {
+			log.Fatalf("Failed to seed users: %v", err)
+		}
 	}
 
 	mux := http.ServeMux{}
 	mux.HandleFunc(
 		"/login", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromPublicRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
 			var request model.UserLoginRequest
 			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 				log.Printf("Error decoding login request: %v", err)
@@ -27,25 +63,43 @@ func main() {
 				return
 			}
 
-			log.Printf("Login attempt for user: %s", request.Username)
+			if errs := validate.Struct(request); errs != nil {
+				validate.WriteErrors(w, errs)
+				return
+			}
+
+			log.Printf("Login attempt for user: %s (restaurant %d)", request.Username, restaurantID)
 
-			foundUser, exists := userDB.GetUserByUsername(request.Username)
+			lock.RLock()
+			foundUser, exists := userDB.GetUserByUsername(restaurantID, request.Username)
+			lock.RUnlock()
 			if !exists {
 				http.Error(w, "User not found", http.StatusNotFound)
 				return
 			}
 
-			if foundUser.Password != request.Password {
+			matches, err := password.Check(foundUser.PasswordHash, request.Password)
+			if err != nil {
+				log.Printf("Error checking password of user %d: %v", foundUser.ID, err)
+			}
+			if !matches {
 				http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 				return
 			}
 
+			issued, err := token.Issue(foundUser.ID, foundUser.RestaurantID)
+			if err != nil {
+				log.Printf("Error issuing token: %v", err)
+				http.Error(w, "error issuing token", http.StatusInternalServerError)
+				return
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(
 				map[string]string{
 					"message": "Login successful",
-					"token":   "some-jwt-token",
+					"token":   issued,
 				},
 			)
 		},
@@ -53,6 +107,12 @@ func main() {
 
 	mux.HandleFunc(
 		"/register", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromPublicRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
 			var request model.UserRegisterRequest
 			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 				log.Printf("Error decoding register request: %v", err)
@@ -60,18 +120,45 @@ func main() {
 				return
 			}
 
-			log.Printf("Register attempt for user: %s", request.Username)
+			if errs := validate.Struct(request); errs != nil {
+				validate.WriteErrors(w, errs)
+				return
+			}
+
+			log.Printf("Register attempt for user: %s (restaurant %d)", request.Username, restaurantID)
 
-			if userDB.UserExists(request.Username) {
+			lock.RLock()
+			exists := userDB.UserExists(restaurantID, request.Username)
+			lock.RUnlock()
+			if exists {
 				http.Error(w, "User already exists", http.StatusConflict)
 				return
 			}
 
-			userDB.AddUser(model.User{
-				ID:       userDB.GetUserCount() + 1,
-				Username: request.Username,
-				Password: request.Password,
-			})
+			hash, err := password.Hash(request.Password)
+			if err != nil {
+				log.Printf("Error hashing password: %v", err)
+				http.Error(w, "error registering user", http.StatusInternalServerError)
+				return
+			}
+
+			// The username is checked again, as another registration may
+			// have taken it while the password was hashed.
+			lock.Lock()
+			exists = userDB.UserExists(restaurantID, request.Username)
+			if !exists {
+				userDB.AddUser(model.User{
+					ID:           userDB.NextUserID(),
+					RestaurantID: restaurantID,
+					Username:     request.Username,
+					PasswordHash: hash,
+				})
+			}
+			lock.Unlock()
+			if exists {
+				http.Error(w, "User already exists", http.StatusConflict)
+				return
+			}
 
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
@@ -90,7 +177,19 @@ func main() {
 				return
 			}
 
-			if userDB.GetUserCount() == 0 {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			lock.RLock()
+			users := userDB.GetUsersByRestaurant(restaurantID)
+			lock.RUnlock()
+			for i := range users {
+				users[i].PasswordHash = ""
+			}
+			if len(users) == 0 {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusNoContent)
 				json.NewEncoder(w).Encode(map[string]string{"message": "no users found"})
@@ -99,7 +198,7 @@ func main() {
 
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(userDB.Users)
+			json.NewEncoder(w).Encode(users)
 		},
 	)
 
@@ -110,6 +209,12 @@ func main() {
 				return
 			}
 
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
 			strID := r.URL.Path[len("/users/"):]
 			id, err := strconv.Atoi(strID)
 			if err != nil {
@@ -117,7 +222,9 @@ func main() {
 				return
 			}
 
-			foundUser, exists := userDB.GetUserByID(id)
+			lock.RLock()
+			foundUser, exists := userDB.GetUserByID(restaurantID, id)
+			lock.RUnlock()
 			if !exists {
 				http.Error(w, "User not found", http.StatusNotFound)
 				return
@@ -129,6 +236,95 @@ func main() {
 		},
 	)
 
+	if fixture.AdminEnabled() {
+		// Seeding, export and restore cover every restaurant, so they are
+		// only available when enabled explicitly, and only to the admin
+		// tools, which call them with a service token.
+		mux.HandleFunc(
+			"/admin/export", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				lock.RLock()
+				defer lock.RUnlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(userDB.Users)
+			},
+		)
+
+		mux.HandleFunc(
+			"/admin/restore", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				var users []model.User
+				if err := json.NewDecoder(r.Body).Decode(&users); err != nil {
+					log.Printf("Error decoding users restore: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+
+				if userDB.GetUserCount() > 0 {
+					http.Error(w, "restore requires an empty store", http.StatusConflict)
+					return
+				}
+
+				for _, item := range users {
+					userDB.AddUser(item)
+				}
+
+				log.Printf("Restored %d users", len(users))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{"message": "users restored", "count": len(users)})
+			},
+		)
+
+		mux.HandleFunc(
+			"/admin/seed", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				if err := seedUsers(); err != nil {
+					log.Printf("Error reseeding users: %v", err)
+					http.Error(w, "error loading fixtures", http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "users reseeded from " + fixture.Environment() + " fixtures"})
+			},
+		)
+	}
+
 	log.Println("Authentication service starting on :8081")
 	if err := http.ListenAndServe(":8081", &mux); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
@@ -1,23 +1,73 @@
 package main
 
 import (
+	"bytes"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"restaurant/cache"
+	"restaurant/events"
+	"restaurant/fixture"
 	"restaurant/model"
+	"restaurant/money"
 	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/token"
+	"restaurant/validate"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// serviceClient calls the other services. Its timeout keeps a hung service
+// from hanging the requests that depend on it.
+var serviceClient = &http.Client{Timeout: 5 * time.Second}
+
 func main() {
+	if err := token.RequireSecret(); err != nil && !fixture.Local() {
+		log.Fatalf("Refusing to start in %s: %v", fixture.Environment(), err)
+	}
+
 	orderDB := storage.NewOrderStorage()
+	pendingDB := storage.NewPendingSaleStorage()
+	transactor := storage.NewMemoryTransactor(orderDB, pendingDB)
+	// Handlers here call other services, so instead of guarding the whole
+	// mux they hold lock only while touching the stores.
+	lock := &sync.RWMutex{}
+	productCache, productCacheTTL := newProductCache()
+	// Lookups go through fencedCache, so a product fetched before an
+	// invalidation event is not written back over it.
+	fencedCache := cache.NewFenced(productCache)
+
+	seedOrders := func() error {
+		orders, err := fixture.LoadOrders(fixture.Environment())
+		if err != nil {
+			return err
+		}
 
-	if orderDB.GetOrderCount() == 0 {
-		orderDB.AddOrder(model.Order{ID: 1, UserID: 1, ProductID: 1, Quantity: 2, TotalPrice: 31.98})
-		orderDB.AddOrder(model.Order{ID: 2, UserID: 2, ProductID: 3, Quantity: 1, TotalPrice: 8.99})
+		lock.Lock()
+		defer lock.Unlock()
+
+		orderDB.Reset()
+		for _, order := range orders {
+			orderDB.AddOrder(order)
+		}
+		return nil
+	}
+
+	if fixture.SeedingEnabled() {
+		if err := seedOrders(); err != nil {
+			log.Fatalf("Failed to seed orders: %v", err)
+		}
 	}
 
-	var checkUserFound = func(userID int) (
+	var checkUserFound = func(restaurantID, userID int) (
 		error,
 		int,
 	) {
@@ -30,8 +80,9 @@ func main() {
 		if err != nil {
 			return fmt.Errorf("error creating request"), http.StatusInternalServerError
 		}
+		tenant.Forward(request, restaurantID)
 
-		response, err := http.DefaultClient.Do(request)
+		response, err := serviceClient.Do(request)
 		if err != nil {
 			return fmt.Errorf("error checking user existence"), http.StatusInternalServerError
 		}
@@ -48,12 +99,24 @@ func main() {
 		return nil, http.StatusOK
 	}
 
-	var checkProductFound = func(productID int) (
+	var fetchProduct = func(restaurantID, productID int) (
+		*model.Product,
 		error,
 		int,
 	) {
+		key := productCacheKey(restaurantID, productID)
+		generation := fencedCache.Generation(key)
+		if cached, found, err := fencedCache.Get(key); err != nil {
+			log.Printf("Error reading product cache: %v", err)
+		} else if found {
+			var product model.Product
+			if err := json.Unmarshal(cached, &product); err == nil {
+				return &product, nil, http.StatusOK
+			}
+		}
+
 		url := fmt.Sprintf("http://product-service:8082/product/%d", productID)
-		log.Printf("Checking product existence: %s", url)
+		log.Printf("Fetching product: %s", url)
 		request, err := http.NewRequest(
 			http.MethodGet,
 			url,
@@ -62,31 +125,286 @@ func main() {
 
 		if err != nil {
 			log.Printf("Error creating product request: %v", err)
-			return fmt.Errorf("error creating request"), http.StatusInternalServerError
+			return nil, fmt.Errorf("error creating request"), http.StatusInternalServerError
 		}
+		tenant.Forward(request, restaurantID)
 
-		response, err := http.DefaultClient.Do(request)
+		response, err := serviceClient.Do(request)
 		if err != nil {
 			log.Printf("Error making product request: %v", err)
-			return fmt.Errorf("product not found"), http.StatusNotFound
+			return nil, fmt.Errorf("product not found"), http.StatusNotFound
 		}
 		defer response.Body.Close()
 
 		log.Printf("Product response status: %d", response.StatusCode)
 		if response.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("product not found"), http.StatusNotFound
+			return nil, fmt.Errorf("product not found"), http.StatusNotFound
 		}
 
 		if response.StatusCode != http.StatusOK {
-			return fmt.Errorf("error checking product existence"), http.StatusInternalServerError
+			return nil, fmt.Errorf("error checking product existence"), http.StatusInternalServerError
+		}
+
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading product"), http.StatusInternalServerError
+		}
+
+		var product model.Product
+		if err := json.Unmarshal(body, &product); err != nil {
+			log.Printf("Error decoding product: %v", err)
+			return nil, fmt.Errorf("error reading product"), http.StatusInternalServerError
+		}
+
+		if stored, err := fencedCache.SetIfCurrent(key, body, productCacheTTL, generation); err != nil {
+			log.Printf("Error writing product cache: %v", err)
+		} else if !stored {
+			log.Printf("Not caching product %d: it changed while it was fetched", productID)
 		}
 
+		return &product, nil, http.StatusOK
+	}
+
+	// checkSchedule asks the product service whether the product's
+	// availability window is open now. The answer depends on the clock, so it
+	// is never cached.
+	var checkSchedule = func(restaurantID int, product *model.Product) (
+		error,
+		int,
+	) {
+		request, err := http.NewRequest(
+			http.MethodGet,
+			fmt.Sprintf("http://product-service:8082/product/%d/availability", product.ID),
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("error creating request"), http.StatusInternalServerError
+		}
+		tenant.Forward(request, restaurantID)
+
+		response, err := serviceClient.Do(request)
+		if err != nil {
+			log.Printf("Error checking product availability: %v", err)
+			return fmt.Errorf("error checking product availability"), http.StatusBadGateway
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("product not found"), http.StatusNotFound
+		}
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("error checking product availability"), http.StatusBadGateway
+		}
+
+		var availability struct {
+			Scheduled bool `json:"scheduled"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&availability); err != nil {
+			return fmt.Errorf("error checking product availability"), http.StatusBadGateway
+		}
+
+		if !availability.Scheduled {
+			return fmt.Errorf("%s is not available at this time", product.Name), http.StatusConflict
+		}
 		return nil, http.StatusOK
 	}
 
+	// fetchExchangeRate looks up the restaurant's rate from base to currency.
+	// A missing rate is not an error here; pricing fails only if it needs one.
+	var fetchExchangeRate = func(restaurantID int, base, currency string) (
+		*model.ExchangeRate,
+		error,
+		int,
+	) {
+		request, err := http.NewRequest(
+			http.MethodGet,
+			fmt.Sprintf("http://product-service:8082/exchange-rates/%s/%s", base, currency),
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request"), http.StatusInternalServerError
+		}
+		tenant.Forward(request, restaurantID)
+
+		response, err := serviceClient.Do(request)
+		if err != nil {
+			log.Printf("Error fetching exchange rate: %v", err)
+			return nil, fmt.Errorf("error fetching exchange rate"), http.StatusBadGateway
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			return nil, nil, http.StatusOK
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error fetching exchange rate"), http.StatusBadGateway
+		}
+
+		var rate model.ExchangeRate
+		if err := json.NewDecoder(response.Body).Decode(&rate); err != nil {
+			return nil, fmt.Errorf("error fetching exchange rate"), http.StatusBadGateway
+		}
+		return &rate, nil, http.StatusOK
+	}
+
+	// recordSales reports sold units to the product service, which deducts
+	// them from stock in one transaction. The order is only kept when this
+	// succeeds. Unless the product service refused the sale, the outcome is
+	// unknown after an error, and the sale has to be cancelled.
+	var recordSales = func(restaurantID int, saleID string, items []model.SaleItem) (
+		error,
+		int,
+	) {
+		body, err := json.Marshal(model.SaleRequest{SaleID: saleID, Items: items})
+		if err != nil {
+			return fmt.Errorf("error encoding sale"), http.StatusInternalServerError
+		}
+
+		request, err := http.NewRequest(
+			http.MethodPost,
+			"http://product-service:8082/product/sales",
+			bytes.NewBuffer(body),
+		)
+		if err != nil {
+			log.Printf("Error creating sale request: %v", err)
+			return fmt.Errorf("error creating request"), http.StatusInternalServerError
+		}
+		tenant.Forward(request, restaurantID)
+
+		response, err := serviceClient.Do(request)
+		if err != nil {
+			log.Printf("Error recording sale: %v", err)
+			return fmt.Errorf("error reserving stock"), http.StatusBadGateway
+		}
+		defer response.Body.Close()
+
+		switch response.StatusCode {
+		case http.StatusOK:
+			return nil, http.StatusOK
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+			body, _ := io.ReadAll(response.Body)
+			return fmt.Errorf("%s", strings.TrimSpace(string(body))), response.StatusCode
+		default:
+			log.Printf("Recording sale failed with status %d", response.StatusCode)
+			return fmt.Errorf("error reserving stock"), http.StatusBadGateway
+		}
+	}
+
+	// cancelSale asks the product service to put back what a sale took. A
+	// sale it never recorded is fine: the product service remembers the
+	// cancellation and refuses the sale should it still arrive.
+	var cancelSale = func(restaurantID int, saleID string) error {
+		body, err := json.Marshal(map[string]string{"sale_id": saleID})
+		if err != nil {
+			return err
+		}
+
+		request, err := http.NewRequest(
+			http.MethodPost,
+			"http://product-service:8082/product/sales/cancel",
+			bytes.NewBuffer(body),
+		)
+		if err != nil {
+			return err
+		}
+		tenant.Forward(request, restaurantID)
+
+		response, err := serviceClient.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("cancelling sale %s failed with status %d", saleID, response.StatusCode)
+		}
+		return nil
+	}
+
+	// abandonSale marks a sale that will not become an order for
+	// cancellation and tries to cancel it right away. A failed attempt is
+	// retried by the sale reconciler.
+	var abandonSale = func(restaurantID int, saleID string) {
+		lock.Lock()
+		pendingDB.RequestCancel(saleID)
+		lock.Unlock()
+
+		err := cancelSale(restaurantID, saleID)
+
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			log.Printf("Error cancelling sale %s, it will be retried: %v", saleID, err)
+			pendingDB.RecordAttempt(saleID)
+			return
+		}
+		pendingDB.Cancelled(saleID)
+	}
+
+	// expandBundle resolves the product chosen for every slot of a bundle and
+	// checks that each can be sold in the ordered quantity. It returns the
+	// components and their summed price for one bundle.
+	var expandBundle = func(restaurantID int, bundle *model.Product, choices []model.BundleChoice, quantity int) (
+		[]model.BundleComponent,
+		money.Money,
+		error,
+		int,
+	) {
+		resolved, err := bundle.Bundle.Choose(choices)
+		if err != nil {
+			return nil, money.Money{}, err, http.StatusBadRequest
+		}
+
+		components := make([]model.BundleComponent, 0, len(resolved))
+		componentTotal := money.New(0, bundle.Price.Currency())
+		for i, choice := range resolved {
+			slot := bundle.Bundle.Slots[i]
+
+			product, err, status := fetchProduct(restaurantID, choice.ProductID)
+			if err != nil {
+				return nil, money.Money{}, fmt.Errorf("bundle slot %q: %v", slot.Name, err), status
+			}
+
+			units := slot.Quantity * quantity
+			if !product.Orderable() {
+				return nil, money.Money{}, fmt.Errorf("bundle slot %q: %s is unavailable", slot.Name, product.Name), http.StatusConflict
+			}
+			if product.TrackStock && product.Stock < units {
+				return nil, money.Money{}, fmt.Errorf("bundle slot %q: insufficient stock of %s", slot.Name, product.Name), http.StatusConflict
+			}
+			if err, status := checkSchedule(restaurantID, product); err != nil {
+				return nil, money.Money{}, fmt.Errorf("bundle slot %q: %v", slot.Name, err), status
+			}
+
+			components = append(components, model.BundleComponent{
+				SlotID:      slot.ID,
+				SlotName:    slot.Name,
+				ProductID:   product.ID,
+				ProductName: product.Name,
+				Quantity:    units,
+				UnitPrice:   product.Price,
+			})
+			slotTotal, err := product.Price.Mul(slot.Quantity)
+			if err == nil {
+				componentTotal, err = componentTotal.Add(slotTotal)
+			}
+			if err != nil {
+				return nil, money.Money{}, fmt.Errorf("bundle slot %q: %w", slot.Name, err), priceErrorStatus(err)
+			}
+		}
+
+		return components, componentTotal, nil, http.StatusOK
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc(
 		"/order", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
 			if r.Method == http.MethodPost {
 				var orderRequest model.OrderRequest
 				if err := json.NewDecoder(r.Body).Decode(&orderRequest); err != nil {
@@ -95,44 +413,207 @@ func main() {
 					return
 				}
 
-				log.Printf("Creating order for user %d, product %d", orderRequest.UserID, orderRequest.ProductID)
+				if errs := validate.Struct(orderRequest); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				log.Printf(
+					"Creating order for user %d, product %d (restaurant %d)",
+					orderRequest.UserID,
+					orderRequest.ProductID,
+					restaurantID,
+				)
+
+				if err, status := checkUserFound(restaurantID, orderRequest.UserID); err != nil {
+					http.Error(w, err.Error(), status)
+					return
+				}
 
-				if err, status := checkUserFound(orderRequest.UserID); err != nil {
+				product, err, status := fetchProduct(restaurantID, orderRequest.ProductID)
+				if err != nil {
 					http.Error(w, err.Error(), status)
 					return
 				}
 
-				if err, status := checkProductFound(orderRequest.ProductID); err != nil {
+				if product.Archived() {
+					http.Error(w, "product is no longer on the menu", http.StatusConflict)
+					return
+				}
+				if !product.Orderable() {
+					http.Error(w, "product is unavailable", http.StatusConflict)
+					return
+				}
+				if product.TrackStock && product.Stock < orderRequest.Quantity {
+					http.Error(w, "insufficient stock", http.StatusConflict)
+					return
+				}
+				if err, status := checkSchedule(restaurantID, product); err != nil {
+					http.Error(w, err.Error(), status)
+					return
+				}
+
+				options, err := product.SelectOptions(orderRequest.Options)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				// The order is charged in the currency of the request body,
+				// the X-Currency header or else the product's own currency.
+				currency := orderRequest.Currency
+				if currency == "" {
+					currency = r.Header.Get(money.Header)
+				}
+				if currency == "" {
+					currency = product.Price.Currency()
+				}
+				if currency, err = money.ParseCurrency(currency); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				var rate *model.ExchangeRate
+				if currency != product.Price.Currency() {
+					if rate, err, status = fetchExchangeRate(restaurantID, product.Price.Currency(), currency); err != nil {
+						http.Error(w, err.Error(), status)
+						return
+					}
+				}
+
+				// The total is priced from the product and its options; the
+				// client's total_price is not trusted.
+				unitPrice, err := product.UnitPriceIn(currency, rate, options)
+				if err != nil {
+					http.Error(w, err.Error(), priceErrorStatus(err))
+					return
+				}
+
+				// The rate is recorded when the price could not be found
+				// without it, i.e. when something was converted.
+				usedRate := false
+				if rate != nil {
+					_, err := product.UnitPriceIn(currency, nil, options)
+					usedRate = err != nil
+				}
+				sales := []model.SaleItem{{ProductID: product.ID, Quantity: orderRequest.Quantity}}
+
+				var components []model.BundleComponent
+				if product.Bundle != nil {
+					var componentTotal money.Money
+					components, componentTotal, err, status = expandBundle(restaurantID, product, orderRequest.BundleChoices, orderRequest.Quantity)
+					if err != nil {
+						http.Error(w, err.Error(), status)
+						return
+					}
+
+					// A discount rule replaces the product's own price;
+					// option deltas still apply on top.
+					if product.Bundle.Pricing != model.BundlePricingFixed {
+						ownPrice, _ := product.PriceIn(currency, rate)
+						bundlePrice, err := product.Bundle.Price(product.Price, componentTotal)
+						if err == nil {
+							bundlePrice, err = model.ConvertPrice(bundlePrice, currency, rate)
+						}
+						if err == nil {
+							if unitPrice, err = unitPrice.Sub(ownPrice); err == nil {
+								unitPrice, err = unitPrice.Add(bundlePrice)
+							}
+						}
+						if err != nil {
+							http.Error(w, err.Error(), priceErrorStatus(err))
+							return
+						}
+						usedRate = rate != nil
+					}
+					for _, component := range components {
+						sales = append(sales, model.SaleItem{ProductID: component.ProductID, Quantity: component.Quantity})
+					}
+				}
+
+				totalPrice, err := unitPrice.Mul(orderRequest.Quantity)
+				if err != nil {
+					http.Error(w, err.Error(), priceErrorStatus(err))
+					return
+				}
+
+				order := model.Order{
+					RestaurantID: restaurantID,
+					UserID:       orderRequest.UserID,
+					ProductID:    orderRequest.ProductID,
+					Quantity:     orderRequest.Quantity,
+					TotalPrice:   totalPrice,
+					CreatedAt:    time.Now().UTC(),
+					Currency:     currency,
+					ProductName:  product.Name,
+					Options:      options,
+					Components:   components,
+				}
+
+				if usedRate {
+					order.ExchangeRate = rate
+				}
+
+				// The stock is deducted before the order is stored, so no
+				// lock is held while the product service answers. The sale
+				// is kept as pending until the order is stored, so it can be
+				// cancelled if the order never is.
+				order.SaleID = rand.Text()
+				lock.Lock()
+				pendingDB.AddPendingSale(model.PendingSale{SaleID: order.SaleID, RestaurantID: restaurantID, CreatedAt: order.CreatedAt})
+				lock.Unlock()
+
+				if err, status := recordSales(restaurantID, order.SaleID, sales); err != nil {
+					log.Printf("Error creating order: %v", err)
+					if saleRefused(status) {
+						lock.Lock()
+						pendingDB.Cancelled(order.SaleID)
+						lock.Unlock()
+					} else {
+						abandonSale(restaurantID, order.SaleID)
+					}
 					http.Error(w, err.Error(), status)
 					return
 				}
 
-				orderDB.AddOrder(model.Order{
-					ID:         orderDB.GetOrderCount() + 1,
-					UserID:     orderRequest.UserID,
-					ProductID:  orderRequest.ProductID,
-					Quantity:   orderRequest.Quantity,
-					TotalPrice: orderRequest.TotalPrice,
+				lock.Lock()
+				err = storage.WithTransaction(transactor, func(tx storage.Tx) error {
+					order.ID = orderDB.NextOrderID()
+					orderDB.AddOrder(order)
+					if !pendingDB.Settle(order.SaleID) {
+						return errSaleCancelled
+					}
+					return nil
 				})
+				lock.Unlock()
+				if err != nil {
+					log.Printf("Error storing order for sale %s: %v", order.SaleID, err)
+					abandonSale(restaurantID, order.SaleID)
+					http.Error(w, "error storing order", http.StatusInternalServerError)
+					return
+				}
 
 				w.WriteHeader(http.StatusOK)
 				json.NewEncoder(w).Encode(
 					map[string]interface{}{
 						"message": "order placed successfully",
-						"order":   orderRequest,
+						"order":   order,
 					},
 				)
 			} else if r.Method == http.MethodGet {
-				if len(orderDB.Orders) == 0 {
+				lock.RLock()
+				orders := orderDB.GetOrdersByRestaurant(restaurantID)
+				lock.RUnlock()
+				if len(orders) == 0 {
 					w.WriteHeader(http.StatusNoContent)
 					json.NewEncoder(w).Encode(map[string]string{"message": "no orders found"})
 					return
 				}
 
-				for _, order := range orderDB.Orders {
+				for _, order := range orders {
 					fmt.Println("ID\tUSERID\tPRODUCTID\tQUANTITY\tTOTAL PRICE")
 					fmt.Printf(
-						"%d\t%d\t%d\t%d\t%.2f\n",
+						"%d\t%d\t%d\t%d\t%s\n",
 						order.ID,
 						order.UserID,
 						order.ProductID,
@@ -142,7 +623,7 @@ func main() {
 				}
 
 				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(orderDB.Orders)
+				json.NewEncoder(w).Encode(orders)
 			} else {
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 				return
@@ -150,8 +631,364 @@ func main() {
 		},
 	)
 
+	// The product service turns these counts into upsell suggestions for
+	// GET /product/{id}/recommendations.
+	mux.HandleFunc(
+		"/order/recommendations", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			productID, err := strconv.Atoi(r.URL.Query().Get("product_id"))
+			if err != nil || productID <= 0 {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			lock.RLock()
+			orders := orderDB.GetOrdersByRestaurant(restaurantID)
+			lock.RUnlock()
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(model.OrderedTogether(orders, productID, visitGap))
+		},
+	)
+
+	mux.HandleFunc(
+		"/order/{id}/ticket", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid order ID", http.StatusBadRequest)
+				return
+			}
+
+			lock.RLock()
+			order, exists := orderDB.GetOrderByID(restaurantID, id)
+			lock.RUnlock()
+			if !exists {
+				http.Error(w, "Order not found", http.StatusNotFound)
+				return
+			}
+
+			// Orders placed before product names were stored fall back to
+			// the current product name.
+			name := order.ProductName
+			if name == "" {
+				name = fmt.Sprintf("product %d", order.ProductID)
+				if product, err, _ := fetchProduct(restaurantID, order.ProductID); err == nil {
+					name = product.Name
+				}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(kitchenTicket(*order, name))
+		},
+	)
+
+	if fixture.AdminEnabled() {
+		// Seeding, export and restore cover every restaurant, so they are
+		// only available when enabled explicitly, and only to the admin
+		// tools, which call them with a service token.
+		mux.HandleFunc(
+			"/admin/export", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				lock.RLock()
+				defer lock.RUnlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(orderDB.Orders)
+			},
+		)
+
+		mux.HandleFunc(
+			"/admin/restore", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				var orders []model.Order
+				if err := json.NewDecoder(r.Body).Decode(&orders); err != nil {
+					log.Printf("Error decoding orders restore: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+
+				if orderDB.GetOrderCount() > 0 {
+					http.Error(w, "restore requires an empty store", http.StatusConflict)
+					return
+				}
+
+				for _, item := range orders {
+					orderDB.AddOrder(item)
+				}
+
+				log.Printf("Restored %d orders", len(orders))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{"message": "orders restored", "count": len(orders)})
+			},
+		)
+
+		mux.HandleFunc(
+			"/admin/seed", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				if err := seedOrders(); err != nil {
+					log.Printf("Error reseeding orders: %v", err)
+					http.Error(w, "error loading fixtures", http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "orders reseeded from " + fixture.Environment() + " fixtures"})
+			},
+		)
+	}
+
+	mux.HandleFunc(
+		"/events/product", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			if err := tenant.RequireService(r); err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			var event events.ProductEvent
+			if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+				log.Printf("Error decoding product event: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("Received %s for product %d (restaurant %d)", event.Type, event.ProductID, event.RestaurantID)
+
+			if err := fencedCache.Delete(productCacheKey(event.RestaurantID, event.ProductID)); err != nil {
+				log.Printf("Error invalidating product cache: %v", err)
+				http.Error(w, "error invalidating cache", http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+
+	mux.HandleFunc(
+		"/metrics/cache", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			if err := tenant.RequireService(r); err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			stats := productCache.Stats()
+			hitRatio := 0.0
+			if lookups := stats.Hits + stats.Misses; lookups > 0 {
+				hitRatio = float64(stats.Hits) / float64(lookups)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"product_cache": stats,
+					"hit_ratio":     hitRatio,
+				},
+			)
+		},
+	)
+
+	go runSaleReconciler(lock, pendingDB, cancelSale)
+
 	log.Println("Order service starting on :8080")
 	if err := http.ListenAndServe(":8080", mux); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// errSaleCancelled is returned when a sale was cancelled before its order
+// could be stored.
+var errSaleCancelled = errors.New("sale was cancelled before the order was stored")
+
+// staleSaleAge is how long a sale may stay pending before its order counts
+// as abandoned. It is well above the time a request takes once the sale is
+// recorded.
+const staleSaleAge = time.Minute
+
+// saleRefused reports whether the product service definitely did not record
+// a sale, given the status recordSales returned.
+func saleRefused(status int) bool {
+	return status == http.StatusBadRequest || status == http.StatusNotFound || status == http.StatusConflict
+}
+
+// runSaleReconciler cancels pending sales that will not become orders every
+// SALE_RECONCILE_INTERVAL (default 30s), until the product service confirms
+// the cancellation.
+func runSaleReconciler(lock *sync.RWMutex, pendingDB *storage.PendingSaleStorage, cancel func(restaurantID int, saleID string) error) {
+	interval := 30 * time.Second
+	if value := os.Getenv("SALE_RECONCILE_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("Invalid SALE_RECONCILE_INTERVAL: %q", value)
+		}
+		interval = parsed
+	}
+
+	for now := range time.Tick(interval) {
+		lock.Lock()
+		sales := pendingDB.ToCancel(now.Add(-staleSaleAge))
+		lock.Unlock()
+
+		for _, sale := range sales {
+			err := cancel(sale.RestaurantID, sale.SaleID)
+
+			lock.Lock()
+			if err != nil {
+				pendingDB.RecordAttempt(sale.SaleID)
+				log.Printf("Error cancelling sale %s (attempt %d): %v", sale.SaleID, sale.Attempts+1, err)
+			} else {
+				pendingDB.Cancelled(sale.SaleID)
+			}
+			lock.Unlock()
+		}
+	}
+}
+
+// visitGap is the longest pause between two orders of a user that still
+// counts as one visit when looking for products ordered together.
+const visitGap = 2 * time.Hour
+
+// newProductCache builds the product lookup cache from PRODUCT_CACHE_BACKEND
+// (memory or redis), REDIS_ADDR and PRODUCT_CACHE_TTL.
+func newProductCache() (*cache.Metered, time.Duration) {
+	ttl := 30 * time.Second
+	if value := os.Getenv("PRODUCT_CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid PRODUCT_CACHE_TTL: %v", err)
+		}
+		ttl = parsed
+	}
+
+	switch backend := os.Getenv("PRODUCT_CACHE_BACKEND"); backend {
+	case "", "memory":
+		log.Printf("Product cache: in-memory, TTL %s", ttl)
+		return cache.NewMetered(cache.NewMemoryCache()), ttl
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		log.Printf("Product cache: redis at %s, TTL %s", addr, ttl)
+		return cache.NewMetered(cache.NewRedisCache(addr)), ttl
+	default:
+		log.Fatalf("Unknown PRODUCT_CACHE_BACKEND %q", backend)
+		return nil, 0
+	}
+}
+
+func productCacheKey(restaurantID, productID int) string {
+	return fmt.Sprintf("product:%d:%d", restaurantID, productID)
+}
+
+// kitchenTicket lists what the kitchen has to prepare for an order. A bundle
+// becomes one line per component; any other product is a single line.
+func kitchenTicket(order model.Order, productName string) model.KitchenTicket {
+	ticket := model.KitchenTicket{
+		OrderID:      order.ID,
+		RestaurantID: order.RestaurantID,
+		Product:      productName,
+		Quantity:     order.Quantity,
+		Lines:        make([]model.TicketLine, 0, len(order.Components)+1),
+	}
+
+	for _, option := range order.Options {
+		ticket.Options = append(ticket.Options, option.GroupName+": "+option.OptionName)
+	}
+
+	if len(order.Components) == 0 {
+		ticket.Lines = append(ticket.Lines, model.TicketLine{
+			ProductID: order.ProductID,
+			Name:      productName,
+			Quantity:  order.Quantity,
+		})
+		return ticket
+	}
+
+	for _, component := range order.Components {
+		ticket.Lines = append(ticket.Lines, model.TicketLine{
+			ProductID: component.ProductID,
+			Name:      component.ProductName,
+			Quantity:  component.Quantity,
+			Slot:      component.SlotName,
+		})
+	}
+	return ticket
+}
+
+// priceErrorStatus maps an error from pricing an order: a missing exchange
+// rate is the client's choice of currency, a total too large to hold is an
+// unprocessable order, anything else a conflict in the product's prices.
+func priceErrorStatus(err error) int {
+	if errors.Is(err, model.ErrNoExchangeRate) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, money.ErrOverflow) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusConflict
+}
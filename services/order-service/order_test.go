@@ -7,17 +7,58 @@ import (
 	"io"
 	"net/http"
 	"restaurant/model"
+	"restaurant/money"
+	"restaurant/token"
+	"strconv"
 	"testing"
+	"time"
 )
 
 var baseURL = "http://localhost:8080"
 
+// authorizingTransport signs test requests that carry no token of their own
+// with a user token for the restaurant they name, so tests keep addressing
+// restaurants through the X-Restaurant-ID header.
+type authorizingTransport struct {
+	next http.RoundTripper
+}
+
+func (t authorizingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("Authorization") == "" {
+		restaurantID := 1
+		if value := request.Header.Get("X-Restaurant-ID"); value != "" {
+			if id, err := strconv.Atoi(value); err == nil {
+				restaurantID = id
+			}
+		}
+		userID := 1000 + restaurantID
+		switch restaurantID {
+		case 1:
+			userID = 1
+		case 2:
+			userID = 3
+		}
+
+		issued, err := token.Issue(userID, restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		request = request.Clone(request.Context())
+		request.Header.Set("Authorization", "Bearer "+issued)
+	}
+	return t.next.RoundTrip(request)
+}
+
+func init() {
+	http.DefaultTransport = authorizingTransport{next: http.DefaultTransport}
+}
+
 func TestCreateOrderUserNotFound(t *testing.T) {
 	body := model.OrderRequest{
 		UserID:     999,
 		ProductID:  1,
 		Quantity:   2,
-		TotalPrice: 50.0,
+		TotalPrice: money.MustParse("50.00", "USD"),
 	}
 
 	bodyJSON, err := json.Marshal(body)
@@ -59,7 +100,7 @@ func TestCreateOrderSuccess(t *testing.T) {
 		UserID:     1,
 		ProductID:  2,
 		Quantity:   2,
-		TotalPrice: 25.0,
+		TotalPrice: money.MustParse("25.00", "USD"),
 	}
 
 	bodyJSON, err := json.Marshal(body)
@@ -188,3 +229,896 @@ func TestOrderServiceMethodNotAllowed(t *testing.T) {
 	t.Logf("Response status code: %d", response.StatusCode)
 	t.Logf("Response body: %s", string(bodyBytes))
 }
+
+func TestCreateOrderProductFromOtherRestaurant(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:     3,
+		ProductID:  1,
+		Quantity:   1,
+		TotalPrice: money.MustParse("15.99", "USD"),
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestGetOrdersScopedToRestaurant(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/order", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNoContent {
+		return
+	}
+
+	var orders []model.Order
+	if err := json.NewDecoder(response.Body).Decode(&orders); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	for _, order := range orders {
+		if order.RestaurantID != 2 {
+			t.Errorf("Expected only orders of restaurant 2, but got %+v", order)
+		}
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Orders: %+v", orders)
+}
+
+func TestGetCacheMetricsSuccess(t *testing.T) {
+	serviceToken, err := token.IssueService()
+	if err != nil {
+		t.Fatalf("Error issuing service token: %v", err)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/metrics/cache", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestGetCacheMetricsRequiresService(t *testing.T) {
+	unauthenticated := &http.Client{Transport: http.DefaultTransport.(authorizingTransport).next}
+	for _, test := range []struct {
+		name   string
+		client *http.Client
+		status int
+	}{
+		{"no token", unauthenticated, http.StatusUnauthorized},
+		{"user token", &http.Client{}, http.StatusForbidden},
+	} {
+		response, err := test.client.Get(fmt.Sprintf("%s/metrics/cache", baseURL))
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != test.status {
+			t.Errorf("%s: expected status code %d, but got %d", test.name, test.status, response.StatusCode)
+		}
+	}
+}
+
+func TestCreateOrderInsufficientStock(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:     3,
+		ProductID:  5,
+		Quantity:   100000,
+		TotalPrice: money.MustParse("150000.00", "USD"),
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateOrderWithOptions(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:    1,
+		ProductID: 12,
+		Quantity:  2,
+		Options: []model.OptionSelection{
+			{GroupID: 1, OptionID: 2},
+			{GroupID: 2, OptionID: 1},
+		},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var result struct {
+		Order model.Order `json:"order"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	// Build Your Own Pizza 12.50 + Large 4.00 + Extra cheese 1.50, twice.
+	if result.Order.TotalPrice.String() != "36.00 USD" {
+		t.Errorf("Expected total price 36.00 USD, but got %s", result.Order.TotalPrice)
+	}
+	if len(result.Order.Options) != 2 {
+		t.Errorf("Expected 2 options stored on the order, but got %+v", result.Order.Options)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Order: %+v", result.Order)
+}
+
+func TestCreateOrderMissingRequiredOption(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:    1,
+		ProductID: 12,
+		Quantity:  1,
+		Options: []model.OptionSelection{
+			{GroupID: 2, OptionID: 2},
+		},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateBundleOrderSuccess(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:    3,
+		ProductID: 10,
+		Quantity:  1,
+		BundleChoices: []model.BundleChoice{
+			{SlotID: 2, ProductID: 9},
+		},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var result struct {
+		Order model.Order `json:"order"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if result.Order.TotalPrice.String() != "7.00 USD" {
+		t.Errorf("Expected bundle price 7.00 USD, but got %s", result.Order.TotalPrice)
+	}
+	if len(result.Order.Components) != 2 {
+		t.Fatalf("Expected 2 components, but got %+v", result.Order.Components)
+	}
+
+	ticketRequest, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/order/%d/ticket", baseURL, result.Order.ID), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	ticketRequest.Header.Set("X-Restaurant-ID", "2")
+
+	ticketResponse, err := client.Do(ticketRequest)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer ticketResponse.Body.Close()
+
+	if ticketResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, ticketResponse.StatusCode)
+	}
+
+	var ticket model.KitchenTicket
+	if err := json.NewDecoder(ticketResponse.Body).Decode(&ticket); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if len(ticket.Lines) != 2 || ticket.Lines[1].Name != "Es Jeruk" {
+		t.Errorf("Expected ticket lines for nasi goreng and es jeruk, but got %+v", ticket.Lines)
+	}
+
+	t.Logf("Order: %+v", result.Order)
+	t.Logf("Ticket: %+v", ticket)
+}
+
+func TestCreateBundleOrderMissingChoice(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:    3,
+		ProductID: 10,
+		Quantity:  1,
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateOrderOutsideSchedule(t *testing.T) {
+	// A window that opens two hours from now is closed at the time of the
+	// order, whatever the time of day.
+	now := time.Now().UTC()
+	product := model.Product{
+		Name:      "Late Special",
+		Price:     money.MustParse("5.00", "USD"),
+		Available: true,
+		Schedule: &model.Schedule{
+			Windows: []model.TimeWindow{{
+				Start: now.Add(2 * time.Hour).Format("15:04"),
+				End:   now.Add(3 * time.Hour).Format("15:04"),
+			}},
+		},
+	}
+
+	productJSON, err := json.Marshal(product)
+	if err != nil {
+		t.Errorf("Error marshaling product: %v", err)
+		return
+	}
+
+	productRequest, err := http.NewRequest(http.MethodPost, "http://localhost:8082/product", bytes.NewBuffer(productJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	productRequest.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	productResponse, err := client.Do(productRequest)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer productResponse.Body.Close()
+
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	if err := json.NewDecoder(productResponse.Body).Decode(&created); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	body := model.OrderRequest{
+		UserID:    3,
+		ProductID: created.Product.ID,
+		Quantity:  1,
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateOrderArchivedProduct(t *testing.T) {
+	client := &http.Client{}
+
+	productJSON, err := json.Marshal(model.Product{Name: "Old Special", Price: money.MustParse("4.00", "USD"), Available: true})
+	if err != nil {
+		t.Errorf("Error marshaling product: %v", err)
+		return
+	}
+
+	productRequest, err := http.NewRequest(http.MethodPost, "http://localhost:8082/product", bytes.NewBuffer(productJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	productRequest.Header.Set("X-Restaurant-ID", "2")
+
+	productResponse, err := client.Do(productRequest)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer productResponse.Body.Close()
+
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	if err := json.NewDecoder(productResponse.Body).Decode(&created); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	archiveRequest, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost:8082/product/%d", created.Product.ID), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	archiveRequest.Header.Set("X-Restaurant-ID", "2")
+
+	archiveResponse, err := client.Do(archiveRequest)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	archiveResponse.Body.Close()
+
+	bodyJSON, err := json.Marshal(model.OrderRequest{UserID: 3, ProductID: created.Product.ID, Quantity: 1})
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateOrderInvalidQuantity(t *testing.T) {
+	body := model.OrderRequest{
+		UserID:    1,
+		ProductID: 2,
+		Quantity:  0,
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateOrderQuantityTooLarge(t *testing.T) {
+	bodyJSON, err := json.Marshal(model.OrderRequest{UserID: 1, ProductID: 6, Quantity: 40000000000000000})
+	if err != nil {
+		t.Fatalf("Error marshaling request body: %v", err)
+	}
+
+	client := &http.Client{}
+	response, err := client.Post(fmt.Sprintf("%s/order", baseURL), "application/json", bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+}
+
+func TestCreateOrderTotalOverflow(t *testing.T) {
+	client := &http.Client{}
+
+	productJSON, err := json.Marshal(model.Product{Name: "Golden Durian", Price: money.MustParse("1000000000000000.00", "USD"), Available: true})
+	if err != nil {
+		t.Fatalf("Error marshaling product: %v", err)
+	}
+	productRequest, err := http.NewRequest(http.MethodPost, "http://localhost:8082/product", bytes.NewBuffer(productJSON))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	productRequest.Header.Set("X-Restaurant-ID", "2")
+
+	productResponse, err := client.Do(productRequest)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer productResponse.Body.Close()
+	if productResponse.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, productResponse.StatusCode)
+	}
+
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	if err := json.NewDecoder(productResponse.Body).Decode(&created); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+
+	bodyJSON, err := json.Marshal(model.OrderRequest{UserID: 3, ProductID: created.Product.ID, Quantity: 100})
+	if err != nil {
+		t.Fatalf("Error marshaling request body: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a total too large to hold to return %d, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+}
+
+func TestCreateOrderTotalIsExact(t *testing.T) {
+	client := &http.Client{}
+
+	// A plain number is still accepted as a price in the default currency.
+	productJSON := []byte(`{"name": "Sate Ayam", "price": 15.99, "available": true}`)
+	productRequest, err := http.NewRequest(http.MethodPost, "http://localhost:8082/product", bytes.NewBuffer(productJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	productRequest.Header.Set("X-Restaurant-ID", "2")
+
+	productResponse, err := client.Do(productRequest)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer productResponse.Body.Close()
+
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	if err := json.NewDecoder(productResponse.Body).Decode(&created); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	bodyJSON, err := json.Marshal(model.OrderRequest{UserID: 3, ProductID: created.Product.ID, Quantity: 2})
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/order", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var result struct {
+		Order struct {
+			TotalPrice json.RawMessage `json:"total_price"`
+		} `json:"order"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if string(result.Order.TotalPrice) != `{"amount":"31.98","currency":"USD"}` {
+		t.Errorf("Expected 2 x 15.99 to total 31.98 USD, but got %s", result.Order.TotalPrice)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Total price: %s", result.Order.TotalPrice)
+}
+
+func TestCreateOrderInOtherCurrency(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}) *http.Response {
+		t.Helper()
+
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return response
+	}
+
+	rate, _ := money.ParseRate("15000")
+	response := send(http.MethodPut, "http://localhost:8082/exchange-rates/USD/IDR", model.ExchangeRateRequest{Rate: rate})
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	response = send(http.MethodPost, "http://localhost:8082/product", model.Product{Name: "Kerak Telor", Price: money.MustParse("2.50", "USD"), Available: true})
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	json.NewDecoder(response.Body).Decode(&created)
+	response.Body.Close()
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/order", baseURL), model.OrderRequest{UserID: 3, ProductID: created.Product.ID, Quantity: 2, Currency: "IDR"})
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var result struct {
+		Order model.Order `json:"order"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+
+	if result.Order.TotalPrice.String() != "75000.00 IDR" || result.Order.Currency != "IDR" {
+		t.Errorf("Expected 2 x 2.50 USD to be charged as 75000.00 IDR, but got %s in %q", result.Order.TotalPrice, result.Order.Currency)
+	}
+	if result.Order.ExchangeRate == nil || result.Order.ExchangeRate.Rate.String() != "15000" {
+		t.Errorf("Expected the order to record the rate 15000, but got %+v", result.Order.ExchangeRate)
+	}
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/order", baseURL), model.OrderRequest{UserID: 3, ProductID: created.Product.ID, Quantity: 1, Currency: "SGD"})
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d without an exchange rate, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestProductRecommendationsFromOrders(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}) *http.Response {
+		t.Helper()
+
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return response
+	}
+	create := func(product model.Product) model.Product {
+		t.Helper()
+
+		response := send(http.MethodPost, "http://localhost:8082/product", product)
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+		}
+
+		var created struct {
+			Product model.Product `json:"product"`
+		}
+		json.NewDecoder(response.Body).Decode(&created)
+		return created.Product
+	}
+
+	coffee := create(model.Product{Name: "Kopi Tubruk", Price: money.MustParse("1.50", "USD"), Tags: []string{"Kopi Test", " kopi  test "}, Available: true})
+	cake := create(model.Product{Name: "Kue Lapis", Price: money.MustParse("1.00", "USD"), Available: true})
+	iced := create(model.Product{Name: "Es Kopi Susu", Price: money.MustParse("2.00", "USD"), Tags: []string{"kopi test"}, Available: true})
+	unrelated := create(model.Product{Name: "Sate Padang", Price: money.MustParse("3.00", "USD"), Available: true})
+
+	if len(coffee.Tags) != 1 || coffee.Tags[0] != "Kopi Test" {
+		t.Errorf("Expected duplicate tags to be merged, but got %q", coffee.Tags)
+	}
+
+	for _, productID := range []int{coffee.ID, cake.ID} {
+		response := send(http.MethodPost, fmt.Sprintf("%s/order", baseURL), model.OrderRequest{UserID: 3, ProductID: productID, Quantity: 1})
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+		}
+	}
+
+	response := send(http.MethodGet, fmt.Sprintf("http://localhost:8082/product/%d/recommendations?limit=20", coffee.ID), nil)
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var recommendations []model.Recommendation
+	if err := json.NewDecoder(response.Body).Decode(&recommendations); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+
+	reasons := make(map[int]string)
+	for _, recommendation := range recommendations {
+		reasons[recommendation.ProductID] = recommendation.Reason
+	}
+	if reasons[cake.ID] != model.RecommendationOrderedTogether {
+		t.Errorf("Expected %s recommended as ordered together, but got %+v", cake.Name, recommendations)
+	}
+	if reasons[iced.ID] != model.RecommendationSharedTags {
+		t.Errorf("Expected %s recommended for its tag, but got %+v", iced.Name, recommendations)
+	}
+	if _, found := reasons[unrelated.ID]; found {
+		t.Errorf("Expected %s not to be recommended", unrelated.Name)
+	}
+	if _, found := reasons[coffee.ID]; found {
+		t.Errorf("Expected a product not to recommend itself")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"restaurant/events"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"strconv"
+)
+
+func registerArchiveRoutes(mux *http.ServeMux, productDB *storage.ProductStorage, publisher *events.Publisher) {
+	mux.HandleFunc(
+		"/product/{id}/restore", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			if foundProduct, exists := productDB.GetProductByID(restaurantID, id); exists && !checkIfMatch(w, r, *foundProduct) {
+				return
+			}
+
+			log.Printf("Restoring product ID: %d", id)
+
+			product, err := productDB.RestoreProduct(restaurantID, id)
+			if err != nil {
+				http.Error(w, err.Error(), archiveErrorStatus(err))
+				return
+			}
+			publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+			w.Header().Set("ETag", productETag(*product))
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message": "product restored successfully",
+					"product": product,
+				},
+			)
+		},
+	)
+}
+
+func archiveErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrProductArchived),
+		errors.Is(err, storage.ErrProductNotArchived):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"restaurant/events"
+	"restaurant/model"
+	"restaurant/money"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the product fields carried by CSV import and export. List
+// cells separate their values with semicolons.
+var csvColumns = []string{
+	"sku",
+	"name",
+	"description",
+	"price",
+	"category_ids",
+	"tags",
+	"available",
+	"allergens",
+	"dietary_labels",
+}
+
+// importRow is one product of an import file. Columns holds the CSV columns
+// present in the file; it is nil for JSON rows, which replace the whole
+// product.
+type importRow struct {
+	Line    int
+	Product model.Product
+	Columns map[string]bool
+	Errors  []string
+}
+
+type importRowError struct {
+	Row    int      `json:"row"`
+	SKU    string   `json:"sku,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+type importReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Errors  []importRowError `json:"errors"`
+}
+
+func registerBulkRoutes(
+	mux *http.ServeMux,
+	transactor *storage.MemoryTransactor,
+	productDB *storage.ProductStorage,
+	categoryDB *storage.CategoryStorage,
+	ingredientDB *storage.IngredientStorage,
+	priceDB *storage.PriceStorage,
+	menuDB *storage.MenuStorage,
+	publisher *events.Publisher,
+) {
+	mux.HandleFunc(
+		"/product/import", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			dryRun := false
+			if value := r.URL.Query().Get("dry_run"); value != "" {
+				if dryRun, err = strconv.ParseBool(value); err != nil {
+					http.Error(w, "invalid dry_run", http.StatusBadRequest)
+					return
+				}
+			}
+
+			format := bulkFormat(r)
+			var rows []importRow
+			switch format {
+			case "csv":
+				rows, err = parseCSVImport(r.Body)
+			case "json":
+				rows, err = parseJSONImport(r.Body)
+			default:
+				http.Error(w, "format must be csv or json", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Printf("Error reading import: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("Importing %d products from %s (restaurant %d, dry run %v)", len(rows), format, restaurantID, dryRun)
+
+			creates, updates, rowErrors := planImport(productDB, categoryDB, ingredientDB, menuDB, restaurantID, rows)
+			report := importReport{
+				DryRun:  dryRun,
+				Created: len(creates),
+				Updated: len(updates),
+				Errors:  rowErrors,
+			}
+
+			// Nothing is imported unless every row is valid.
+			if len(rowErrors) > 0 {
+				report.Created, report.Updated = 0, 0
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(report)
+				return
+			}
+
+			if !dryRun {
+				changesPrice := false
+				for _, product := range updates {
+					if existing, exists := productDB.GetProductByID(restaurantID, product.ID); exists && !existing.Price.Equal(product.Price) {
+						changesPrice = true
+					}
+				}
+				userID, ok := checkPriceUser(w, r, changesPrice)
+				if !ok {
+					return
+				}
+
+				storage.WithTransaction(transactor, func(tx storage.Tx) error {
+					for _, product := range creates {
+						productDB.AddProduct(product)
+					}
+					for _, product := range updates {
+						if existing, exists := productDB.GetProductByID(restaurantID, product.ID); exists {
+							recordPriceChange(priceDB, restaurantID, product.ID, existing.Price, product.Price, model.PriceSourceImport, userID)
+						}
+						productDB.UpdateProduct(restaurantID, product.ID, product)
+					}
+					return nil
+				})
+
+				refreshIngredientShortages(productDB, ingredientDB, publisher, restaurantID)
+				for _, product := range updates {
+					publisher.PublishProduct(events.ProductUpdated, restaurantID, product.ID)
+				}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(report)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/export", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			products := productDB.GetProductsByRestaurant(restaurantID)
+
+			switch r.URL.Query().Get("format") {
+			case "", "json":
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Disposition", `attachment; filename="menu.json"`)
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(products)
+
+			case "csv":
+				w.Header().Set("Content-Type", "text/csv")
+				w.Header().Set("Content-Disposition", `attachment; filename="menu.csv"`)
+				w.WriteHeader(http.StatusOK)
+				if err := writeCSVExport(w, products); err != nil {
+					log.Printf("Error writing export: %v", err)
+				}
+
+			default:
+				http.Error(w, "format must be csv or json", http.StatusBadRequest)
+			}
+		},
+	)
+}
+
+// bulkFormat takes the format from the query, falling back to the request's
+// content type.
+func bulkFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+func parseJSONImport(body io.Reader) ([]importRow, error) {
+	var products []model.Product
+	if err := json.NewDecoder(body).Decode(&products); err != nil {
+		return nil, fmt.Errorf("invalid JSON, expected an array of products: %v", err)
+	}
+
+	rows := make([]importRow, 0, len(products))
+	for i, product := range products {
+		// Sales figures are managed by the service.
+		product.Popularity = 0
+		rows = append(rows, importRow{Line: i + 1, Product: product})
+	}
+	return rows, nil
+}
+
+func parseCSVImport(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV, expected a header row: %v", err)
+	}
+
+	columns := make(map[string]bool, len(header))
+	for _, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsString(csvColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q, expected %s", name, strings.Join(csvColumns, ", "))
+		}
+		columns[name] = true
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if !columns[required] {
+			return nil, fmt.Errorf("CSV column %q is required", required)
+		}
+	}
+
+	rows := make([]importRow, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+
+		row := importRow{Line: line, Product: model.Product{Available: true}, Columns: columns}
+		for i, value := range record {
+			if err := setCSVField(&row.Product, strings.ToLower(strings.TrimSpace(header[i])), strings.TrimSpace(value)); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func setCSVField(product *model.Product, column, value string) error {
+	switch column {
+	case "sku":
+		product.SKU = value
+	case "name":
+		product.Name = value
+	case "description":
+		product.Description = value
+	case "price":
+		price, err := money.ParseString(value)
+		if err != nil {
+			return fmt.Errorf("invalid price %q", value)
+		}
+		product.Price = price
+	case "category_ids":
+		product.CategoryIDs = make([]int, 0)
+		for _, item := range splitList(value) {
+			id, err := strconv.Atoi(item)
+			if err != nil {
+				return fmt.Errorf("invalid category ID %q", item)
+			}
+			product.CategoryIDs = append(product.CategoryIDs, id)
+		}
+	case "tags":
+		product.Tags = splitList(value)
+	case "available":
+		if value == "" {
+			return nil
+		}
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid available %q", value)
+		}
+		product.Available = available
+	case "allergens":
+		product.Allergens = splitAllergens(value)
+	case "dietary_labels":
+		product.DietaryLabels = splitList(value)
+	}
+	return nil
+}
+
+// mergeCSVRow copies the columns present in the file onto an existing
+// product, keeping everything CSV cannot express.
+func mergeCSVRow(existing model.Product, row importRow) model.Product {
+	merged := existing
+	for column := range row.Columns {
+		switch column {
+		case "name":
+			merged.Name = row.Product.Name
+		case "description":
+			merged.Description = row.Product.Description
+		case "price":
+			merged.Price = row.Product.Price
+		case "category_ids":
+			merged.CategoryIDs = row.Product.CategoryIDs
+		case "tags":
+			merged.Tags = row.Product.Tags
+		case "available":
+			merged.Available = row.Product.Available
+		case "allergens":
+			merged.Allergens = row.Product.Allergens
+		case "dietary_labels":
+			merged.DietaryLabels = row.Product.DietaryLabels
+		}
+	}
+	return merged
+}
+
+// planImport validates every row and sorts the valid ones into products to
+// create and products to update, matching on SKU. All row errors are
+// collected so the whole file can be fixed in one go.
+func planImport(
+	productDB *storage.ProductStorage,
+	categoryDB *storage.CategoryStorage,
+	ingredientDB *storage.IngredientStorage,
+	menuDB *storage.MenuStorage,
+	restaurantID int,
+	rows []importRow,
+) ([]model.Product, []model.Product, []importRowError) {
+	var (
+		creates   []model.Product
+		updates   []model.Product
+		rowErrors []importRowError
+	)
+
+	nextID := nextProductID(productDB, menuDB)
+	skus := make(map[string]int, len(rows))
+
+	for _, row := range rows {
+		product := row.Product
+		errs := row.Errors
+
+		if product.SKU == "" {
+			errs = append(errs, "sku is required")
+		} else if line, seen := skus[product.SKU]; seen {
+			errs = append(errs, fmt.Sprintf("sku %s is already used in row %d", product.SKU, line))
+		} else {
+			skus[product.SKU] = row.Line
+		}
+
+		existing, exists := productDB.GetProductBySKU(restaurantID, product.SKU)
+		if exists && existing.Archived() {
+			errs = append(errs, fmt.Sprintf("product %d with sku %s is archived, restore it first", existing.ID, product.SKU))
+		}
+		if exists {
+			if row.Columns != nil {
+				product = mergeCSVRow(*existing, row)
+			}
+			product.ID = existing.ID
+		} else {
+			product.ID = nextID
+		}
+		product.RestaurantID = restaurantID
+
+		errs = append(errs, validate.Struct(product).Messages()...)
+		for _, check := range []func() error{
+			func() error { return checkCategories(categoryDB, restaurantID, product.CategoryIDs) },
+			func() error { return checkRecipe(ingredientDB, restaurantID, product.Recipe) },
+			func() (err error) {
+				product.Translations, err = checkTranslations(product.Translations)
+				return err
+			},
+			product.PrepareOptionGroups,
+			func() error { return checkSchedule(product.Schedule) },
+			product.PrepareDietary,
+			product.PrepareTags,
+			func() error { return checkBundle(productDB, restaurantID, &product) },
+		} {
+			if err := check(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, importRowError{Row: row.Line, SKU: product.SKU, Errors: errs})
+			continue
+		}
+
+		if exists {
+			updates = append(updates, product)
+		} else {
+			product.IngredientShortage = len(product.Recipe) > 0 && !ingredientDB.CanMake(restaurantID, product.Recipe, 1)
+			product.RefreshSoldOut()
+			creates = append(creates, product)
+			nextID++
+		}
+	}
+
+	return creates, updates, rowErrors
+}
+
+func writeCSVExport(w io.Writer, products []model.Product) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		categoryIDs := make([]string, 0, len(product.CategoryIDs))
+		for _, id := range product.CategoryIDs {
+			categoryIDs = append(categoryIDs, strconv.Itoa(id))
+		}
+
+		if err := writer.Write([]string{
+			product.SKU,
+			product.Name,
+			product.Description,
+			product.Price.String(),
+			strings.Join(categoryIDs, ";"),
+			strings.Join(product.Tags, ";"),
+			strconv.FormatBool(product.Available),
+			joinAllergens(product.Allergens),
+			strings.Join(product.DietaryLabels, ";"),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// noAllergens is the allergens cell of a product that declares it contains
+// none. An empty cell leaves its allergens undeclared.
+const noAllergens = "none"
+
+func splitAllergens(value string) []string {
+	if strings.EqualFold(strings.TrimSpace(value), noAllergens) {
+		return make([]string, 0)
+	}
+	if items := splitList(value); len(items) > 0 {
+		return items
+	}
+	return nil
+}
+
+func joinAllergens(allergens []string) string {
+	if allergens != nil && len(allergens) == 0 {
+		return noAllergens
+	}
+	return strings.Join(allergens, ";")
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSKU reports an error when another product of the restaurant already
+// uses the SKU. Products without a SKU are not checked.
+func checkSKU(productDB *storage.ProductStorage, restaurantID int, sku string, id int) error {
+	if sku == "" {
+		return nil
+	}
+	if existing, exists := productDB.GetProductBySKU(restaurantID, sku); exists && existing.ID != id {
+		return fmt.Errorf("sku %s is already used by product %d", sku, existing.ID)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"restaurant/model"
+	"restaurant/storage"
+)
+
+// checkBundle prepares the bundle of a product, if it has one, and checks
+// that every slot offers existing products of the restaurant. Bundles cannot
+// contain other bundles, so a product offered by a bundle cannot become one.
+func checkBundle(productDB *storage.ProductStorage, restaurantID int, product *model.Product) error {
+	if product.Bundle == nil {
+		return nil
+	}
+
+	if err := product.Bundle.Prepare(); err != nil {
+		return err
+	}
+	if bundle, exists := productDB.GetBundleContaining(restaurantID, product.ID); exists {
+		return fmt.Errorf("product %d is offered by bundle %d and cannot be a bundle itself", product.ID, bundle.ID)
+	}
+	if amountOff := product.Bundle.AmountOff; amountOff != nil && amountOff.Currency() != product.Price.Currency() {
+		return fmt.Errorf("amount_off must be in %s, the currency of the bundle", product.Price.Currency())
+	}
+
+	for _, slot := range product.Bundle.Slots {
+		for _, id := range slot.ProductIDs {
+			component, exists := productDB.GetProductByID(restaurantID, id)
+			if !exists {
+				return fmt.Errorf("bundle slot %q: product %d not found", slot.Name, id)
+			}
+			if component.Archived() {
+				return fmt.Errorf("bundle slot %q: product %d is archived", slot.Name, id)
+			}
+			if component.Bundle != nil || component.ID == product.ID {
+				return fmt.Errorf("bundle slot %q: product %d is a bundle itself", slot.Name, id)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+)
+
+func registerCategoryRoutes(mux *http.ServeMux, categoryDB *storage.CategoryStorage, productDB *storage.ProductStorage, menuDB *storage.MenuStorage) {
+	mux.HandleFunc(
+		"/category", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				var category model.Category
+				if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+					log.Printf("Error decoding category: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if errs := validate.Struct(category); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				if err := checkSchedule(category.Schedule); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if category.Translations, err = checkTranslations(category.Translations); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				log.Printf("Creating category: %s (restaurant %d)", category.Name, restaurantID)
+
+				category.ID = categoryDB.NextCategoryID()
+				category.RestaurantID = restaurantID
+				categoryDB.AddCategory(category)
+
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":  "category created successfully",
+						"category": category,
+					},
+				)
+
+			case http.MethodGet:
+				categories := categoryDB.GetCategoriesByRestaurant(restaurantID)
+				if len(categories) == 0 {
+					w.WriteHeader(http.StatusNoContent)
+					json.NewEncoder(w).Encode(map[string]string{"message": "no categories found"})
+					return
+				}
+
+				language := requestLanguage(w, r)
+				w.Header().Set("Content-Language", language)
+				for i := range categories {
+					categories[i] = categories[i].Localized(language)
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(categories)
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/category/", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.URL.Path[len("/category/"):])
+			if err != nil {
+				http.Error(w, "Invalid category ID", http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				foundCategory, exists := categoryDB.GetCategoryByID(restaurantID, id)
+				if !exists {
+					http.Error(w, "Category not found", http.StatusNotFound)
+					return
+				}
+
+				language := requestLanguage(w, r)
+				w.Header().Set("Content-Language", language)
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					model.MenuSection{
+						Category: foundCategory.Localized(language),
+						Products: localizeProducts(productDB.GetProductsByCategory(restaurantID, id), language),
+					},
+				)
+
+			case http.MethodPut:
+				var updatedCategory model.Category
+				if err := json.NewDecoder(r.Body).Decode(&updatedCategory); err != nil {
+					log.Printf("Error decoding update category: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if errs := validate.Struct(updatedCategory); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				if err := checkSchedule(updatedCategory.Schedule); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				log.Printf("Updating category ID: %d", id)
+
+				if !categoryDB.UpdateCategory(restaurantID, id, updatedCategory) {
+					http.Error(w, "Category not found", http.StatusNotFound)
+					return
+				}
+
+				if stored, exists := categoryDB.GetCategoryByID(restaurantID, id); exists {
+					updatedCategory = *stored
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":  "category updated successfully",
+						"category": updatedCategory,
+					},
+				)
+
+			case http.MethodDelete:
+				if !categoryDB.DeleteCategory(restaurantID, id) {
+					http.Error(w, "Category not found", http.StatusNotFound)
+					return
+				}
+				productDB.RemoveCategory(restaurantID, id)
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "category deleted successfully"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			language := requestLanguage(w, r)
+			w.Header().Set("Content-Language", language)
+
+			categories := categoryDB.GetCategoriesByRestaurant(restaurantID)
+			for i := range categories {
+				categories[i] = categories[i].Localized(language)
+			}
+
+			menu := buildMenu(categories, localizeProducts(productDB.GetProductsByRestaurant(restaurantID), language))
+			menu.Version = menuDB.LiveVersion(restaurantID)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(menu)
+		},
+	)
+}
+
+// buildMenu groups products under their categories in category order. A
+// product assigned to several categories appears in each of them.
+func buildMenu(categories []model.Category, products []model.Product) model.Menu {
+	menu := model.Menu{Sections: make([]model.MenuSection, 0, len(categories))}
+
+	known := make(map[int]bool, len(categories))
+	for _, category := range categories {
+		known[category.ID] = true
+
+		section := model.MenuSection{Category: category, Products: make([]model.Product, 0)}
+		for _, product := range products {
+			if product.InCategory(category.ID) {
+				section.Products = append(section.Products, product)
+			}
+		}
+		menu.Sections = append(menu.Sections, section)
+	}
+
+	for _, product := range products {
+		categorized := false
+		for _, id := range product.CategoryIDs {
+			if known[id] {
+				categorized = true
+				break
+			}
+		}
+		if !categorized {
+			menu.Uncategorized = append(menu.Uncategorized, product)
+		}
+	}
+
+	return menu
+}
+
+// checkCategories reports the first category ID that does not belong to the
+// restaurant.
+func checkCategories(categoryDB *storage.CategoryStorage, restaurantID int, categoryIDs []int) error {
+	for _, id := range categoryIDs {
+		if _, exists := categoryDB.GetCategoryByID(restaurantID, id); !exists {
+			return fmt.Errorf("category %d not found", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant/model"
+	"restaurant/money"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"time"
+)
+
+func registerCurrencyRoutes(mux *http.ServeMux, rateDB *storage.ExchangeRateStorage) {
+	mux.HandleFunc(
+		"/exchange-rates", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(rateDB.GetRatesByRestaurant(restaurantID))
+		},
+	)
+
+	mux.HandleFunc(
+		"/exchange-rates/{base}/{currency}", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			base, err := money.ParseCurrency(r.PathValue("base"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			currency, err := money.ParseCurrency(r.PathValue("currency"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				rate, exists := rateDB.GetRate(restaurantID, base, currency)
+				if !exists {
+					http.Error(w, "Exchange rate not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(rate)
+
+			case http.MethodPut:
+				var request model.ExchangeRateRequest
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					log.Printf("Error decoding exchange rate: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if errs := validate.Struct(request); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				if base == currency {
+					http.Error(w, "base and currency must differ", http.StatusBadRequest)
+					return
+				}
+
+				rate := rateDB.SetRate(model.ExchangeRate{
+					RestaurantID: restaurantID,
+					Base:         base,
+					Currency:     currency,
+					Rate:         request.Rate,
+					UpdatedAt:    time.Now().UTC(),
+				})
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":       "exchange rate set",
+						"exchange_rate": rate,
+					},
+				)
+
+			case http.MethodDelete:
+				if !rateDB.DeleteRate(restaurantID, base, currency) {
+					http.Error(w, "Exchange rate not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "exchange rate deleted"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+}
+
+// productInCurrency shows the product in currency using the restaurant's
+// rate from the product's own currency.
+func productInCurrency(rateDB *storage.ExchangeRateStorage, product model.Product, currency string) (model.Product, error) {
+	rate, _ := rateDB.GetRate(product.RestaurantID, product.Price.Currency(), currency)
+	converted, err := product.InCurrency(currency, rate)
+	if err != nil {
+		return model.Product{}, fmt.Errorf("%s: %w", product.Name, err)
+	}
+	return converted, nil
+}
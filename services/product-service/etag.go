@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"restaurant/model"
+	"restaurant/storage"
+	"strings"
+)
+
+// productETag is the strong entity tag of a product version.
+func productETag(product model.Product) string {
+	return fmt.Sprintf(`"%d-%d"`, product.ID, product.Version)
+}
+
+// variantETag tags a product shown in another currency or language. The
+// representation depends on more than the product version, such as the
+// exchange rate, so the tag is weak and never satisfies If-Match.
+func variantETag(product model.Product, variants []string) string {
+	return fmt.Sprintf(`W/"%d-%d-%s"`, product.ID, product.Version, strings.Join(variants, "-"))
+}
+
+// currencyVariant names the currency and exchange rate a product is shown
+// in, for variantETag.
+func currencyVariant(rateDB *storage.ExchangeRateStorage, product model.Product, currency string) string {
+	rate := "fixed"
+	if exchangeRate, exists := rateDB.GetRate(product.RestaurantID, product.Price.Currency(), currency); exists {
+		rate = exchangeRate.Rate.String()
+	}
+	return currency + "-" + rate
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists the
+// tag. If-Match compares strongly, so weak tags never match it.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch answers 412 and returns false when the request carries an
+// If-Match header that does not name the product's current version.
+// Requests without the header are let through.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, product model.Product) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, productETag(product), false) {
+		return true
+	}
+
+	w.Header().Set("ETag", productETag(product))
+	http.Error(w, "product was modified by another request", http.StatusPreconditionFailed)
+	return false
+}
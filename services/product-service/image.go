@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"restaurant/blob"
+	"restaurant/events"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/thumbnail"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decoding holds four bytes per pixel in memory, so besides the file size the
+// pixel count is capped: 16 megapixels decode to about 64 MB.
+const (
+	maxImageSize      = 5 << 20
+	maxImageDimension = 8000
+	maxImagePixels    = 16_000_000
+	imageURLPrefix    = "/images/"
+)
+
+// thumbnailSizes maps thumbnail names to the longest side in pixels.
+var thumbnailSizes = map[string]int{
+	"small":  150,
+	"medium": 600,
+}
+
+// imageFormats maps the accepted upload types to file extensions.
+var imageFormats = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// newBlobStore stores images below BLOB_DIR, defaulting to data/images in the
+// working directory.
+func newBlobStore() (blob.Store, error) {
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = "data/images"
+	}
+	return blob.NewDiskStore(dir)
+}
+
+// registerImageRoutes serves product images. Decoding, resizing and writing
+// the files is slow, so the routes are not guarded and only hold the store
+// lock while reading or updating the product.
+func registerImageRoutes(
+	mux *http.ServeMux,
+	lock *sync.RWMutex,
+	productDB *storage.ProductStorage,
+	blobs blob.Store,
+	publisher *events.Publisher,
+) {
+	mux.HandleFunc(
+		"/product/{id}/image", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			lock.RLock()
+			_, exists := productDB.GetProductByID(restaurantID, id)
+			lock.RUnlock()
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				data, err := readImageUpload(w, r)
+				if err != nil {
+					var maxBytesError *http.MaxBytesError
+					if errors.As(err, &maxBytesError) {
+						http.Error(w, fmt.Sprintf("image must not exceed %d bytes", maxImageSize), http.StatusRequestEntityTooLarge)
+						return
+					}
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				contentType := http.DetectContentType(data)
+				if _, accepted := imageFormats[contentType]; !accepted {
+					http.Error(w, "image must be JPEG, PNG or GIF", http.StatusUnsupportedMediaType)
+					return
+				}
+
+				log.Printf("Uploading image for product %d (%s, %d bytes)", id, contentType, len(data))
+
+				productImage, err := storeImage(blobs, restaurantID, id, contentType, data)
+				if err != nil {
+					log.Printf("Error storing image: %v", err)
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				// The product may have changed while the image was
+				// processed; SetImage checks it again.
+				lock.Lock()
+				previous, err := productDB.SetImage(restaurantID, id, productImage)
+				lock.Unlock()
+				if err != nil {
+					deleteImage(blobs, productImage)
+					http.Error(w, err.Error(), stockErrorStatus(err))
+					return
+				}
+				deleteImage(blobs, previous)
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message": "image uploaded successfully",
+						"image":   productImage,
+					},
+				)
+
+			case http.MethodDelete:
+				lock.Lock()
+				previous, err := productDB.SetImage(restaurantID, id, nil)
+				lock.Unlock()
+				if err != nil {
+					http.Error(w, err.Error(), stockErrorStatus(err))
+					return
+				}
+				if previous == nil {
+					http.Error(w, "Product has no image", http.StatusNotFound)
+					return
+				}
+				deleteImage(blobs, previous)
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "image deleted successfully"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/images/{key...}", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			key := r.PathValue("key")
+			reader, err := blobs.Get(key)
+			if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+				http.Error(w, "Image not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("Error reading image %s: %v", key, err)
+				http.Error(w, "error reading image", http.StatusInternalServerError)
+				return
+			}
+			defer reader.Close()
+
+			// Keys are never reused, so images can be cached indefinitely.
+			w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			w.WriteHeader(http.StatusOK)
+			io.Copy(w, reader)
+		},
+	)
+}
+
+// readImageUpload returns the "image" file of a multipart request, reading at
+// most maxImageSize bytes.
+func readImageUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	// Leave room for the multipart framing around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize+64<<10)
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("expected a multipart form with an image field")
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return nil, fmt.Errorf("expected a multipart form with an image field")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading image")
+	}
+	if len(data) > maxImageSize {
+		return nil, &http.MaxBytesError{Limit: maxImageSize}
+	}
+	return data, nil
+}
+
+// storeImage decodes the upload, writes the original and one thumbnail per
+// size, and returns the resulting image description. Keys include the upload
+// time so a replaced image never shares a URL with its predecessor.
+func storeImage(blobs blob.Store, restaurantID, productID int, contentType string, data []byte) (*model.ProductImage, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image could not be decoded")
+	}
+	if config.Width > maxImageDimension || config.Height > maxImageDimension {
+		return nil, fmt.Errorf("image must not exceed %dx%d pixels", maxImageDimension, maxImageDimension)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("image must not exceed %d megapixels", maxImagePixels/1_000_000)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image could not be decoded")
+	}
+
+	prefix := fmt.Sprintf("products/%d/%d/%d", restaurantID, productID, time.Now().UnixNano())
+	extension := imageFormats[contentType]
+
+	productImage := &model.ProductImage{
+		URL:         imageURLPrefix + prefix + "-original." + extension,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Thumbnails:  make(map[string]string, len(thumbnailSizes)),
+	}
+
+	if err := blobs.Put(imageKey(productImage.URL), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	for name, size := range thumbnailSizes {
+		var buf bytes.Buffer
+		key := prefix + "-" + name
+
+		// GIF thumbnails are stored as PNG to keep their quality.
+		resized := thumbnail.Fit(img, size)
+		if contentType == "image/jpeg" {
+			key += ".jpg"
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		} else {
+			key += ".png"
+			err = png.Encode(&buf, resized)
+		}
+		if err == nil {
+			err = blobs.Put(key, &buf)
+		}
+		if err != nil {
+			deleteImage(blobs, productImage)
+			return nil, fmt.Errorf("error creating %s thumbnail: %w", name, err)
+		}
+
+		productImage.Thumbnails[name] = imageURLPrefix + key
+	}
+
+	return productImage, nil
+}
+
+// deleteImage removes the files of an image. Failures are only logged; an
+// orphaned file does no harm.
+func deleteImage(blobs blob.Store, productImage *model.ProductImage) {
+	if productImage == nil {
+		return
+	}
+	for _, url := range productImage.URLs() {
+		if err := blobs.Delete(imageKey(url)); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			log.Printf("Error deleting image %s: %v", url, err)
+		}
+	}
+}
+
+func imageKey(url string) string {
+	return strings.TrimPrefix(url, imageURLPrefix)
+}
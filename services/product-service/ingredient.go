@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant/events"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+)
+
+func registerIngredientRoutes(
+	mux *http.ServeMux,
+	ingredientDB *storage.IngredientStorage,
+	productDB *storage.ProductStorage,
+	publisher *events.Publisher,
+) {
+	mux.HandleFunc(
+		"/ingredient", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			switch r.Method {
+			case http.MethodPost:
+				var ingredient model.Ingredient
+				if err := json.NewDecoder(r.Body).Decode(&ingredient); err != nil {
+					log.Printf("Error decoding ingredient: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if errs := validate.Struct(ingredient); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				log.Printf("Creating ingredient: %s (restaurant %d)", ingredient.Name, restaurantID)
+
+				ingredient.ID = ingredientDB.NextIngredientID()
+				ingredient.RestaurantID = restaurantID
+				ingredientDB.AddIngredient(ingredient)
+
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":    "ingredient created successfully",
+						"ingredient": ingredient,
+					},
+				)
+
+			case http.MethodGet:
+				ingredients := ingredientDB.GetIngredientsByRestaurant(restaurantID)
+				if len(ingredients) == 0 {
+					w.WriteHeader(http.StatusNoContent)
+					json.NewEncoder(w).Encode(map[string]string{"message": "no ingredients found"})
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(ingredients)
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/ingredient/{id}", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid ingredient ID", http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				foundIngredient, exists := ingredientDB.GetIngredientByID(restaurantID, id)
+				if !exists {
+					http.Error(w, "Ingredient not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(foundIngredient)
+
+			case http.MethodPut:
+				var updatedIngredient model.Ingredient
+				if err := json.NewDecoder(r.Body).Decode(&updatedIngredient); err != nil {
+					log.Printf("Error decoding update ingredient: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if errs := validate.Struct(updatedIngredient); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				log.Printf("Updating ingredient ID: %d", id)
+
+				if !ingredientDB.UpdateIngredient(restaurantID, id, updatedIngredient) {
+					http.Error(w, "Ingredient not found", http.StatusNotFound)
+					return
+				}
+
+				if stored, exists := ingredientDB.GetIngredientByID(restaurantID, id); exists {
+					updatedIngredient = *stored
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":    "ingredient updated successfully",
+						"ingredient": updatedIngredient,
+					},
+				)
+
+			case http.MethodDelete:
+				if productDB.UsesIngredient(restaurantID, id) {
+					http.Error(w, "ingredient is used in a recipe", http.StatusConflict)
+					return
+				}
+
+				if !ingredientDB.DeleteIngredient(restaurantID, id) {
+					http.Error(w, "Ingredient not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "ingredient deleted successfully"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/ingredient/{id}/stock", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid ingredient ID", http.StatusBadRequest)
+				return
+			}
+
+			var request model.IngredientStockRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				log.Printf("Error decoding ingredient stock adjustment: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			if errs := validate.Struct(request); errs != nil {
+				validate.WriteErrors(w, errs)
+				return
+			}
+
+			if err := checkStockReason(request.Reason, request.Change); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("Adjusting stock of ingredient %d by %.2f (%s)", id, request.Change, request.Reason)
+
+			ingredient, err := ingredientDB.AdjustStock(restaurantID, id, request.Change)
+			if err != nil {
+				http.Error(w, err.Error(), stockErrorStatus(err))
+				return
+			}
+
+			refreshIngredientShortages(productDB, ingredientDB, publisher, restaurantID)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message":    "ingredient stock adjusted successfully",
+					"ingredient": ingredient,
+					"low_stock":  ingredient.LowStock(),
+				},
+			)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/{id}/recipe", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				foundProduct, exists := productDB.GetProductByID(restaurantID, id)
+				if !exists {
+					http.Error(w, "Product not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(foundProduct.Recipe)
+
+			case http.MethodPut:
+				var recipe []model.RecipeItem
+				if err := json.NewDecoder(r.Body).Decode(&recipe); err != nil {
+					log.Printf("Error decoding recipe: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if err := checkRecipe(ingredientDB, restaurantID, recipe); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				log.Printf("Setting recipe of product %d", id)
+
+				product, err := productDB.SetRecipe(restaurantID, id, recipe)
+				if err != nil {
+					http.Error(w, err.Error(), stockErrorStatus(err))
+					return
+				}
+
+				refreshIngredientShortages(productDB, ingredientDB, publisher, restaurantID)
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message": "recipe updated successfully",
+						"product": product,
+					},
+				)
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+}
+
+// checkRecipe reports the first recipe item that references an unknown
+// ingredient, repeats one, or has a non-positive quantity.
+func checkRecipe(ingredientDB *storage.IngredientStorage, restaurantID int, recipe []model.RecipeItem) error {
+	seen := make(map[int]bool, len(recipe))
+	for _, item := range recipe {
+		if _, exists := ingredientDB.GetIngredientByID(restaurantID, item.IngredientID); !exists {
+			return fmt.Errorf("ingredient %d not found", item.IngredientID)
+		}
+		if seen[item.IngredientID] {
+			return fmt.Errorf("ingredient %d is listed twice", item.IngredientID)
+		}
+		if item.Quantity <= 0 {
+			return fmt.Errorf("quantity of ingredient %d must be positive", item.IngredientID)
+		}
+		seen[item.IngredientID] = true
+	}
+	return nil
+}
+
+// refreshIngredientShortages marks products of the restaurant sold out when
+// an ingredient of their recipe runs short, and back in stock once it is
+// replenished. Subscribers are told about every product that changed.
+func refreshIngredientShortages(
+	productDB *storage.ProductStorage,
+	ingredientDB *storage.IngredientStorage,
+	publisher *events.Publisher,
+	restaurantID int,
+) {
+	changed := productDB.RefreshIngredientShortages(restaurantID, func(recipe []model.RecipeItem) bool {
+		return ingredientDB.CanMake(restaurantID, recipe, 1)
+	})
+
+	for _, id := range changed {
+		publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+	}
+}
+
+// checkStockReason validates the reason of a manual stock adjustment against
+// the direction of the change.
+func checkStockReason(reason string, change float64) error {
+	switch {
+	case reason == model.StockReasonDelivery && change <= 0:
+		return errors.New("a delivery must increase stock")
+	case reason == model.StockReasonWaste && change >= 0:
+		return errors.New("waste must decrease stock")
+	case reason == model.StockReasonCorrection && change == 0:
+		return errors.New("a correction must change stock")
+	case reason != model.StockReasonDelivery &&
+		reason != model.StockReasonWaste &&
+		reason != model.StockReasonCorrection:
+		return errors.New("reason must be one of delivery, waste, correction")
+	}
+	return nil
+}
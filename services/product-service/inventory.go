@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant/events"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+	"time"
+)
+
+func registerInventoryRoutes(
+	mux *http.ServeMux,
+	transactor *storage.MemoryTransactor,
+	productDB *storage.ProductStorage,
+	stockDB *storage.StockStorage,
+	ingredientDB *storage.IngredientStorage,
+	publisher *events.Publisher,
+) {
+	// sell deducts a sold quantity from stock and ingredients. It must run
+	// inside a transaction so a failed item undoes the ones before it.
+	sell := func(restaurantID, id, quantity int) (*model.Product, error) {
+		product, err := productDB.RecordSale(restaurantID, id, quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ingredientDB.ConsumeRecipe(restaurantID, product.Recipe, quantity); err != nil {
+			return nil, err
+		}
+
+		if product.TrackStock {
+			stockDB.AddAdjustment(model.StockAdjustment{
+				RestaurantID: restaurantID,
+				ProductID:    id,
+				Change:       -quantity,
+				Reason:       model.StockReasonSale,
+				StockAfter:   product.Stock,
+				CreatedAt:    time.Now().UTC(),
+			})
+		}
+		return product, nil
+	}
+
+	// afterSale tells subscribers about stock changes of the sold products.
+	afterSale := func(restaurantID int, products []*model.Product) {
+		usedIngredients := false
+		for _, product := range products {
+			if product.TrackStock {
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, product.ID)
+			}
+			if len(product.Recipe) > 0 {
+				usedIngredients = true
+			}
+		}
+		if usedIngredients {
+			refreshIngredientShortages(productDB, ingredientDB, publisher, restaurantID)
+		}
+	}
+
+	mux.HandleFunc(
+		"/product/{id}/sales", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			if err := tenant.RequireService(r); err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			var sale struct {
+				Quantity int `json:"quantity"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&sale); err != nil || sale.Quantity <= 0 {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			var product *model.Product
+			err = storage.WithTransaction(transactor, func(tx storage.Tx) error {
+				product, err = sell(restaurantID, id, sale.Quantity)
+				return err
+			})
+			if err != nil {
+				http.Error(w, err.Error(), stockErrorStatus(err))
+				return
+			}
+
+			afterSale(restaurantID, []*model.Product{product})
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message": "sale recorded",
+					"product": product,
+				},
+			)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/sales", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			if err := tenant.RequireService(r); err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			var sale model.SaleRequest
+			if err := json.NewDecoder(r.Body).Decode(&sale); err != nil || len(sale.Items) == 0 {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			for _, item := range sale.Items {
+				if item.Quantity <= 0 {
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+			}
+			if errs := validate.Struct(sale); errs != nil {
+				validate.WriteErrors(w, errs)
+				return
+			}
+
+			// A retried request must not deduct the stock again.
+			if sale.SaleID != "" {
+				if recorded, exists := stockDB.GetSale(restaurantID, sale.SaleID); exists {
+					if recorded.Cancelled() {
+						http.Error(w, fmt.Sprintf("sale %s was cancelled", sale.SaleID), http.StatusConflict)
+						return
+					}
+
+					w.WriteHeader(http.StatusOK)
+					json.NewEncoder(w).Encode(
+						map[string]interface{}{
+							"message": "sale already recorded",
+							"sale":    recorded,
+						},
+					)
+					return
+				}
+			}
+
+			log.Printf("Recording sale of %d items (restaurant %d)", len(sale.Items), restaurantID)
+
+			products := make([]*model.Product, 0, len(sale.Items))
+			err = storage.WithTransaction(transactor, func(tx storage.Tx) error {
+				record := model.Sale{ID: sale.SaleID, RestaurantID: restaurantID, Items: sale.Items, CreatedAt: time.Now().UTC()}
+				for _, item := range sale.Items {
+					product, err := sell(restaurantID, item.ProductID, item.Quantity)
+					if err != nil {
+						return fmt.Errorf("product %d: %w", item.ProductID, err)
+					}
+					products = append(products, product)
+
+					if product.TrackStock {
+						record.Restocked = append(record.Restocked, item)
+					}
+					for _, ingredient := range product.Recipe {
+						ingredient.Quantity *= float64(item.Quantity)
+						record.Ingredients = append(record.Ingredients, ingredient)
+					}
+				}
+
+				if record.ID != "" {
+					stockDB.AddSale(record)
+				}
+				return nil
+			})
+			if err != nil {
+				http.Error(w, err.Error(), stockErrorStatus(err))
+				return
+			}
+
+			afterSale(restaurantID, products)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message":  "sale recorded",
+					"products": products,
+				},
+			)
+		},
+	)
+
+	// The order service cancels a sale when it cannot tell whether the sale
+	// went through, so cancelling an unknown sale is not an error to it. The
+	// cancellation is still recorded, so a sale request that arrives late is
+	// refused instead of taking stock for an order that does not exist.
+	mux.HandleFunc(
+		"/product/sales/cancel", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			if err := tenant.RequireService(r); err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			var request struct {
+				SaleID string `json:"sale_id"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.SaleID == "" {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			recorded, exists := stockDB.GetSale(restaurantID, request.SaleID)
+			if !exists {
+				now := time.Now().UTC()
+				stockDB.AddSale(model.Sale{ID: request.SaleID, RestaurantID: restaurantID, CreatedAt: now, CancelledAt: now})
+				http.Error(w, "Sale not found", http.StatusNotFound)
+				return
+			}
+			if recorded.Cancelled() {
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{"message": "sale already cancelled", "sale": recorded})
+				return
+			}
+
+			log.Printf("Cancelling sale %s (restaurant %d)", request.SaleID, restaurantID)
+
+			restocked := make(map[int]bool, len(recorded.Restocked))
+			for _, item := range recorded.Restocked {
+				restocked[item.ProductID] = true
+			}
+
+			var (
+				sale     *model.Sale
+				products []*model.Product
+			)
+			err = storage.WithTransaction(transactor, func(tx storage.Tx) error {
+				now := time.Now().UTC()
+				for _, item := range recorded.Items {
+					product, err := productDB.CancelSale(restaurantID, item.ProductID, item.Quantity, restocked[item.ProductID])
+					if errors.Is(err, storage.ErrProductNotFound) {
+						continue
+					}
+					if err != nil {
+						return fmt.Errorf("product %d: %w", item.ProductID, err)
+					}
+					products = append(products, product)
+
+					if restocked[item.ProductID] && product.TrackStock {
+						stockDB.AddAdjustment(model.StockAdjustment{
+							RestaurantID: restaurantID,
+							ProductID:    item.ProductID,
+							Change:       item.Quantity,
+							Reason:       model.StockReasonSaleCancelled,
+							Note:         "sale " + request.SaleID,
+							StockAfter:   product.Stock,
+							CreatedAt:    now,
+						})
+					}
+				}
+				ingredientDB.ReturnIngredients(restaurantID, recorded.Ingredients)
+
+				sale, err = stockDB.CancelSale(restaurantID, request.SaleID, now)
+				return err
+			})
+			if err != nil {
+				http.Error(w, err.Error(), stockErrorStatus(err))
+				return
+			}
+
+			afterSale(restaurantID, products)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message": "sale cancelled",
+					"sale":    sale,
+				},
+			)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/{id}/stock", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			var request model.StockAdjustmentRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				log.Printf("Error decoding stock adjustment: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			if errs := validate.Struct(request); errs != nil {
+				validate.WriteErrors(w, errs)
+				return
+			}
+
+			if err := checkStockReason(request.Reason, float64(request.Change)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("Adjusting stock of product %d by %d (%s)", id, request.Change, request.Reason)
+
+			var (
+				product    *model.Product
+				adjustment model.StockAdjustment
+			)
+			err = storage.WithTransaction(transactor, func(tx storage.Tx) error {
+				product, err = productDB.AdjustStock(restaurantID, id, request.Change)
+				if err != nil {
+					return err
+				}
+
+				adjustment = stockDB.AddAdjustment(model.StockAdjustment{
+					RestaurantID: restaurantID,
+					ProductID:    id,
+					Change:       request.Change,
+					Reason:       request.Reason,
+					Note:         request.Note,
+					StockAfter:   product.Stock,
+					CreatedAt:    time.Now().UTC(),
+				})
+				return nil
+			})
+			if err != nil {
+				http.Error(w, err.Error(), stockErrorStatus(err))
+				return
+			}
+
+			publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message":    "stock adjusted successfully",
+					"adjustment": adjustment,
+					"product":    product,
+					"low_stock":  product.LowStock(),
+				},
+			)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/{id}/stock/history", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			if _, exists := productDB.GetProductByID(restaurantID, id); !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(stockDB.GetAdjustmentsByProduct(restaurantID, id))
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/low-stock", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(productDB.GetLowStockProducts(restaurantID))
+		},
+	)
+}
+
+func stockErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrProductNotFound),
+		errors.Is(err, storage.ErrIngredientNotFound),
+		errors.Is(err, storage.ErrSaleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrProductUnavailable),
+		errors.Is(err, storage.ErrStockNotTracked),
+		errors.Is(err, storage.ErrInsufficientStock),
+		errors.Is(err, storage.ErrInsufficientIngredients):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
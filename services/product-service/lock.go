@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// guard serves h while holding lock: shared for GET and HEAD requests,
+// exclusive for all others. Handlers that call other services or process
+// images are left unguarded and lock around their own storage access instead,
+// so slow work does not hold up every request, and two services calling each
+// other cannot deadlock.
+func guard(lock *sync.RWMutex, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			lock.RLock()
+			defer lock.RUnlock()
+		} else {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -5,37 +5,191 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"restaurant/backup"
+	"restaurant/events"
+	"restaurant/fixture"
+	"restaurant/locale"
+	"restaurant/mergepatch"
 	"restaurant/model"
+	"restaurant/money"
 	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/token"
+	"restaurant/validate"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 func main() {
+	if err := token.RequireSecret(); err != nil && !fixture.Local() {
+		log.Fatalf("Refusing to start in %s: %v", fixture.Environment(), err)
+	}
+
 	productDB := storage.NewProductStorage()
+	categoryDB := storage.NewCategoryStorage()
+	stockDB := storage.NewStockStorage()
+	ingredientDB := storage.NewIngredientStorage()
+	priceDB := storage.NewPriceStorage()
+	rateDB := storage.NewExchangeRateStorage()
+	menuDB := storage.NewMenuStorage()
+	transactor := storage.NewMemoryTransactor(productDB, categoryDB, stockDB, ingredientDB, priceDB, rateDB, menuDB)
+	// lock guards every store above; transactions are only begun while it
+	// is held exclusively.
+	lock := &sync.RWMutex{}
+	publisher := events.NewPublisherFromEnv("PRODUCT_EVENT_SUBSCRIBERS", "http://order-service:8080/events/product")
 
-	if productDB.GetProductCount() == 0 {
-		productDB.AddProduct(model.Product{ID: 1, Name: "Burger", Description: "Delicious beef burger", Price: 15.99})
-		productDB.AddProduct(model.Product{ID: 2, Name: "Pizza", Description: "Margherita pizza", Price: 12.50})
-		productDB.AddProduct(model.Product{ID: 3, Name: "Salad", Description: "Fresh garden salad", Price: 8.99})
+	blobs, err := newBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to open image store: %v", err)
+	}
+
+	seedProducts := func() error {
+		categories, err := fixture.LoadCategories(fixture.Environment())
+		if err != nil {
+			return err
+		}
+
+		ingredients, err := fixture.LoadIngredients(fixture.Environment())
+		if err != nil {
+			return err
+		}
+
+		products, err := fixture.LoadProducts(fixture.Environment())
+		if err != nil {
+			return err
+		}
+
+		stockDB.Reset()
+		priceDB.Reset()
+		rateDB.Reset()
+		menuDB.Reset()
+		categoryDB.Reset()
+		for _, category := range categories {
+			categoryDB.AddCategory(category)
+		}
+
+		ingredientDB.Reset()
+		for _, ingredient := range ingredients {
+			ingredientDB.AddIngredient(ingredient)
+		}
+
+		productDB.Reset()
+		restaurantIDs := make(map[int]bool)
+		for _, product := range products {
+			productDB.AddProduct(product)
+			restaurantIDs[product.RestaurantID] = true
+		}
+		for restaurantID := range restaurantIDs {
+			productDB.RefreshIngredientShortages(restaurantID, func(recipe []model.RecipeItem) bool {
+				return ingredientDB.CanMake(restaurantID, recipe, 1)
+			})
+		}
+		return nil
+	}
+
+	if fixture.SeedingEnabled() {
+		if err := seedProducts(); err != nil {
+			log.Fatalf("Failed to seed products: %v", err)
+		}
 	}
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc(
 		"/product", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
 			if r.Method == http.MethodPost {
-				var product model.Product
+				draft, err := draftRequested(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				product := model.Product{Available: true}
 				if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
 					log.Printf("Error decoding product: %v", err)
 					http.Error(w, "Invalid request body", http.StatusBadRequest)
 					return
 				}
 
-				log.Printf("Creating product: %s", product.Name)
+				if errs := validate.Struct(product); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				log.Printf("Creating product: %s (restaurant %d)", product.Name, restaurantID)
+
+				if err := checkSKU(productDB, restaurantID, product.SKU, 0); err != nil {
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
 
-				product.ID = productDB.GetProductCount() + 1
+				if err := checkCategories(categoryDB, restaurantID, product.CategoryIDs); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := checkRecipe(ingredientDB, restaurantID, product.Recipe); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if product.Translations, err = checkTranslations(product.Translations); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := product.PrepareOptionGroups(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := product.PreparePrices(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := checkSchedule(product.Schedule); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := product.PrepareDietary(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := product.PrepareTags(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				product.ID = nextProductID(productDB, menuDB)
+				if err := checkBundle(productDB, restaurantID, &product); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				product.RestaurantID = restaurantID
+				product.Popularity = 0
+				product.IngredientShortage = len(product.Recipe) > 0 && !ingredientDB.CanMake(restaurantID, product.Recipe, 1)
+				product.Version = 1
+				product.RefreshSoldOut()
+
+				if draft {
+					stageDraft(w, r, menuDB, model.DraftChange{RestaurantID: restaurantID, ProductID: product.ID, Action: model.DraftCreate, Product: &product})
+					return
+				}
 				productDB.AddProduct(product)
 
+				w.Header().Set("ETag", productETag(product))
 				w.WriteHeader(http.StatusCreated)
 				json.NewEncoder(w).Encode(
 					map[string]interface{}{
@@ -44,19 +198,59 @@ func main() {
 					},
 				)
 			} else if r.Method == http.MethodGet {
-				if len(productDB.Products) == 0 {
+				currency, err := money.RequestedCurrency(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				query, err := parseProductQuery(r.URL.Query(), currency)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				allProducts := productDB.GetProductsByRestaurant(restaurantID)
+				if query.archived {
+					allProducts = productDB.GetArchivedProducts(restaurantID)
+				}
+				if len(allProducts) == 0 {
 					w.WriteHeader(http.StatusNoContent)
 					json.NewEncoder(w).Encode(map[string]string{"message": "no products found"})
 					return
 				}
 
-				for _, product := range productDB.Products {
+				// Prices are converted before filtering and sorting so both
+				// work in the currency the client sees.
+				if currency != "" {
+					w.Header().Add("Vary", money.Header)
+					for i, product := range allProducts {
+						if allProducts[i], err = productInCurrency(rateDB, product, currency); err != nil {
+							http.Error(w, err.Error(), http.StatusBadRequest)
+							return
+						}
+					}
+				}
+
+				// Likewise names and descriptions are localized first so a
+				// search matches the text the client sees.
+				language := requestLanguage(w, r)
+				w.Header().Set("Content-Language", language)
+				allProducts = localizeProducts(allProducts, language)
+
+				query.categories = categoryDB.GetCategoriesByRestaurant(restaurantID)
+				products, total := query.apply(allProducts)
+				if query.paginated {
+					setPaginationHeaders(w, r, query, total)
+				}
+
+				for _, product := range products {
 					fmt.Println("ID\tNAME\tDESCRIPTION\tPRICE")
-					fmt.Printf("%d\t%s\t%s\t%.2f\n", product.ID, product.Name, product.Description, product.Price)
+					fmt.Printf("%d\t%s\t%s\t%s\n", product.ID, product.Name, product.Description, product.Price)
 				}
 
 				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(productDB.Products)
+				json.NewEncoder(w).Encode(products)
 			} else {
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 				return
@@ -66,6 +260,12 @@ func main() {
 
 	mux.HandleFunc(
 		"/product/", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
 			path := r.URL.Path[len("/product/"):]
 			id, err := strconv.Atoi(path)
 			if err != nil {
@@ -75,30 +275,175 @@ func main() {
 
 			switch r.Method {
 			case http.MethodGet:
-				foundProduct, exists := productDB.GetProductByID(id)
+				foundProduct, exists := productDB.GetProductByID(restaurantID, id)
 				if !exists {
 					http.Error(w, "Product not found", http.StatusNotFound)
 					return
 				}
 
+				currency, err := money.RequestedCurrency(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				shownProduct := *foundProduct
+				variants := make([]string, 0, 2)
+				if currency != "" {
+					if shownProduct, err = productInCurrency(rateDB, shownProduct, currency); err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					variants = append(variants, currencyVariant(rateDB, *foundProduct, currency))
+					w.Header().Add("Vary", money.Header)
+				}
+
+				// Content-Language names the language actually shown, which
+				// falls back to the default one when the product has no
+				// translation into the negotiated language.
+				language := requestLanguage(w, r)
+				if _, translated := foundProduct.Translations[language]; translated {
+					shownProduct = shownProduct.Localized(language)
+					variants = append(variants, language)
+				} else {
+					language = locale.Default
+				}
+				w.Header().Set("Content-Language", language)
+
+				etag := productETag(*foundProduct)
+				if len(variants) > 0 {
+					etag = variantETag(*foundProduct, variants)
+				}
+				w.Header().Set("ETag", etag)
+				if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, strings.TrimPrefix(etag, "W/"), true) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+
 				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(foundProduct)
+				json.NewEncoder(w).Encode(shownProduct)
+
+			case http.MethodPut, http.MethodPatch:
+				draft, err := draftRequested(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				currentProduct, exists := productDB.GetProductByID(restaurantID, id)
+				if !exists {
+					http.Error(w, "Product not found", http.StatusNotFound)
+					return
+				}
+				if !checkIfMatch(w, r, *currentProduct) {
+					return
+				}
+				if currentProduct.Archived() {
+					http.Error(w, "product is archived, restore it before editing", http.StatusConflict)
+					return
+				}
+				oldPrice := currentProduct.Price
+				baseVersion := currentProduct.Version
+
+				// A draft PATCH builds on the change already staged for the
+				// product, if there is one.
+				patchBase := *currentProduct
+				if staged, exists := menuDB.GetDraft(restaurantID, id); draft && exists && staged.Product != nil {
+					patchBase = *staged.Product
+				}
 
-			case http.MethodPut:
-				var updatedProduct model.Product
-				if err := json.NewDecoder(r.Body).Decode(&updatedProduct); err != nil {
+				// PUT replaces the product, PATCH merges a JSON merge patch
+				// into it. Both validate the resulting product the same way.
+				updatedProduct := model.Product{Available: true}
+				if r.Method == http.MethodPatch {
+					var status int
+					updatedProduct, status, err = patchProduct(patchBase, r)
+					if err != nil {
+						log.Printf("Error applying product patch: %v", err)
+						if status == http.StatusUnsupportedMediaType {
+							w.Header().Set("Accept-Patch", mergepatch.ContentType)
+						}
+						http.Error(w, err.Error(), status)
+						return
+					}
+				} else if err := json.NewDecoder(r.Body).Decode(&updatedProduct); err != nil {
 					log.Printf("Error decoding update product: %v", err)
 					http.Error(w, "Invalid request body", http.StatusBadRequest)
 					return
 				}
 
+				if errs := validate.Struct(updatedProduct); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
 				log.Printf("Updating product ID: %d", id)
 
-				if !productDB.UpdateProduct(id, updatedProduct) {
+				if err := checkSKU(productDB, restaurantID, updatedProduct.SKU, id); err != nil {
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
+
+				if err := checkCategories(categoryDB, restaurantID, updatedProduct.CategoryIDs); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := updatedProduct.PrepareOptionGroups(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := updatedProduct.PreparePrices(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := checkSchedule(updatedProduct.Schedule); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := updatedProduct.PrepareDietary(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if err := updatedProduct.PrepareTags(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				updatedProduct.ID = id
+				if err := checkBundle(productDB, restaurantID, &updatedProduct); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if draft {
+					updatedProduct.RestaurantID = restaurantID
+					stageDraft(w, r, menuDB, model.DraftChange{RestaurantID: restaurantID, ProductID: id, Action: model.DraftUpdate, Product: &updatedProduct, BaseVersion: baseVersion})
+					return
+				}
+
+				userID, ok := checkPriceUser(w, r, !oldPrice.Equal(updatedProduct.Price))
+				if !ok {
+					return
+				}
+
+				if !productDB.UpdateProduct(restaurantID, id, updatedProduct) {
 					http.Error(w, "Product not found", http.StatusNotFound)
 					return
 				}
 
+				recordPriceChange(priceDB, restaurantID, id, oldPrice, updatedProduct.Price, model.PriceSourceUpdate, userID)
+
+				if stored, exists := productDB.GetProductByID(restaurantID, id); exists {
+					updatedProduct = *stored
+				}
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+				w.Header().Set("ETag", productETag(updatedProduct))
 				w.WriteHeader(http.StatusOK)
 				json.NewEncoder(w).Encode(
 					map[string]interface{}{
@@ -108,13 +453,48 @@ func main() {
 				)
 
 			case http.MethodDelete:
-				if !productDB.DeleteProduct(id) {
+				draft, err := draftRequested(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				foundProduct, exists := productDB.GetProductByID(restaurantID, id)
+				if !exists {
 					http.Error(w, "Product not found", http.StatusNotFound)
 					return
 				}
+				if !checkIfMatch(w, r, *foundProduct) {
+					return
+				}
+
+				if draft {
+					if foundProduct.Archived() {
+						http.Error(w, storage.ErrProductArchived.Error(), http.StatusConflict)
+						return
+					}
+					stageDraft(w, r, menuDB, model.DraftChange{RestaurantID: restaurantID, ProductID: id, Action: model.DraftArchive, BaseVersion: foundProduct.Version})
+					return
+				}
 
+				// Products are archived rather than removed so that orders
+				// referencing them keep resolving. The image is kept for a
+				// later restore.
+				archivedProduct, err := productDB.ArchiveProduct(restaurantID, id, time.Now().UTC())
+				if err != nil {
+					http.Error(w, err.Error(), archiveErrorStatus(err))
+					return
+				}
+				publisher.PublishProduct(events.ProductDeleted, restaurantID, id)
+
+				w.Header().Set("ETag", productETag(*archivedProduct))
 				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(map[string]string{"message": "product deleted successfully"})
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message": "product archived successfully",
+						"product": archivedProduct,
+					},
+				)
 
 			default:
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -122,7 +502,148 @@ func main() {
 		},
 	)
 
-	if err := http.ListenAndServe(":8082", mux); err != nil {
+	registerInventoryRoutes(mux, transactor, productDB, stockDB, ingredientDB, publisher)
+	registerIngredientRoutes(mux, ingredientDB, productDB, publisher)
+	registerCategoryRoutes(mux, categoryDB, productDB, menuDB)
+	registerScheduleRoutes(mux, productDB, categoryDB)
+	registerBulkRoutes(mux, transactor, productDB, categoryDB, ingredientDB, priceDB, menuDB, publisher)
+	registerPriceRoutes(mux, productDB, priceDB)
+	registerArchiveRoutes(mux, productDB, publisher)
+	registerCurrencyRoutes(mux, rateDB)
+	registerTranslationRoutes(mux, productDB, categoryDB, publisher)
+	registerMenuRoutes(mux, transactor, productDB, categoryDB, ingredientDB, priceDB, menuDB, publisher)
+
+	go runPriceScheduler(lock, transactor, productDB, priceDB, publisher)
+
+	if fixture.AdminEnabled() {
+		// Seeding, export and restore cover every restaurant, so they are
+		// only available when enabled explicitly, and only to the admin
+		// tools, which call them with a service token.
+		mux.HandleFunc(
+			"/admin/export", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(backup.Catalog{
+					Products:         productDB.Products,
+					Categories:       categoryDB.Categories,
+					Ingredients:      ingredientDB.Ingredients,
+					PriceChanges:     priceDB.Changes,
+					MenuVersions:     menuDB.Versions,
+					ExchangeRates:    rateDB.Rates,
+					StockAdjustments: stockDB.Adjustments,
+					Sales:            stockDB.Sales,
+					MenuDrafts:       menuDB.Drafts,
+				})
+			},
+		)
+
+		mux.HandleFunc(
+			"/admin/restore", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				var catalog backup.Catalog
+				if err := json.NewDecoder(r.Body).Decode(&catalog); err != nil {
+					log.Printf("Error decoding products restore: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				// The references between the catalog's records are checked
+				// like those of a whole archive.
+				if err := (&backup.Archive{Version: backup.FormatVersion, Catalog: catalog}).Validate(); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				if productDB.GetProductCount() > 0 || categoryDB.GetCategoryCount() > 0 || ingredientDB.GetIngredientCount() > 0 ||
+					len(priceDB.Changes) > 0 || len(menuDB.Versions) > 0 || len(menuDB.Drafts) > 0 ||
+					len(rateDB.Rates) > 0 || len(stockDB.Adjustments) > 0 || len(stockDB.Sales) > 0 {
+					http.Error(w, "restore requires an empty store", http.StatusConflict)
+					return
+				}
+
+				for _, item := range catalog.Categories {
+					categoryDB.AddCategory(item)
+				}
+				for _, item := range catalog.Ingredients {
+					ingredientDB.AddIngredient(item)
+				}
+				for _, item := range catalog.Products {
+					productDB.AddProduct(item)
+				}
+				// The histories, rates, sales and drafts keep their IDs and
+				// numbers, so they are restored as they were exported.
+				priceDB.Changes = append(priceDB.Changes, catalog.PriceChanges...)
+				menuDB.Versions = append(menuDB.Versions, catalog.MenuVersions...)
+				menuDB.Drafts = append(menuDB.Drafts, catalog.MenuDrafts...)
+				rateDB.Rates = append(rateDB.Rates, catalog.ExchangeRates...)
+				stockDB.Adjustments = append(stockDB.Adjustments, catalog.StockAdjustments...)
+				stockDB.Sales = append(stockDB.Sales, catalog.Sales...)
+
+				log.Printf(
+					"Restored %d products, %d categories, %d ingredients, %d price changes, %d menu versions, %d menu drafts, %d exchange rates, %d stock adjustments and %d sales",
+					len(catalog.Products), len(catalog.Categories), len(catalog.Ingredients), len(catalog.PriceChanges), len(catalog.MenuVersions),
+					len(catalog.MenuDrafts), len(catalog.ExchangeRates), len(catalog.StockAdjustments), len(catalog.Sales),
+				)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{"message": "products restored", "count": catalog.Len()})
+			},
+		)
+
+		mux.HandleFunc(
+			"/admin/seed", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+					return
+				}
+
+				if err := tenant.RequireService(r); err != nil {
+					http.Error(w, err.Error(), tenant.StatusCode(err))
+					return
+				}
+
+				if err := seedProducts(); err != nil {
+					log.Printf("Error reseeding products: %v", err)
+					http.Error(w, "error loading fixtures", http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "products reseeded from " + fixture.Environment() + " fixtures"})
+			},
+		)
+	}
+
+	// Every route above runs under the store lock. Routes that call other
+	// services or do slow work are registered on the server directly and
+	// lock for themselves.
+	server := http.NewServeMux()
+	server.Handle("/", guard(lock, mux))
+	registerRecommendationRoutes(server, lock, productDB)
+	registerImageRoutes(server, lock, productDB, blobs, publisher)
+
+	if err := http.ListenAndServe(":8082", server); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"restaurant/events"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// registerMenuRoutes serves the publish workflow. Product changes made with
+// ?draft=true are staged in menuDB instead of going live; publishing applies
+// all of them at once and records the resulting menu as a new version, which
+// a later rollback can publish again.
+func registerMenuRoutes(
+	mux *http.ServeMux,
+	transactor *storage.MemoryTransactor,
+	productDB *storage.ProductStorage,
+	categoryDB *storage.CategoryStorage,
+	ingredientDB *storage.IngredientStorage,
+	priceDB *storage.PriceStorage,
+	menuDB *storage.MenuStorage,
+	publisher *events.Publisher,
+) {
+	mux.HandleFunc(
+		"/menu/draft", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(menuDB.GetDrafts(restaurantID))
+
+			case http.MethodDelete:
+				count := menuDB.ClearDrafts(restaurantID)
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message": "draft changes discarded",
+						"count":   count,
+					},
+				)
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu/draft/{id}", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				draft, exists := menuDB.GetDraft(restaurantID, id)
+				if !exists {
+					http.Error(w, "Draft not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(draft)
+
+			case http.MethodDelete:
+				if !menuDB.DiscardDraft(restaurantID, id) {
+					http.Error(w, "Draft not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "draft change discarded"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu/preview", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			language := requestLanguage(w, r)
+			w.Header().Set("Content-Language", language)
+
+			categories := categoryDB.GetCategoriesByRestaurant(restaurantID)
+			for i := range categories {
+				categories[i] = categories[i].Localized(language)
+			}
+			products := model.ApplyDrafts(productDB.GetMenuSnapshot(restaurantID), menuDB.GetDrafts(restaurantID))
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(buildMenu(categories, localizeProducts(products, language)))
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu/publish", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			var request model.PublishRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+				log.Printf("Error decoding publish request: %v", err)
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			if errs := validate.Struct(request); errs != nil {
+				validate.WriteErrors(w, errs)
+				return
+			}
+
+			drafts := menuDB.GetDrafts(restaurantID)
+			if len(drafts) == 0 {
+				http.Error(w, "no draft changes to publish", http.StatusConflict)
+				return
+			}
+			if err := checkDrafts(productDB, categoryDB, restaurantID, drafts); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			log.Printf("Publishing %d draft changes (restaurant %d)", len(drafts), restaurantID)
+
+			changesPrice := false
+			for _, draft := range drafts {
+				if draft.Action != model.DraftUpdate {
+					continue
+				}
+				if existing, exists := productDB.GetProductByID(restaurantID, draft.ProductID); exists && !existing.Price.Equal(draft.Product.Price) {
+					changesPrice = true
+				}
+			}
+			userID, ok := checkPriceUser(w, r, changesPrice)
+			if !ok {
+				return
+			}
+
+			now := time.Now().UTC()
+			var published model.MenuVersion
+			err = storage.WithTransaction(transactor, func(tx storage.Tx) error {
+				// The menu as it was before the first publish becomes
+				// version 1, so the first publish can be rolled back too.
+				if menuDB.LiveVersion(restaurantID) == 0 {
+					menuDB.AddVersion(model.MenuVersion{
+						RestaurantID: restaurantID,
+						Note:         "menu before the first publish",
+						PublishedAt:  now,
+						Products:     productDB.GetMenuSnapshot(restaurantID),
+					})
+				}
+
+				for _, draft := range drafts {
+					switch draft.Action {
+					case model.DraftCreate:
+						product := *draft.Product
+						product.IngredientShortage = len(product.Recipe) > 0 && !ingredientDB.CanMake(restaurantID, product.Recipe, 1)
+						product.RefreshSoldOut()
+						productDB.AddProduct(product)
+
+					case model.DraftUpdate:
+						if existing, exists := productDB.GetProductByID(restaurantID, draft.ProductID); exists {
+							recordPriceChange(priceDB, restaurantID, draft.ProductID, existing.Price, draft.Product.Price, model.PriceSourcePublish, userID)
+						}
+						if !productDB.UpdateProduct(restaurantID, draft.ProductID, *draft.Product) {
+							return storage.ErrProductNotFound
+						}
+
+					case model.DraftArchive:
+						if _, err := productDB.ArchiveProduct(restaurantID, draft.ProductID, now); err != nil {
+							return err
+						}
+					}
+				}
+
+				menuDB.ClearDrafts(restaurantID)
+				published = menuDB.AddVersion(model.MenuVersion{
+					RestaurantID: restaurantID,
+					Note:         request.Note,
+					Changes:      len(drafts),
+					PublishedBy:  userID,
+					PublishedAt:  now,
+					Products:     productDB.GetMenuSnapshot(restaurantID),
+				})
+				return nil
+			})
+			if err != nil {
+				log.Printf("Error publishing menu: %v", err)
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			refreshIngredientShortages(productDB, ingredientDB, publisher, restaurantID)
+			for _, draft := range drafts {
+				switch draft.Action {
+				case model.DraftUpdate:
+					publisher.PublishProduct(events.ProductUpdated, restaurantID, draft.ProductID)
+				case model.DraftArchive:
+					publisher.PublishProduct(events.ProductDeleted, restaurantID, draft.ProductID)
+				}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message": "menu published successfully",
+					"version": published.Summary(),
+				},
+			)
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu/versions", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			versions := menuDB.GetVersions(restaurantID)
+			for i := range versions {
+				versions[i] = versions[i].Summary()
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(versions)
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu/versions/{version}", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			number, err := strconv.Atoi(r.PathValue("version"))
+			if err != nil {
+				http.Error(w, "Invalid menu version", http.StatusBadRequest)
+				return
+			}
+
+			version, exists := menuDB.GetVersion(restaurantID, number)
+			if !exists {
+				http.Error(w, "Menu version not found", http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(version)
+		},
+	)
+
+	mux.HandleFunc(
+		"/menu/versions/{version}/rollback", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			number, err := strconv.Atoi(r.PathValue("version"))
+			if err != nil {
+				http.Error(w, "Invalid menu version", http.StatusBadRequest)
+				return
+			}
+
+			version, exists := menuDB.GetVersion(restaurantID, number)
+			if !exists {
+				http.Error(w, "Menu version not found", http.StatusNotFound)
+				return
+			}
+
+			log.Printf("Rolling back menu to version %d (restaurant %d)", number, restaurantID)
+
+			// Categories deleted since the version was published are left
+			// out rather than brought back.
+			snapshot := make([]model.Product, 0, len(version.Products))
+			for _, product := range version.Products {
+				categoryIDs := make([]int, 0, len(product.CategoryIDs))
+				for _, id := range product.CategoryIDs {
+					if _, exists := categoryDB.GetCategoryByID(restaurantID, id); exists {
+						categoryIDs = append(categoryIDs, id)
+					}
+				}
+				product.CategoryIDs = categoryIDs
+				snapshot = append(snapshot, product)
+			}
+
+			current := make(map[int]model.Product)
+			for _, product := range productDB.GetMenuSnapshot(restaurantID) {
+				current[product.ID] = product
+			}
+			changesPrice := false
+			for _, product := range snapshot {
+				if old, exists := current[product.ID]; exists && !old.Price.Equal(product.Price) {
+					changesPrice = true
+				}
+			}
+			userID, ok := checkPriceUser(w, r, changesPrice)
+			if !ok {
+				return
+			}
+
+			now := time.Now().UTC()
+			var changed []int
+			var published model.MenuVersion
+			storage.WithTransaction(transactor, func(tx storage.Tx) error {
+				previous := make(map[int]model.Product)
+				for _, product := range productDB.GetMenuSnapshot(restaurantID) {
+					previous[product.ID] = product
+				}
+
+				changed = productDB.RestoreMenu(restaurantID, snapshot, now)
+				for _, product := range snapshot {
+					if old, exists := previous[product.ID]; exists {
+						recordPriceChange(priceDB, restaurantID, product.ID, old.Price, product.Price, model.PriceSourceRollback, userID)
+					}
+				}
+
+				published = menuDB.AddVersion(model.MenuVersion{
+					RestaurantID:   restaurantID,
+					Note:           fmt.Sprintf("rollback to version %d", number),
+					Changes:        len(changed),
+					RolledBackFrom: number,
+					PublishedBy:    userID,
+					PublishedAt:    now,
+					Products:       productDB.GetMenuSnapshot(restaurantID),
+				})
+				return nil
+			})
+
+			refreshIngredientShortages(productDB, ingredientDB, publisher, restaurantID)
+			for _, id := range changed {
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message": "menu rolled back successfully",
+					"version": published.Summary(),
+				},
+			)
+		},
+	)
+}
+
+// draftRequested reports whether a product change should be staged for the
+// next publish (?draft=true) instead of going live.
+func draftRequested(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("draft")
+	if value == "" {
+		return false, nil
+	}
+	draft, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid draft")
+	}
+	return draft, nil
+}
+
+// stageDraft stages a product change and answers 202.
+func stageDraft(w http.ResponseWriter, r *http.Request, menuDB *storage.MenuStorage, draft model.DraftChange) {
+	draft.StagedBy = tenant.UserID(r)
+	draft.StagedAt = time.Now().UTC()
+	menuDB.StageDraft(draft)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(
+		map[string]interface{}{
+			"message": "product change staged for the next publish",
+			"draft":   draft,
+		},
+	)
+}
+
+// nextProductID returns an ID for a new product that is neither live nor
+// reserved by a product staged for creation.
+func nextProductID(productDB *storage.ProductStorage, menuDB *storage.MenuStorage) int {
+	return max(productDB.NextProductID(), menuDB.NextProductID())
+}
+
+// checkDrafts makes sure the staged changes can still be applied: every
+// product they change is unchanged since it was staged, and the categories
+// they assign still exist.
+func checkDrafts(productDB *storage.ProductStorage, categoryDB *storage.CategoryStorage, restaurantID int, drafts []model.DraftChange) error {
+	problems := make([]string, 0)
+	for _, draft := range drafts {
+		if draft.Action != model.DraftCreate {
+			live, exists := productDB.GetProductByID(restaurantID, draft.ProductID)
+			switch {
+			case !exists:
+				problems = append(problems, fmt.Sprintf("product %d no longer exists", draft.ProductID))
+			case live.Version != draft.BaseVersion:
+				problems = append(problems, fmt.Sprintf("product %d was modified after the change was staged", draft.ProductID))
+			case live.Archived():
+				problems = append(problems, fmt.Sprintf("product %d is archived", draft.ProductID))
+			}
+		}
+		if draft.Product != nil {
+			if err := checkCategories(categoryDB, restaurantID, draft.Product.CategoryIDs); err != nil {
+				problems = append(problems, fmt.Sprintf("product %d: %v", draft.ProductID, err))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cannot publish: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"restaurant/mergepatch"
+	"restaurant/model"
+)
+
+// patchProduct applies the JSON merge patch in the request body to the
+// current product. Errors come with the status code to answer.
+func patchProduct(current model.Product, r *http.Request) (model.Product, int, error) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergepatch.ContentType && mediaType != "application/json") {
+			return current, http.StatusUnsupportedMediaType, fmt.Errorf("PATCH expects Content-Type %s", mergepatch.ContentType)
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		return current, http.StatusBadRequest, fmt.Errorf("Invalid request body")
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return current, http.StatusInternalServerError, err
+	}
+
+	merged, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return current, http.StatusBadRequest, err
+	}
+
+	// Unknown members are most likely typos that would otherwise be dropped
+	// silently.
+	var patched model.Product
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return current, http.StatusBadRequest, fmt.Errorf("invalid merge patch: %v", err)
+	}
+	return patched, 0, nil
+}
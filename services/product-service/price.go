@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"restaurant/events"
+	"restaurant/model"
+	"restaurant/money"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func registerPriceRoutes(
+	mux *http.ServeMux,
+	productDB *storage.ProductStorage,
+	priceDB *storage.PriceStorage,
+) {
+	mux.HandleFunc(
+		"/product/{id}/prices", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			product, exists := productDB.GetProductByID(restaurantID, id)
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(priceDB.GetChangesByProduct(restaurantID, id))
+
+			case http.MethodPost:
+				// The change is applied later by the scheduler, so the
+				// history needs to know who asked for it.
+				userID := tenant.UserID(r)
+				if userID == 0 {
+					http.Error(w, "scheduling a price change requires a signed-in user", http.StatusUnauthorized)
+					return
+				}
+
+				var request model.PriceChangeRequest
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					log.Printf("Error decoding price change: %v", err)
+					http.Error(w, "Invalid request body", http.StatusBadRequest)
+					return
+				}
+
+				if errs := validate.Struct(request); errs != nil {
+					validate.WriteErrors(w, errs)
+					return
+				}
+
+				// Immediate changes go through PUT /product/{id}.
+				now := time.Now()
+				if !request.EffectiveAt.After(now) {
+					http.Error(w, "effective_at must be in the future", http.StatusBadRequest)
+					return
+				}
+				if _, err := request.Price.Cmp(product.Price); err != nil {
+					http.Error(w, fmt.Sprintf("price must be in %s", product.Price.Currency()), http.StatusBadRequest)
+					return
+				}
+
+				log.Printf("Scheduling price %s for product ID %d at %s", request.Price, id, request.EffectiveAt)
+
+				change := priceDB.AddChange(model.PriceChange{
+					RestaurantID: restaurantID,
+					ProductID:    id,
+					NewPrice:     request.Price,
+					Status:       model.PriceChangePending,
+					Source:       model.PriceSourceScheduled,
+					Note:         request.Note,
+					ChangedBy:    userID,
+					EffectiveAt:  request.EffectiveAt.UTC(),
+					CreatedAt:    now.UTC(),
+				})
+
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":      "price change scheduled",
+						"price_change": change,
+					},
+				)
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/{id}/prices/{changeID}", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			changeID, err := strconv.Atoi(r.PathValue("changeID"))
+			if err != nil {
+				http.Error(w, "Invalid price change ID", http.StatusBadRequest)
+				return
+			}
+
+			if change, exists := priceDB.GetChangeByID(restaurantID, changeID); !exists || change.ProductID != id {
+				http.Error(w, storage.ErrPriceChangeNotFound.Error(), http.StatusNotFound)
+				return
+			}
+
+			change, err := priceDB.Cancel(restaurantID, changeID)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, storage.ErrPriceChangeNotPending) {
+					status = http.StatusConflict
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"message":      "price change cancelled",
+					"price_change": change,
+				},
+			)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/{id}/price", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			at := time.Now()
+			if value := r.URL.Query().Get("at"); value != "" {
+				at, err = time.Parse(time.RFC3339, value)
+				if err != nil {
+					http.Error(w, "invalid at, expected RFC 3339 time", http.StatusBadRequest)
+					return
+				}
+			}
+
+			product, exists := productDB.GetProductByID(restaurantID, id)
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"product_id": id,
+					"at":         at,
+					"price":      priceDB.PriceAt(restaurantID, id, at, product.Price),
+				},
+			)
+		},
+	)
+}
+
+// checkPriceUser returns the signed-in user of a write. The price history
+// names who changed each price, so a write that changes prices is refused
+// without one, as are service calls, which carry no user.
+func checkPriceUser(w http.ResponseWriter, r *http.Request, changesPrice bool) (int, bool) {
+	userID := tenant.UserID(r)
+	if changesPrice && userID == 0 {
+		http.Error(w, "changing a price requires a signed-in user", http.StatusUnauthorized)
+		return 0, false
+	}
+	return userID, true
+}
+
+// recordPriceChange adds an applied change to the price history when the
+// price actually changed.
+func recordPriceChange(priceDB *storage.PriceStorage, restaurantID, productID int, oldPrice, newPrice money.Money, source string, userID int) {
+	if oldPrice.Equal(newPrice) {
+		return
+	}
+
+	now := time.Now().UTC()
+	priceDB.AddChange(model.PriceChange{
+		RestaurantID: restaurantID,
+		ProductID:    productID,
+		OldPrice:     oldPrice,
+		NewPrice:     newPrice,
+		Status:       model.PriceChangeApplied,
+		Source:       source,
+		ChangedBy:    userID,
+		EffectiveAt:  now,
+		CreatedAt:    now,
+	})
+}
+
+// applyDuePrices applies every scheduled change due at now. Changes for
+// products that no longer exist are cancelled. It holds the store lock like
+// any handler that writes.
+func applyDuePrices(lock *sync.RWMutex, transactor *storage.MemoryTransactor, productDB *storage.ProductStorage, priceDB *storage.PriceStorage, publisher *events.Publisher, now time.Time) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, change := range priceDB.Due(now) {
+		err := storage.WithTransaction(transactor, func(tx storage.Tx) error {
+			oldPrice, err := productDB.SetPrice(change.RestaurantID, change.ProductID, change.NewPrice)
+			if err != nil {
+				return err
+			}
+			_, err = priceDB.MarkApplied(change.RestaurantID, change.ID, oldPrice, now.UTC())
+			return err
+		})
+
+		if errors.Is(err, storage.ErrProductNotFound) {
+			log.Printf("Cancelling price change %d: product %d no longer exists", change.ID, change.ProductID)
+			priceDB.Cancel(change.RestaurantID, change.ID)
+			continue
+		}
+		if err != nil {
+			log.Printf("Error applying price change %d: %v", change.ID, err)
+			continue
+		}
+
+		publisher.PublishProduct(events.ProductUpdated, change.RestaurantID, change.ProductID)
+	}
+}
+
+// runPriceScheduler applies scheduled price changes every
+// PRICE_SCHEDULER_INTERVAL (default 30s).
+func runPriceScheduler(lock *sync.RWMutex, transactor *storage.MemoryTransactor, productDB *storage.ProductStorage, priceDB *storage.PriceStorage, publisher *events.Publisher) {
+	interval := 30 * time.Second
+	if value := os.Getenv("PRICE_SCHEDULER_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("Invalid PRICE_SCHEDULER_INTERVAL: %q", value)
+		}
+		interval = parsed
+	}
+
+	log.Printf("Price scheduler: checking every %s", interval)
+	for now := range time.Tick(interval) {
+		applyDuePrices(lock, transactor, productDB, priceDB, publisher, now)
+	}
+}
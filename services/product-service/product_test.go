@@ -2,21 +2,99 @@ package main
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"restaurant/model"
+	"restaurant/money"
+	"restaurant/token"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var baseURL = "http://localhost:8082"
 
+// authorizingTransport signs test requests that carry no token of their own
+// with a user token for the restaurant they name, so tests keep addressing
+// restaurants through the X-Restaurant-ID header.
+type authorizingTransport struct {
+	next http.RoundTripper
+}
+
+func (t authorizingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Header.Get("Authorization") == "" {
+		restaurantID := 1
+		if value := request.Header.Get("X-Restaurant-ID"); value != "" {
+			if id, err := strconv.Atoi(value); err == nil {
+				restaurantID = id
+			}
+		}
+		userID := 1000 + restaurantID
+		switch restaurantID {
+		case 1:
+			userID = 1
+		case 2:
+			userID = 3
+		}
+
+		issued, err := token.Issue(userID, restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		request = request.Clone(request.Context())
+		request.Header.Set("Authorization", "Bearer "+issued)
+	}
+	return t.next.RoundTrip(request)
+}
+
+// sendAsService posts body for restaurant 2 signed with a service token, the
+// way the order service reports and cancels sales.
+func sendAsService(t *testing.T, path string, body interface{}) int {
+	t.Helper()
+
+	serviceToken, err := token.IssueService()
+	if err != nil {
+		t.Fatalf("Error issuing service token: %v", err)
+	}
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Error marshaling request body: %v", err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, baseURL+path, bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+	request.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func init() {
+	http.DefaultTransport = authorizingTransport{next: http.DefaultTransport}
+}
+
 func TestCreateProductSuccess(t *testing.T) {
 	body := model.Product{
 		Name:        "Burger",
 		Description: "Delicious beef burger",
-		Price:       15.99,
+		Price:       money.MustParse("15.99", "USD"),
 	}
 
 	bodyJSON, err := json.Marshal(body)
@@ -144,7 +222,7 @@ func TestUpdateProductSuccess(t *testing.T) {
 	body := model.Product{
 		Name:        "Updated Burger",
 		Description: "Updated delicious beef burger",
-		Price:       17.99,
+		Price:       money.MustParse("17.99", "USD"),
 	}
 
 	bodyJSON, err := json.Marshal(body)
@@ -185,7 +263,7 @@ func TestUpdateProductNotFound(t *testing.T) {
 	body := model.Product{
 		Name:        "Non-existent Product",
 		Description: "This product doesn't exist",
-		Price:       99.99,
+		Price:       money.MustParse("99.99", "USD"),
 	}
 
 	bodyJSON, err := json.Marshal(body)
@@ -343,3 +421,2295 @@ func TestCreateProductInvalidData(t *testing.T) {
 	t.Logf("Response status code: %d", response.StatusCode)
 	t.Logf("Response body: %s", string(bodyBytes))
 }
+
+func TestGetProductFromOtherRestaurantNotFound(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product/1", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestProductRequiresToken(t *testing.T) {
+	anonymous := &http.Client{Transport: http.DefaultTransport.(authorizingTransport).next}
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "no header"},
+		{name: "header only", header: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product", baseURL), nil)
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+			if tt.header != "" {
+				request.Header.Set("X-Restaurant-ID", tt.header)
+			}
+
+			response, err := anonymous.Do(request)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Expected status code %d, but got %d", http.StatusUnauthorized, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestGetAllProductsScopedToRestaurant(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNoContent {
+		return
+	}
+
+	var products []model.Product
+	if err := json.NewDecoder(response.Body).Decode(&products); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	for _, product := range products {
+		if product.RestaurantID != 2 {
+			t.Errorf("Expected only products of restaurant 2, but got %+v", product)
+		}
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Products: %+v", products)
+}
+
+func TestCreateCategorySuccess(t *testing.T) {
+	body := model.Category{
+		Name:        "Sides",
+		Description: "Fries and more",
+		Position:    5,
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/category", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateProductUnknownCategory(t *testing.T) {
+	body := model.Product{
+		Name:        "Mystery Dish",
+		Description: "Belongs to no known category",
+		Price:       money.MustParse("9.99", "USD"),
+		CategoryIDs: []int{999},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestGetMenuSuccess(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/menu", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var menu model.Menu
+	if err := json.NewDecoder(response.Body).Decode(&menu); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	for i := 1; i < len(menu.Sections); i++ {
+		if menu.Sections[i-1].Position > menu.Sections[i].Position {
+			t.Errorf("Expected sections ordered by position, but got %+v", menu.Sections)
+		}
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Menu: %+v", menu)
+}
+
+func TestSearchProductsWithPagination(t *testing.T) {
+	request, err := http.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/product?max_price=20&sort=-price&offset=0&limit=1", baseURL),
+		nil,
+	)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	if response.Header.Get("X-Total-Count") == "" {
+		t.Errorf("Expected X-Total-Count header")
+	}
+	if response.Header.Get("Link") == "" {
+		t.Errorf("Expected Link header")
+	}
+
+	var products []model.Product
+	if err := json.NewDecoder(response.Body).Decode(&products); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if len(products) > 1 {
+		t.Errorf("Expected at most 1 product, but got %d", len(products))
+	}
+	for _, product := range products {
+		if product.Price.Minor() > 2000 {
+			t.Errorf("Expected price at most 20, but got %s", product.Price)
+		}
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Total: %s, Link: %s", response.Header.Get("X-Total-Count"), response.Header.Get("Link"))
+}
+
+func TestSearchProductsPaginatesOnlyOnRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedLinks bool
+	}{
+		{name: "no pagination", query: "sort=id"},
+		{name: "offset past any int", query: "offset=9223372036854775807", expectedLinks: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := http.Get(fmt.Sprintf("%s/product?%s", baseURL, tt.query))
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+			}
+			if hasLinks := response.Header.Get("Link") != ""; hasLinks != tt.expectedLinks {
+				t.Errorf("Expected Link header %v, but got %q", tt.expectedLinks, response.Header.Get("Link"))
+			}
+			if strings.Contains(response.Header.Get("Link"), `rel="next"`) {
+				t.Errorf("Expected no next page, but got %q", response.Header.Get("Link"))
+			}
+		})
+	}
+}
+
+func TestSearchProductsInvalidSort(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product?sort=calories", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestAdjustStockDeliverySuccess(t *testing.T) {
+	body := model.StockAdjustmentRequest{
+		Change: 24,
+		Reason: model.StockReasonDelivery,
+		Note:   "weekly tea delivery",
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/5/stock", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestAdjustStockInvalidReason(t *testing.T) {
+	body := model.StockAdjustmentRequest{
+		Change: -3,
+		Reason: model.StockReasonDelivery,
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/5/stock", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestGetStockHistorySuccess(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product/5/stock/history", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var history []model.StockAdjustment
+	if err := json.NewDecoder(response.Body).Decode(&history); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("History: %+v", history)
+}
+
+func TestGetIngredientsSuccess(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/ingredient", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var ingredients []model.Ingredient
+	if err := json.NewDecoder(response.Body).Decode(&ingredients); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	for _, ingredient := range ingredients {
+		if ingredient.RestaurantID != 2 {
+			t.Errorf("Expected only ingredients of restaurant 2, but got %+v", ingredient)
+		}
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Ingredients: %+v", ingredients)
+}
+
+func TestSetRecipeUnknownIngredient(t *testing.T) {
+	body := []model.RecipeItem{
+		{IngredientID: 1, Quantity: 1},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/product/4/recipe", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestDeleteIngredientInUse(t *testing.T) {
+	request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/ingredient/7", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestIngredientShortageMarksProductSoldOut(t *testing.T) {
+	client := &http.Client{}
+
+	send := func(method, path string, body interface{}, v interface{}) int {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, baseURL+path, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer response.Body.Close()
+
+		if v != nil {
+			if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+				t.Fatalf("Error decoding response body: %v", err)
+			}
+		}
+		return response.StatusCode
+	}
+
+	var created struct {
+		Ingredient model.Ingredient `json:"ingredient"`
+	}
+	status := send(http.MethodPost, "/ingredient", model.Ingredient{Name: "Sambal", Unit: "g", Stock: 50}, &created)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, status)
+	}
+
+	var product struct {
+		Product model.Product `json:"product"`
+	}
+	status = send(http.MethodPost, "/product", model.Product{
+		Name:      "Sambal Goreng",
+		Price:     money.MustParse("3.00", "USD"),
+		Available: true,
+		Recipe:    []model.RecipeItem{{IngredientID: created.Ingredient.ID, Quantity: 50}},
+	}, &product)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, status)
+	}
+
+	path := fmt.Sprintf("/product/%d", product.Product.ID)
+	if status := sendAsService(t, path+"/sales", map[string]int{"quantity": 1}); status != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, status)
+	}
+
+	var soldOut model.Product
+	send(http.MethodGet, path, nil, &soldOut)
+	if !soldOut.SoldOut || !soldOut.IngredientShortage {
+		t.Errorf("Expected product to be sold out after its ingredient ran out, but got %+v", soldOut)
+	}
+
+	if status := sendAsService(t, path+"/sales", map[string]int{"quantity": 1}); status != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, status)
+	}
+
+	t.Logf("Sold out product: %+v", soldOut)
+}
+
+func TestCreateBundleUnknownComponent(t *testing.T) {
+	body := model.Product{
+		Name:      "Mystery Combo",
+		Price:     money.MustParse("10.00", "USD"),
+		Available: true,
+		Bundle: &model.Bundle{
+			Pricing: model.BundlePricingFixed,
+			Slots: []model.BundleSlot{
+				{Name: "Main", ProductIDs: []int{999}},
+			},
+		},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestBundleComponentCannotBecomeBundle(t *testing.T) {
+	client := &http.Client{}
+
+	send := func(method, path string, body interface{}, v interface{}) int {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, baseURL+path, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer response.Body.Close()
+
+		if v != nil {
+			if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+				t.Fatalf("Error decoding response body: %v", err)
+			}
+		}
+		return response.StatusCode
+	}
+
+	create := func(product model.Product) model.Product {
+		var created struct {
+			Product model.Product `json:"product"`
+		}
+		if status := send(http.MethodPost, "/product", product, &created); status != http.StatusCreated {
+			t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, status)
+		}
+		return created.Product
+	}
+
+	rice := create(model.Product{Name: "Nasi Lemak", Price: money.MustParse("4.00", "USD"), Available: true})
+	drink := create(model.Product{Name: "Milo Ais", Price: money.MustParse("2.00", "USD"), Available: true})
+	create(model.Product{
+		Name:      "Breakfast Set",
+		Price:     money.MustParse("5.50", "USD"),
+		Available: true,
+		Bundle:    &model.Bundle{Slots: []model.BundleSlot{{Name: "Main", ProductIDs: []int{rice.ID}}}},
+	})
+
+	rice.Bundle = &model.Bundle{Slots: []model.BundleSlot{{Name: "Drink", ProductIDs: []int{drink.ID}}}}
+	if status := send(http.MethodPut, fmt.Sprintf("/product/%d", rice.ID), rice, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected turning a bundle component into a bundle to return %d, but got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestGetProductAvailabilityOutsideWindow(t *testing.T) {
+	request, err := http.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/product/11/availability?at=2026-01-05T20:00:00Z", baseURL),
+		nil,
+	)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var availability struct {
+		Scheduled bool `json:"scheduled"`
+		Available bool `json:"available"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&availability); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if availability.Scheduled || availability.Available {
+		t.Errorf("Expected breakfast to be unavailable in the evening, but got %+v", availability)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Availability: %+v", availability)
+}
+
+func TestCreateProductInvalidSchedule(t *testing.T) {
+	body := model.Product{
+		Name:      "Midnight Snack",
+		Price:     money.MustParse("4.00", "USD"),
+		Available: true,
+		Schedule: &model.Schedule{
+			Timezone: "Nowhere/Special",
+			Windows:  []model.TimeWindow{{Start: "23:00", End: "01:00"}},
+		},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestSearchProductsExcludingAllergens(t *testing.T) {
+	request, err := http.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf("%s/product?exclude_allergens=gluten,dairy&dietary=vegan", baseURL),
+		nil,
+	)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var products []model.Product
+	if err := json.NewDecoder(response.Body).Decode(&products); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	for _, product := range products {
+		if !product.AllergensDeclared() || product.ContainsAllergen("gluten") || product.ContainsAllergen("dairy") || !product.HasDietaryLabel("vegan") {
+			t.Errorf("Expected only vegan products without gluten or dairy, but got %+v", product)
+		}
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Products: %d", len(products))
+}
+
+func TestSearchProductsExcludingAllergensSkipsUndeclared(t *testing.T) {
+	client := &http.Client{}
+	create := func(body string) model.Product {
+		t.Helper()
+
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer response.Body.Close()
+
+		var created struct {
+			Product model.Product `json:"product"`
+		}
+		json.NewDecoder(response.Body).Decode(&created)
+		if response.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+		}
+		return created.Product
+	}
+
+	undeclared := create(`{"name": "Kerupuk", "price": {"amount": "1.00", "currency": "USD"}, "available": true}`)
+	declared := create(`{"name": "Air Mineral", "price": {"amount": "1.00", "currency": "USD"}, "available": true, "allergens": []}`)
+	if undeclared.AllergensDeclared() || !declared.AllergensDeclared() {
+		t.Fatalf("Expected allergens null and [], but got %v and %v", undeclared.Allergens, declared.Allergens)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product?exclude_allergens=nuts", baseURL), nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	var products []model.Product
+	if err := json.NewDecoder(response.Body).Decode(&products); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+
+	found := make(map[int]bool, len(products))
+	for _, product := range products {
+		found[product.ID] = true
+	}
+	if found[undeclared.ID] || !found[declared.ID] {
+		t.Errorf("Expected only the product declaring no allergens, but got %+v", products)
+	}
+}
+
+func TestSearchProductsUnknownAllergen(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product?exclude_allergens=chocolate", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateProductConflictingDietaryLabel(t *testing.T) {
+	body := model.Product{
+		Name:          "Cheese Toastie",
+		Price:         money.MustParse("5.50", "USD"),
+		Available:     true,
+		Allergens:     []string{"gluten", "dairy"},
+		DietaryLabels: []string{"vegan"},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestUploadProductImageSuccess(t *testing.T) {
+	var imageBytes bytes.Buffer
+	if err := png.Encode(&imageBytes, image.NewNRGBA(image.Rect(0, 0, 400, 300))); err != nil {
+		t.Fatalf("Error encoding image: %v", err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "nasi-goreng.png")
+	if err != nil {
+		t.Fatalf("Error creating form file: %v", err)
+	}
+	part.Write(imageBytes.Bytes())
+	form.Close()
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/4/image", baseURL), &body)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	var result struct {
+		Image model.ProductImage `json:"image"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	thumbnailResponse, err := client.Get(baseURL + result.Image.Thumbnails["small"])
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer thumbnailResponse.Body.Close()
+
+	if thumbnailResponse.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, thumbnailResponse.StatusCode)
+	}
+
+	thumbnail, err := png.Decode(thumbnailResponse.Body)
+	if err != nil {
+		t.Fatalf("Error decoding thumbnail: %v", err)
+	}
+	if bounds := thumbnail.Bounds(); bounds.Dx() != 150 || bounds.Dy() != 112 {
+		t.Errorf("Expected a 150x112 thumbnail, but got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Image: %+v", result.Image)
+}
+
+func TestUploadProductImageTooManyPixels(t *testing.T) {
+	// A 1x1 PNG whose header claims 5000x4000 pixels: within the side
+	// limit, but over the pixel limit. Only the header is read before the
+	// upload is refused.
+	var imageBytes bytes.Buffer
+	if err := png.Encode(&imageBytes, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("Error encoding image: %v", err)
+	}
+	data := imageBytes.Bytes()
+	binary.BigEndian.PutUint32(data[16:20], 5000)
+	binary.BigEndian.PutUint32(data[20:24], 4000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "poster.png")
+	if err != nil {
+		t.Fatalf("Error creating form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/4/image", baseURL), &body)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusBadRequest || !strings.Contains(string(bodyBytes), "megapixels") {
+		t.Errorf("Expected status code %d for too many pixels, but got %d: %s", http.StatusBadRequest, response.StatusCode, bodyBytes)
+	}
+}
+
+func TestUploadProductImageUnsupportedType(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "menu.txt")
+	if err != nil {
+		t.Fatalf("Error creating form file: %v", err)
+	}
+	part.Write([]byte("not an image"))
+	form.Close()
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/4/image", baseURL), &body)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnsupportedMediaType, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestImportProductsDryRun(t *testing.T) {
+	csvBody := "sku,name,price,category_ids,dietary_labels\n" +
+		"NSG-001,Nasi Goreng Spesial,6.50,,halal\n" +
+		"MIE-001,Mie Goreng,5.00,,halal\n"
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/import?dry_run=true", baseURL), strings.NewReader(csvBody))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("Content-Type", "text/csv")
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var report struct {
+		DryRun  bool `json:"dry_run"`
+		Created int  `json:"created"`
+		Updated int  `json:"updated"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if !report.DryRun || report.Created != 1 || report.Updated != 1 {
+		t.Errorf("Expected a dry run creating 1 and updating 1 product, but got %+v", report)
+	}
+
+	if sku := "MIE-001"; productWithSKU(t, "2", sku) {
+		t.Errorf("Expected dry run not to create product %s", sku)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Report: %+v", report)
+}
+
+func TestImportProductsRowErrors(t *testing.T) {
+	csvBody := "sku,name,price,category_ids\n" +
+		"SUP-001,Soup,abc,\n" +
+		",Bread,2.00,99\n" +
+		"SUP-002,Stew,4.00,\n"
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/import", baseURL), strings.NewReader(csvBody))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("Content-Type", "text/csv")
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+
+	var report struct {
+		Created int `json:"created"`
+		Errors  []struct {
+			Row    int      `json:"row"`
+			Errors []string `json:"errors"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	if len(report.Errors) != 2 || report.Errors[0].Row != 2 || report.Errors[1].Row != 3 {
+		t.Errorf("Expected errors for rows 2 and 3, but got %+v", report.Errors)
+	} else if len(report.Errors[1].Errors) != 2 {
+		t.Errorf("Expected a missing sku and an unknown category in row 3, but got %v", report.Errors[1].Errors)
+	}
+
+	if productWithSKU(t, "2", "SUP-002") {
+		t.Errorf("Expected no products to be imported when a row is invalid")
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Errors: %+v", report.Errors)
+}
+
+func TestExportProductsCSV(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product/export?format=csv", baseURL), nil)
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(bodyBytes)), "\n")
+	if !strings.HasPrefix(lines[0], "sku,name,description,price") {
+		t.Errorf("Expected a CSV header, but got %q", lines[0])
+	}
+	if !strings.Contains(string(bodyBytes), "PKT-HEMAT,") {
+		t.Errorf("Expected the export to contain PKT-HEMAT")
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestCreateProductDuplicateSKU(t *testing.T) {
+	body := model.Product{
+		SKU:       "TEH-001",
+		Name:      "Es Teh Manis",
+		Price:     money.MustParse("1.50", "USD"),
+		Available: true,
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+// productWithSKU reports whether the restaurant's export contains the SKU.
+func productWithSKU(t *testing.T, restaurantID, sku string) bool {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/product/export", baseURL), nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	request.Header.Set("X-Restaurant-ID", restaurantID)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	var products []model.Product
+	if err := json.NewDecoder(response.Body).Decode(&products); err != nil {
+		t.Fatalf("Error decoding export: %v", err)
+	}
+
+	for _, product := range products {
+		if product.SKU == sku {
+			return true
+		}
+	}
+	return false
+}
+
+func TestProductPriceHistory(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}) *http.Response {
+		t.Helper()
+
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return response
+	}
+
+	response := send(http.MethodPost, fmt.Sprintf("%s/product", baseURL), model.Product{Name: "Teh Tarik", Price: money.MustParse("2.00", "USD"), Available: true})
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	json.NewDecoder(response.Body).Decode(&created)
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	before := time.Now().Add(-time.Second)
+	response = send(http.MethodPut, fmt.Sprintf("%s/product/%d", baseURL, created.Product.ID), model.Product{Name: "Teh Tarik", Price: money.MustParse("2.50", "USD"), Available: true})
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	response = send(http.MethodGet, fmt.Sprintf("%s/product/%d/price?at=%s", baseURL, created.Product.ID, before.Format(time.RFC3339)), nil)
+	var past struct {
+		Price money.Money `json:"price"`
+	}
+	json.NewDecoder(response.Body).Decode(&past)
+	response.Body.Close()
+	if past.Price.String() != "2.00 USD" {
+		t.Errorf("Expected price 2.00 USD before the update, but got %s", past.Price)
+	}
+
+	schedule := model.PriceChangeRequest{Price: money.MustParse("3.00", "USD"), EffectiveAt: time.Now().Add(time.Hour)}
+	serviceToken, err := token.IssueService()
+	if err != nil {
+		t.Fatalf("Error issuing service token: %v", err)
+	}
+	bodyJSON, _ := json.Marshal(schedule)
+	request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/%d/prices", baseURL, created.Product.ID), bytes.NewBuffer(bodyJSON))
+	request.Header.Set("X-Restaurant-ID", "2")
+	request.Header.Set("Authorization", "Bearer "+serviceToken)
+	response, err = client.Do(request)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected scheduling without a signed-in user to return %d, but got %d", http.StatusUnauthorized, response.StatusCode)
+	}
+
+	for _, price := range []string{"2.50", "2.75"} {
+		bodyJSON, _ := json.Marshal(model.Product{Name: "Teh Tarik", Price: money.MustParse(price, "USD"), Available: true})
+		request, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/product/%d", baseURL, created.Product.ID), bytes.NewBuffer(bodyJSON))
+		request.Header.Set("X-Restaurant-ID", "2")
+		request.Header.Set("Authorization", "Bearer "+serviceToken)
+		response, err = client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		response.Body.Close()
+
+		// A service call may update the product, but not change its price.
+		expected := http.StatusOK
+		if price != "2.50" {
+			expected = http.StatusUnauthorized
+		}
+		if response.StatusCode != expected {
+			t.Errorf("Expected a service update to price %s to return %d, but got %d", price, expected, response.StatusCode)
+		}
+	}
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/product/%d/prices", baseURL, created.Product.ID), schedule)
+	var scheduled struct {
+		PriceChange model.PriceChange `json:"price_change"`
+	}
+	json.NewDecoder(response.Body).Decode(&scheduled)
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated || scheduled.PriceChange.Status != model.PriceChangePending {
+		t.Errorf("Expected a pending price change, but got status %d and %+v", response.StatusCode, scheduled.PriceChange)
+	}
+	if scheduled.PriceChange.ChangedBy != 3 {
+		t.Errorf("Expected the change to record user 3, but got %d", scheduled.PriceChange.ChangedBy)
+	}
+
+	response = send(http.MethodGet, fmt.Sprintf("%s/product/%d/prices", baseURL, created.Product.ID), nil)
+	var changes []model.PriceChange
+	json.NewDecoder(response.Body).Decode(&changes)
+	response.Body.Close()
+	if len(changes) != 2 || changes[0].OldPrice.String() != "2.00 USD" || changes[0].NewPrice.String() != "2.50 USD" || changes[0].Source != model.PriceSourceUpdate {
+		t.Errorf("Expected the update followed by the scheduled change, but got %+v", changes)
+	}
+
+	response = send(http.MethodDelete, fmt.Sprintf("%s/product/%d/prices/%d", baseURL, created.Product.ID, scheduled.PriceChange.ID), nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	response = send(http.MethodDelete, fmt.Sprintf("%s/product/%d/prices/%d", baseURL, created.Product.ID, scheduled.PriceChange.ID), nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected cancelling twice to return %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	t.Logf("Price history: %+v", changes)
+}
+
+func TestScheduleProductPriceInPast(t *testing.T) {
+	body := model.PriceChangeRequest{Price: money.MustParse("1.00", "USD"), EffectiveAt: time.Now().Add(-time.Hour)}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product/5/prices", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	bearer, err := token.Issue(3, 2)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+bearer)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, response.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("Error reading response body: %v", err)
+		return
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Response body: %s", string(bodyBytes))
+}
+
+func TestUpdateProductWithStaleETag(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}, headers map[string]string) *http.Response {
+		t.Helper()
+
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			if err != nil {
+				t.Fatalf("Error marshaling request body: %v", err)
+			}
+			reader = bytes.NewBuffer(bodyJSON)
+		}
+
+		request, err := http.NewRequest(method, url, reader)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		response.Body.Close()
+		return response
+	}
+
+	response := send(http.MethodPost, fmt.Sprintf("%s/product", baseURL), model.Product{Name: "Kopi Susu", Price: money.MustParse("2.00", "USD"), Available: true}, nil)
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+	createdETag := response.Header.Get("ETag")
+
+	var id int
+	fmt.Sscanf(createdETag, `"%d-`, &id)
+	productURL := fmt.Sprintf("%s/product/%d", baseURL, id)
+
+	response = send(http.MethodGet, productURL, nil, nil)
+	if etag := response.Header.Get("ETag"); etag == "" || etag != createdETag {
+		t.Fatalf("Expected ETag %s, but got %q", createdETag, etag)
+	}
+
+	response = send(http.MethodGet, productURL, nil, map[string]string{"If-None-Match": createdETag})
+	if response.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotModified, response.StatusCode)
+	}
+
+	// The first manager saves with the current ETag.
+	response = send(http.MethodPut, productURL, model.Product{Name: "Kopi Susu", Price: money.MustParse("2.20", "USD"), Available: true}, map[string]string{"If-Match": createdETag})
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+	updatedETag := response.Header.Get("ETag")
+	if updatedETag == createdETag {
+		t.Errorf("Expected the ETag to change after the update")
+	}
+
+	// The second manager still holds the old ETag.
+	response = send(http.MethodPut, productURL, model.Product{Name: "Kopi Susu Gula Aren", Price: money.MustParse("2.50", "USD"), Available: true}, map[string]string{"If-Match": createdETag})
+	if response.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status code %d, but got %d", http.StatusPreconditionFailed, response.StatusCode)
+	}
+
+	response = send(http.MethodDelete, productURL, nil, map[string]string{"If-Match": createdETag})
+	if response.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status code %d, but got %d", http.StatusPreconditionFailed, response.StatusCode)
+	}
+
+	response = send(http.MethodDelete, productURL, nil, map[string]string{"If-Match": updatedETag})
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	t.Logf("ETags: created %s, updated %s", createdETag, updatedETag)
+}
+
+func TestPatchProductKeepsOmittedFields(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/product/5", baseURL), strings.NewReader(`{"description": "Iced jasmine tea", "tags": null}`))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+	request.Header.Set("Content-Type", "application/merge-patch+json")
+	request.Header.Set("X-Restaurant-ID", "2")
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	var result struct {
+		Product model.Product `json:"product"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	product := result.Product
+	if product.Description != "Iced jasmine tea" || len(product.Tags) != 0 {
+		t.Errorf("Expected the description replaced and the tags removed, but got %+v", product)
+	}
+	if product.Name != "Es Teh" || product.Price.Sign() <= 0 || len(product.OptionGroups) != 1 || product.SKU != "TEH-001" {
+		t.Errorf("Expected fields missing from the patch to be kept, but got %+v", product)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Product: %+v", product)
+}
+
+func TestPatchProductInvalidResult(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		contentType  string
+		patch        string
+		expectedCode int
+	}{
+		{name: "unknown category", contentType: "application/merge-patch+json", patch: `{"category_ids": [99]}`, expectedCode: http.StatusBadRequest},
+		{name: "unknown field", contentType: "application/merge-patch+json", patch: `{"prise": 1}`, expectedCode: http.StatusBadRequest},
+		{name: "json patch", contentType: "application/json-patch+json", patch: `[{"op": "remove", "path": "/price"}]`, expectedCode: http.StatusUnsupportedMediaType},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/product/4", baseURL), strings.NewReader(tt.patch))
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+			request.Header.Set("Content-Type", tt.contentType)
+			request.Header.Set("X-Restaurant-ID", "2")
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.expectedCode {
+				t.Errorf("Expected status code %d, but got %d", tt.expectedCode, response.StatusCode)
+			}
+
+			bodyBytes, _ := io.ReadAll(response.Body)
+			t.Logf("Response body: %s", string(bodyBytes))
+		})
+	}
+}
+
+func TestArchiveAndRestoreProduct(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}) (*http.Response, []byte) {
+		t.Helper()
+
+		var reader io.Reader
+		if body != nil {
+			bodyJSON, err := json.Marshal(body)
+			if err != nil {
+				t.Fatalf("Error marshaling request body: %v", err)
+			}
+			reader = bytes.NewBuffer(bodyJSON)
+		}
+
+		request, err := http.NewRequest(method, url, reader)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer response.Body.Close()
+
+		bodyBytes, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("Error reading response body: %v", err)
+		}
+		return response, bodyBytes
+	}
+
+	listed := func(archived bool, id int) bool {
+		t.Helper()
+
+		_, bodyBytes := send(http.MethodGet, fmt.Sprintf("%s/product?archived=%v&limit=100", baseURL, archived), nil)
+		var products []model.Product
+		json.Unmarshal(bodyBytes, &products)
+		for _, product := range products {
+			if product.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	response, bodyBytes := send(http.MethodPost, fmt.Sprintf("%s/product", baseURL), model.Product{Name: "Bubur Ayam", Price: money.MustParse("3.00", "USD"), Available: true})
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	json.Unmarshal(bodyBytes, &created)
+	productURL := fmt.Sprintf("%s/product/%d", baseURL, created.Product.ID)
+
+	response, _ = send(http.MethodDelete, productURL, nil)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	// Lookups by ID still resolve the archived product.
+	response, bodyBytes = send(http.MethodGet, productURL, nil)
+	var archived model.Product
+	json.Unmarshal(bodyBytes, &archived)
+	if response.StatusCode != http.StatusOK || archived.ArchivedAt == nil || archived.Orderable() {
+		t.Errorf("Expected the archived product to resolve without being orderable, but got status %d and %+v", response.StatusCode, archived)
+	}
+
+	if listed(false, created.Product.ID) || !listed(true, created.Product.ID) {
+		t.Errorf("Expected the archived product to be listed only with archived=true")
+	}
+
+	if response, _ = send(http.MethodPut, productURL, model.Product{Name: "Bubur Ayam", Price: money.MustParse("3.50", "USD"), Available: true}); response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected editing an archived product to return %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+	if response, _ = send(http.MethodDelete, productURL, nil); response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected archiving twice to return %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	response, _ = send(http.MethodPost, productURL+"/restore", nil)
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+	if !listed(false, created.Product.ID) {
+		t.Errorf("Expected the restored product to be back on the menu")
+	}
+
+	if response, _ = send(http.MethodPost, productURL+"/restore", nil); response.StatusCode != http.StatusConflict {
+		t.Errorf("Expected restoring twice to return %d, but got %d", http.StatusConflict, response.StatusCode)
+	}
+}
+
+func TestCreateProductValidationErrors(t *testing.T) {
+	body := model.Product{
+		Name:      " ",
+		Price:     money.MustParse("-1.00", "USD"),
+		Available: true,
+		OptionGroups: []model.OptionGroup{
+			{Name: "Size", Options: []model.Option{{Name: ""}}},
+		},
+	}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("Error marshaling request body: %v", err)
+		return
+	}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/product", baseURL), bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		t.Errorf("Error creating request: %v", err)
+		return
+	}
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Errorf("Error making request: %v", err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+
+	var result struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Rule  string `json:"rule"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Errorf("Error decoding response body: %v", err)
+		return
+	}
+
+	fields := make([]string, 0, len(result.Errors))
+	for _, fieldError := range result.Errors {
+		fields = append(fields, fieldError.Field)
+	}
+	if strings.Join(fields, ",") != "name,price,option_groups[0].options[0].name" {
+		t.Errorf("Expected errors for name, price and the option name, but got %v", fields)
+	}
+
+	t.Logf("Response status code: %d", response.StatusCode)
+	t.Logf("Errors: %+v", result.Errors)
+}
+
+func TestProductPricesInCurrency(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}, headers map[string]string) *http.Response {
+		t.Helper()
+
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return response
+	}
+
+	rate, _ := money.ParseRate("0.90")
+	response := send(http.MethodPut, fmt.Sprintf("%s/exchange-rates/USD/EUR", baseURL), model.ExchangeRateRequest{Rate: rate}, nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/product", baseURL), model.Product{
+		Name:      "Martabak Manis",
+		Price:     money.MustParse("5.00", "USD"),
+		Prices:    []money.Money{money.MustParse("4.00", "GBP")},
+		Available: true,
+	}, nil)
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	json.NewDecoder(response.Body).Decode(&created)
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+	productURL := fmt.Sprintf("%s/product/%d", baseURL, created.Product.ID)
+
+	tests := []struct {
+		name         string
+		url          string
+		headers      map[string]string
+		expectedCode int
+		expected     string
+	}{
+		{name: "converted", url: productURL + "?currency=eur", expectedCode: http.StatusOK, expected: "4.50 EUR"},
+		{name: "explicit price", url: productURL, headers: map[string]string{"X-Currency": "GBP"}, expectedCode: http.StatusOK, expected: "4.00 GBP"},
+		{name: "own currency", url: productURL, expectedCode: http.StatusOK, expected: "5.00 USD"},
+		{name: "no exchange rate", url: productURL + "?currency=CHF", expectedCode: http.StatusBadRequest},
+		{name: "invalid currency", url: productURL + "?currency=euro", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := send(http.MethodGet, tt.url, nil, tt.headers)
+			defer response.Body.Close()
+
+			if response.StatusCode != tt.expectedCode {
+				t.Fatalf("Expected status code %d, but got %d", tt.expectedCode, response.StatusCode)
+			}
+			if tt.expected == "" {
+				return
+			}
+
+			var product model.Product
+			json.NewDecoder(response.Body).Decode(&product)
+			if product.Price.String() != tt.expected {
+				t.Errorf("Expected price %s, but got %s", tt.expected, product.Price)
+			}
+		})
+	}
+
+	response = send(http.MethodGet, fmt.Sprintf("%s/product?currency=EUR&q=martabak&max_price=4.50", baseURL), nil, nil)
+	var products []model.Product
+	json.NewDecoder(response.Body).Decode(&products)
+	response.Body.Close()
+	if len(products) != 1 || products[0].Price.String() != "4.50 EUR" {
+		t.Errorf("Expected the product listed at 4.50 EUR, but got %+v", products)
+	}
+}
+
+func TestProductTranslations(t *testing.T) {
+	client := &http.Client{}
+	send := func(method, url string, body interface{}, headers map[string]string) *http.Response {
+		t.Helper()
+
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return response
+	}
+
+	response := send(http.MethodPost, fmt.Sprintf("%s/product", baseURL), model.Product{
+		Name:        "Fried Rice Special",
+		Description: "Fried rice with egg",
+		Price:       money.MustParse("4.00", "USD"),
+		Available:   true,
+	}, nil)
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	json.NewDecoder(response.Body).Decode(&created)
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+	productURL := fmt.Sprintf("%s/product/%d", baseURL, created.Product.ID)
+
+	for _, language := range []string{"en", "fr", "x"} {
+		response = send(http.MethodPut, productURL+"/translations/"+language, model.Translation{Name: "Nasi Goreng"}, nil)
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, but got %d", language, http.StatusBadRequest, response.StatusCode)
+		}
+	}
+
+	response = send(http.MethodPut, productURL+"/translations/id", model.Translation{}, nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d for a translation without a name, but got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+
+	response = send(http.MethodPut, productURL+"/translations/ID", model.Translation{Name: "Nasi Goreng Spesial"}, nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		language       string
+		productName    string
+	}{
+		{name: "no header", language: "en", productName: "Fried Rice Special"},
+		{name: "region", acceptLanguage: "id-ID,id;q=0.9,en;q=0.8", language: "id", productName: "Nasi Goreng Spesial"},
+		{name: "preferred english", acceptLanguage: "en-US,id;q=0.5", language: "en", productName: "Fried Rice Special"},
+		{name: "unsupported", acceptLanguage: "fr", language: "en", productName: "Fried Rice Special"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := send(http.MethodGet, productURL, nil, map[string]string{"Accept-Language": tt.acceptLanguage})
+			defer response.Body.Close()
+
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+			}
+			if language := response.Header.Get("Content-Language"); language != tt.language {
+				t.Errorf("Expected Content-Language %s, but got %s", tt.language, language)
+			}
+
+			var product model.Product
+			json.NewDecoder(response.Body).Decode(&product)
+			if product.Name != tt.productName {
+				t.Errorf("Expected name %q, but got %q", tt.productName, product.Name)
+			}
+			if product.Description != "Fried rice with egg" {
+				t.Errorf("Expected the untranslated description as fallback, but got %q", product.Description)
+			}
+		})
+	}
+
+	response = send(http.MethodGet, fmt.Sprintf("%s/product?q=spesial", baseURL), nil, map[string]string{"Accept-Language": "id"})
+	var products []model.Product
+	json.NewDecoder(response.Body).Decode(&products)
+	response.Body.Close()
+	if len(products) != 1 || products[0].Name != "Nasi Goreng Spesial" {
+		t.Errorf("Expected the product found by its Indonesian name, but got %+v", products)
+	}
+
+	response = send(http.MethodDelete, productURL+"/translations/id", nil, nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, response.StatusCode)
+	}
+
+	response = send(http.MethodGet, productURL, nil, map[string]string{"Accept-Language": "id"})
+	var product model.Product
+	json.NewDecoder(response.Body).Decode(&product)
+	response.Body.Close()
+	if product.Name != "Fried Rice Special" || response.Header.Get("Content-Language") != "en" {
+		t.Errorf("Expected the English name after deleting the translation, but got %q in %s", product.Name, response.Header.Get("Content-Language"))
+	}
+
+	response = send(http.MethodDelete, productURL+"/translations/id", nil, nil)
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, response.StatusCode)
+	}
+}
+
+func TestMenuDraftPublishAndRollback(t *testing.T) {
+	client := &http.Client{}
+	// Rolling back touches every product of the restaurant, so this test
+	// keeps to a restaurant no other test uses.
+	send := func(method, url string, body interface{}) *http.Response {
+		t.Helper()
+
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "3")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return response
+	}
+	getProduct := func(id int) (model.Product, int) {
+		t.Helper()
+
+		response := send(http.MethodGet, fmt.Sprintf("%s/product/%d", baseURL, id), nil)
+		defer response.Body.Close()
+
+		var product model.Product
+		json.NewDecoder(response.Body).Decode(&product)
+		return product, response.StatusCode
+	}
+	expectStatus := func(response *http.Response, expected int) {
+		t.Helper()
+
+		response.Body.Close()
+		if response.StatusCode != expected {
+			t.Fatalf("Expected status code %d, but got %d", expected, response.StatusCode)
+		}
+	}
+
+	response := send(http.MethodPost, fmt.Sprintf("%s/product", baseURL), model.Product{Name: "Soto Ayam", Price: money.MustParse("3.00", "USD"), Available: true})
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	json.NewDecoder(response.Body).Decode(&created)
+	expectStatus(response, http.StatusCreated)
+	soto := created.Product
+
+	response = send(http.MethodPut, fmt.Sprintf("%s/product/%d?draft=true", baseURL, soto.ID), model.Product{Name: "Soto Ayam", Price: money.MustParse("3.50", "USD"), Available: true})
+	expectStatus(response, http.StatusAccepted)
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/product?draft=true", baseURL), model.Product{Name: "Es Cendol", Price: money.MustParse("1.25", "USD"), Available: true})
+	var staged struct {
+		Draft model.DraftChange `json:"draft"`
+	}
+	json.NewDecoder(response.Body).Decode(&staged)
+	expectStatus(response, http.StatusAccepted)
+	cendolID := staged.Draft.ProductID
+
+	if product, _ := getProduct(soto.ID); product.Price.String() != "3.00 USD" {
+		t.Errorf("Expected the live price to stay 3.00 USD until publish, but got %s", product.Price)
+	}
+	if _, status := getProduct(cendolID); status != http.StatusNotFound {
+		t.Errorf("Expected a staged product to be hidden until publish, but got status %d", status)
+	}
+
+	response = send(http.MethodGet, fmt.Sprintf("%s/menu/preview", baseURL), nil)
+	var preview model.Menu
+	json.NewDecoder(response.Body).Decode(&preview)
+	expectStatus(response, http.StatusOK)
+	previewPrices := make(map[string]string)
+	for _, product := range preview.Uncategorized {
+		previewPrices[product.Name] = product.Price.String()
+	}
+	if previewPrices["Soto Ayam"] != "3.50 USD" || previewPrices["Es Cendol"] != "1.25 USD" {
+		t.Errorf("Expected the preview to show the staged changes, but got %v", previewPrices)
+	}
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/menu/publish", baseURL), model.PublishRequest{Note: "dessert menu"})
+	var published struct {
+		Version model.MenuVersion `json:"version"`
+	}
+	json.NewDecoder(response.Body).Decode(&published)
+	expectStatus(response, http.StatusOK)
+	if published.Version.Version != 2 || published.Version.Changes != 2 {
+		t.Errorf("Expected version 2 with 2 changes after the initial version, but got %+v", published.Version)
+	}
+
+	if product, _ := getProduct(soto.ID); product.Price.String() != "3.50 USD" {
+		t.Errorf("Expected the published price 3.50 USD, but got %s", product.Price)
+	}
+	if _, status := getProduct(cendolID); status != http.StatusOK {
+		t.Errorf("Expected the published product to exist, but got status %d", status)
+	}
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/menu/publish", baseURL), nil)
+	expectStatus(response, http.StatusConflict)
+
+	// A live edit after staging makes the staged change stale.
+	response = send(http.MethodDelete, fmt.Sprintf("%s/product/%d?draft=true", baseURL, cendolID), nil)
+	expectStatus(response, http.StatusAccepted)
+	response = send(http.MethodPut, fmt.Sprintf("%s/product/%d", baseURL, cendolID), model.Product{Name: "Es Cendol Durian", Price: money.MustParse("1.50", "USD"), Available: true})
+	expectStatus(response, http.StatusOK)
+	response = send(http.MethodPost, fmt.Sprintf("%s/menu/publish", baseURL), nil)
+	expectStatus(response, http.StatusConflict)
+	response = send(http.MethodDelete, fmt.Sprintf("%s/menu/draft/%d", baseURL, cendolID), nil)
+	expectStatus(response, http.StatusOK)
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/menu/versions/1/rollback", baseURL), nil)
+	var rolledBack struct {
+		Version model.MenuVersion `json:"version"`
+	}
+	json.NewDecoder(response.Body).Decode(&rolledBack)
+	expectStatus(response, http.StatusOK)
+	if rolledBack.Version.Version != 3 || rolledBack.Version.RolledBackFrom != 1 {
+		t.Errorf("Expected version 3 rolled back from 1, but got %+v", rolledBack.Version)
+	}
+
+	if product, _ := getProduct(soto.ID); product.Price.String() != "3.00 USD" {
+		t.Errorf("Expected the rollback to restore 3.00 USD, but got %s", product.Price)
+	}
+	if product, _ := getProduct(cendolID); !product.Archived() {
+		t.Errorf("Expected a product created after version 1 to be archived by the rollback")
+	}
+
+	response = send(http.MethodGet, fmt.Sprintf("%s/menu", baseURL), nil)
+	var menu model.Menu
+	json.NewDecoder(response.Body).Decode(&menu)
+	expectStatus(response, http.StatusOK)
+	if menu.Version != 3 {
+		t.Errorf("Expected the live menu at version 3, but got %d", menu.Version)
+	}
+
+	response = send(http.MethodPost, fmt.Sprintf("%s/menu/versions/9/rollback", baseURL), nil)
+	expectStatus(response, http.StatusNotFound)
+}
+
+func TestSaleWithIDIsIdempotentAndCancellable(t *testing.T) {
+	client := &http.Client{}
+
+	send := func(method, path string, body interface{}, v interface{}) int {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Error marshaling request body: %v", err)
+		}
+
+		request, err := http.NewRequest(method, baseURL+path, bytes.NewBuffer(bodyJSON))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "2")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer response.Body.Close()
+
+		if v != nil {
+			if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+				t.Fatalf("Error decoding response body: %v", err)
+			}
+		}
+		return response.StatusCode
+	}
+
+	var created struct {
+		Product model.Product `json:"product"`
+	}
+	status := send(http.MethodPost, "/product", model.Product{Name: "Lemper", Price: money.MustParse("1.00", "USD"), Available: true, TrackStock: true}, &created)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusCreated, status)
+	}
+	path := fmt.Sprintf("/product/%d", created.Product.ID)
+
+	if status := send(http.MethodPost, path+"/stock", model.StockAdjustmentRequest{Change: 5, Reason: model.StockReasonDelivery}, nil); status != http.StatusOK {
+		t.Fatalf("Expected status code %d, but got %d", http.StatusOK, status)
+	}
+
+	saleID := fmt.Sprintf("test-sale-%d", created.Product.ID)
+	sale := model.SaleRequest{SaleID: saleID, Items: []model.SaleItem{{ProductID: created.Product.ID, Quantity: 2}}}
+	for range 2 {
+		if status := sendAsService(t, "/product/sales", sale); status != http.StatusOK {
+			t.Fatalf("Expected status code %d, but got %d", http.StatusOK, status)
+		}
+	}
+
+	var product model.Product
+	send(http.MethodGet, path, nil, &product)
+	if product.Stock != 3 {
+		t.Errorf("Expected a repeated sale to deduct stock once, leaving 3, but got %d", product.Stock)
+	}
+
+	for range 2 {
+		if status := sendAsService(t, "/product/sales/cancel", map[string]string{"sale_id": saleID}); status != http.StatusOK {
+			t.Fatalf("Expected status code %d, but got %d", http.StatusOK, status)
+		}
+	}
+
+	send(http.MethodGet, path, nil, &product)
+	if product.Stock != 5 || product.Popularity != 0 {
+		t.Errorf("Expected the cancellation to put back stock 5 and popularity 0, but got %d and %d", product.Stock, product.Popularity)
+	}
+
+	if status := sendAsService(t, "/product/sales", sale); status != http.StatusConflict {
+		t.Errorf("Expected replaying a cancelled sale to return %d, but got %d", http.StatusConflict, status)
+	}
+	unknownSaleID := fmt.Sprintf("unknown-sale-%d", created.Product.ID)
+	if status := sendAsService(t, "/product/sales/cancel", map[string]string{"sale_id": unknownSaleID}); status != http.StatusNotFound {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, status)
+	}
+
+	late := model.SaleRequest{SaleID: unknownSaleID, Items: []model.SaleItem{{ProductID: created.Product.ID, Quantity: 1}}}
+	if status := sendAsService(t, "/product/sales", late); status != http.StatusConflict {
+		t.Errorf("Expected a sale arriving after its cancellation to return %d, but got %d", http.StatusConflict, status)
+	}
+}
+
+func TestSalesRequireServiceToken(t *testing.T) {
+	client := &http.Client{}
+
+	for _, path := range []string{"/product/1/sales", "/product/sales", "/product/sales/cancel"} {
+		request, err := http.NewRequest(http.MethodPost, baseURL+path, strings.NewReader(`{"quantity": 1, "sale_id": "user-sale", "items": [{"product_id": 1, "quantity": 1}]}`))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		request.Header.Set("X-Restaurant-ID", "1")
+
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected a user token on %s to return %d, but got %d", path, http.StatusForbidden, response.StatusCode)
+		}
+	}
+}
+
+func TestGuardLocksByMethod(t *testing.T) {
+	var lock sync.RWMutex
+
+	var shared, exclusive bool
+	handler := guard(&lock, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Another reader can join a GET, but nobody can join a POST.
+		if lock.TryRLock() {
+			lock.RUnlock()
+			shared = true
+		}
+		exclusive = !lock.TryLock()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/product", nil))
+	if !shared || !exclusive {
+		t.Errorf("Expected GET to hold a shared lock, got shared=%v exclusive=%v", shared, exclusive)
+	}
+
+	shared, exclusive = false, false
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/product", nil))
+	if shared || !exclusive {
+		t.Errorf("Expected POST to hold an exclusive lock, got shared=%v exclusive=%v", shared, exclusive)
+	}
+
+	if !lock.TryLock() {
+		t.Fatalf("Expected the lock to be released after the request")
+	}
+	lock.Unlock()
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"restaurant/model"
+	"restaurant/money"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// productQuery holds the search, filter, sort and pagination parameters of
+// GET /product.
+type productQuery struct {
+	terms      []string
+	minPrice   *money.Money
+	maxPrice   *money.Money
+	categoryID int
+	tags       []string
+	available  *bool
+	archived   bool
+	sortField  string
+	descending bool
+	// Only requests that give offset or limit are paginated; others get
+	// every matching product, as before pagination existed.
+	paginated bool
+	offset    int
+	limit     int
+
+	excludeAllergens []string
+	dietaryLabels    []string
+	maxCalories      *int
+
+	// now and categories decide whether scheduled products are available.
+	now        time.Time
+	categories []model.Category
+}
+
+// parseProductQuery reads the query parameters. Price bounds without a
+// currency are in the requested display currency, if any.
+func parseProductQuery(values url.Values, currency string) (productQuery, error) {
+	query := productQuery{limit: defaultPageLimit, now: time.Now()}
+
+	query.terms = strings.Fields(strings.ToLower(values.Get("q")))
+
+	for _, param := range []struct {
+		name   string
+		target **money.Money
+	}{
+		{"min_price", &query.minPrice},
+		{"max_price", &query.maxPrice},
+	} {
+		if value := values.Get(param.name); value != "" {
+			if currency != "" && !strings.Contains(strings.TrimSpace(value), " ") {
+				value += " " + currency
+			}
+			parsed, err := money.ParseString(value)
+			if err != nil || parsed.Sign() < 0 {
+				return query, fmt.Errorf("invalid %s", param.name)
+			}
+			*param.target = &parsed
+		}
+	}
+
+	if value := values.Get("category"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid category")
+		}
+		query.categoryID = id
+	}
+
+	if value := values.Get("tags"); value != "" {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				query.tags = append(query.tags, tag)
+			}
+		}
+	}
+
+	if value := values.Get("available"); value != "" {
+		available, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid available")
+		}
+		query.available = &available
+	}
+
+	if value := values.Get("archived"); value != "" {
+		archived, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid archived")
+		}
+		query.archived = archived
+	}
+
+	if value := values.Get("exclude_allergens"); value != "" {
+		allergens, err := model.ParseAllergens(value)
+		if err != nil {
+			return query, err
+		}
+		query.excludeAllergens = allergens
+	}
+
+	if value := values.Get("dietary"); value != "" {
+		labels, err := model.ParseDietaryLabels(value)
+		if err != nil {
+			return query, err
+		}
+		query.dietaryLabels = labels
+	}
+
+	if value := values.Get("max_calories"); value != "" {
+		calories, err := strconv.Atoi(value)
+		if err != nil || calories < 0 {
+			return query, fmt.Errorf("invalid max_calories")
+		}
+		query.maxCalories = &calories
+	}
+
+	if value := values.Get("sort"); value != "" {
+		query.sortField, query.descending = strings.CutPrefix(value, "-")
+		switch query.sortField {
+		case "id", "price", "name", "popularity":
+		default:
+			return query, fmt.Errorf("invalid sort, expected one of id, price, name, popularity")
+		}
+	}
+
+	if value := values.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return query, fmt.Errorf("invalid offset")
+		}
+		query.offset = offset
+		query.paginated = true
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return query, fmt.Errorf("invalid limit, expected 1 to %d", maxPageLimit)
+		}
+		query.limit = limit
+		query.paginated = true
+	}
+
+	return query, nil
+}
+
+func (q productQuery) matches(product model.Product) bool {
+	text := strings.ToLower(product.Name + " " + product.Description)
+	for _, term := range q.terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+
+	// Price bounds carry a currency ("5.00 EUR") and only match products
+	// shown in it.
+	if q.minPrice != nil {
+		if c, err := product.Price.Cmp(*q.minPrice); err != nil || c < 0 {
+			return false
+		}
+	}
+	if q.maxPrice != nil {
+		if c, err := product.Price.Cmp(*q.maxPrice); err != nil || c > 0 {
+			return false
+		}
+	}
+	if q.categoryID != 0 && !product.InCategory(q.categoryID) {
+		return false
+	}
+	for _, tag := range q.tags {
+		if !product.HasTag(tag) {
+			return false
+		}
+	}
+	// Products that do not declare their allergens cannot be shown to be
+	// free of the excluded ones.
+	if len(q.excludeAllergens) > 0 && !product.AllergensDeclared() {
+		return false
+	}
+	for _, allergen := range q.excludeAllergens {
+		if product.ContainsAllergen(allergen) {
+			return false
+		}
+	}
+	for _, label := range q.dietaryLabels {
+		if !product.HasDietaryLabel(label) {
+			return false
+		}
+	}
+	// Products without nutrition facts cannot be shown to be under the limit.
+	if q.maxCalories != nil && (product.Nutrition == nil || product.Nutrition.Calories > *q.maxCalories) {
+		return false
+	}
+	if q.available != nil && (product.Orderable() && product.AvailableAt(q.now, q.categories)) != *q.available {
+		return false
+	}
+	return true
+}
+
+// apply filters and sorts products. It returns the requested page, or every
+// match if the query is not paginated, together with the number of products
+// that matched before paging.
+func (q productQuery) apply(products []model.Product) ([]model.Product, int) {
+	matched := make([]model.Product, 0, len(products))
+	for _, product := range products {
+		if q.matches(product) {
+			matched = append(matched, product)
+		}
+	}
+
+	if q.sortField != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i], matched[j]
+			if q.descending {
+				a, b = b, a
+			}
+
+			switch q.sortField {
+			case "price":
+				if c := money.Compare(a.Price, b.Price); c != 0 {
+					return c < 0
+				}
+			case "name":
+				if nameA, nameB := strings.ToLower(a.Name), strings.ToLower(b.Name); nameA != nameB {
+					return nameA < nameB
+				}
+			case "popularity":
+				if a.Popularity != b.Popularity {
+					return a.Popularity < b.Popularity
+				}
+			}
+			return a.ID < b.ID
+		})
+	}
+
+	total := len(matched)
+	if !q.paginated {
+		return matched, total
+	}
+	if q.offset >= total {
+		return make([]model.Product, 0), total
+	}
+
+	end := q.offset + q.limit
+	if end > total {
+		end = total
+	}
+	return matched[q.offset:end], total
+}
+
+// setPaginationHeaders writes X-Total-Count and an RFC 8288 Link header with
+// first, prev, next and last relations.
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, q productQuery, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	pageURL := func(offset int) string {
+		values := r.URL.Query()
+		values.Set("offset", strconv.Itoa(offset))
+		values.Set("limit", strconv.Itoa(q.limit))
+		return fmt.Sprintf("<%s?%s>", r.URL.Path, values.Encode())
+	}
+
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = (total - 1) / q.limit * q.limit
+	}
+
+	links := []string{pageURL(0) + `; rel="first"`}
+	if q.offset > 0 {
+		prev := q.offset - q.limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageURL(prev)+`; rel="prev"`)
+	}
+	// Offsets may be as large as an int holds, so compare against total
+	// without adding to them.
+	if q.offset < total-q.limit {
+		links = append(links, pageURL(q.offset+q.limit)+`; rel="next"`)
+	}
+	links = append(links, pageURL(lastOffset)+`; rel="last"`)
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRecommendations = 5
+	maxRecommendations     = 20
+)
+
+// orderServiceClient asks the order service for its history. The short
+// timeout lets recommendations fall back to tags instead of hanging when the
+// order service does.
+var orderServiceClient = &http.Client{Timeout: 2 * time.Second}
+
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// registerRecommendationRoutes serves the routes that ask the order service
+// for its history. They are not guarded, so the call is made without holding
+// the store lock, which is only taken to read the products.
+func registerRecommendationRoutes(mux *http.ServeMux, lock *sync.RWMutex, productDB *storage.ProductStorage) {
+	mux.HandleFunc(
+		"/product/{id}/recommendations", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			limit := defaultRecommendations
+			if value := r.URL.Query().Get("limit"); value != "" {
+				limit, err = strconv.Atoi(value)
+				if err != nil || limit < 1 || limit > maxRecommendations {
+					http.Error(w, fmt.Sprintf("invalid limit, expected 1 to %d", maxRecommendations), http.StatusBadRequest)
+					return
+				}
+			}
+
+			lock.RLock()
+			_, exists := productDB.GetProductByID(restaurantID, id)
+			lock.RUnlock()
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			// Without the order history the suggestions still work from
+			// tags alone, so a failing order service does not break the
+			// ordering screen.
+			together, err := fetchOrderedTogether(restaurantID, id)
+			if err != nil {
+				log.Printf("Error fetching products ordered with %d: %v", id, err)
+			}
+
+			lock.RLock()
+			defer lock.RUnlock()
+
+			product, exists := productDB.GetProductByID(restaurantID, id)
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			language := requestLanguage(w, r)
+			w.Header().Set("Content-Language", language)
+
+			recommendations := recommend(productDB, *product, together, limit)
+			for i := range recommendations {
+				localized := recommendations[i].Product.Localized(language)
+				recommendations[i].Product = &localized
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(recommendations)
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/tags", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			lock.RLock()
+			products := productDB.GetProductsByRestaurant(restaurantID)
+			lock.RUnlock()
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(countTags(products))
+		},
+	)
+}
+
+// fetchOrderedTogether asks the order service which products were ordered in
+// the same visits as the product.
+func fetchOrderedTogether(restaurantID, productID int) ([]model.Recommendation, error) {
+	request, err := http.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf("http://order-service:8080/order/recommendations?product_id=%d", productID),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	tenant.Forward(request, restaurantID)
+
+	response, err := orderServiceClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("order service answered %d", response.StatusCode)
+	}
+
+	var recommendations []model.Recommendation
+	if err := json.NewDecoder(response.Body).Decode(&recommendations); err != nil {
+		return nil, err
+	}
+	return recommendations, nil
+}
+
+// recommend picks up to limit orderable products to offer with product:
+// first those most often ordered together with it, then those sharing the
+// most tags with it, more popular ones first.
+func recommend(productDB *storage.ProductStorage, product model.Product, together []model.Recommendation, limit int) []model.Recommendation {
+	recommendations := make([]model.Recommendation, 0, limit)
+	included := map[int]bool{product.ID: true}
+
+	for _, recommendation := range together {
+		if len(recommendations) == limit {
+			return recommendations
+		}
+		candidate, exists := productDB.GetProductByID(product.RestaurantID, recommendation.ProductID)
+		if !exists || included[candidate.ID] || !candidate.Orderable() {
+			continue
+		}
+		included[candidate.ID] = true
+		found := *candidate
+		recommendation.Product = &found
+		recommendations = append(recommendations, recommendation)
+	}
+
+	tagged := make([]model.Recommendation, 0)
+	for _, candidate := range productDB.GetProductsByRestaurant(product.RestaurantID) {
+		if included[candidate.ID] || !candidate.Orderable() {
+			continue
+		}
+		if shared := product.SharedTags(candidate); shared > 0 {
+			found := candidate
+			tagged = append(tagged, model.Recommendation{ProductID: candidate.ID, Reason: model.RecommendationSharedTags, Score: shared, Product: &found})
+		}
+	}
+	sort.SliceStable(tagged, func(i, j int) bool {
+		if tagged[i].Score != tagged[j].Score {
+			return tagged[i].Score > tagged[j].Score
+		}
+		if tagged[i].Product.Popularity != tagged[j].Product.Popularity {
+			return tagged[i].Product.Popularity > tagged[j].Product.Popularity
+		}
+		return tagged[i].ProductID < tagged[j].ProductID
+	})
+
+	for _, recommendation := range tagged {
+		if len(recommendations) == limit {
+			break
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+	return recommendations
+}
+
+// countTags lists the tags in use with the number of products carrying each,
+// most used first. Spellings that differ only in case are counted together.
+func countTags(products []model.Product) []tagCount {
+	counts := make([]tagCount, 0)
+	index := make(map[string]int)
+	for _, product := range products {
+		for _, tag := range product.Tags {
+			key := strings.ToLower(tag)
+			if i, seen := index[key]; seen {
+				counts[i].Count++
+				continue
+			}
+			index[key] = len(counts)
+			counts = append(counts, tagCount{Tag: tag, Count: 1})
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return strings.ToLower(counts[i].Tag) < strings.ToLower(counts[j].Tag)
+	})
+	return counts
+}
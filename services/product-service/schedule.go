@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"strconv"
+	"time"
+)
+
+func registerScheduleRoutes(mux *http.ServeMux, productDB *storage.ProductStorage, categoryDB *storage.CategoryStorage) {
+	mux.HandleFunc(
+		"/product/{id}/availability", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			at := time.Now()
+			if value := r.URL.Query().Get("at"); value != "" {
+				at, err = time.Parse(time.RFC3339, value)
+				if err != nil {
+					http.Error(w, "invalid at, expected RFC 3339 time", http.StatusBadRequest)
+					return
+				}
+			}
+
+			product, exists := productDB.GetProductByID(restaurantID, id)
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			scheduled := product.AvailableAt(at, categoryDB.GetCategoriesByRestaurant(restaurantID))
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(
+				map[string]interface{}{
+					"product_id": id,
+					"at":         at,
+					"orderable":  product.Orderable(),
+					"scheduled":  scheduled,
+					"available":  product.Orderable() && scheduled,
+				},
+			)
+		},
+	)
+}
+
+// checkSchedule validates an optional schedule.
+func checkSchedule(schedule *model.Schedule) error {
+	if schedule == nil {
+		return nil
+	}
+	return schedule.Validate()
+}
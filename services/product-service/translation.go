@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant/events"
+	"restaurant/locale"
+	"restaurant/model"
+	"restaurant/storage"
+	"restaurant/tenant"
+	"restaurant/validate"
+	"strconv"
+)
+
+// registerTranslationRoutes manages the names and descriptions of products
+// and categories in languages other than locale.Default. Reads pick a
+// language from Accept-Language; see requestLanguage.
+func registerTranslationRoutes(mux *http.ServeMux, productDB *storage.ProductStorage, categoryDB *storage.CategoryStorage, publisher *events.Publisher) {
+	mux.HandleFunc(
+		"/product/{id}/translations", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			product, exists := productDB.GetProductByID(restaurantID, id)
+			if !exists {
+				http.Error(w, "Product not found", http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(translationsOrEmpty(product.Translations))
+		},
+	)
+
+	mux.HandleFunc(
+		"/product/{id}/translations/{language}", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				return
+			}
+
+			language, err := parseLanguage(r.PathValue("language"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				product, exists := productDB.GetProductByID(restaurantID, id)
+				if !exists {
+					http.Error(w, "Product not found", http.StatusNotFound)
+					return
+				}
+				translation, translated := product.Translations[language]
+				if !translated {
+					http.Error(w, "Translation not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(translation)
+
+			case http.MethodPut:
+				translation, ok := decodeTranslation(w, r)
+				if !ok {
+					return
+				}
+
+				log.Printf("Translating product ID %d into %s", id, language)
+
+				product, err := productDB.SetTranslation(restaurantID, id, language, translation)
+				if err != nil {
+					http.Error(w, err.Error(), translationErrorStatus(err))
+					return
+				}
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+				w.Header().Set("ETag", productETag(*product))
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":     "translation saved successfully",
+						"language":    language,
+						"translation": translation,
+					},
+				)
+
+			case http.MethodDelete:
+				product, err := productDB.RemoveTranslation(restaurantID, id, language)
+				if err != nil {
+					http.Error(w, err.Error(), translationErrorStatus(err))
+					return
+				}
+				publisher.PublishProduct(events.ProductUpdated, restaurantID, id)
+
+				w.Header().Set("ETag", productETag(*product))
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "translation deleted successfully"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+
+	mux.HandleFunc(
+		"/category/{id}/translations", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid category ID", http.StatusBadRequest)
+				return
+			}
+
+			category, exists := categoryDB.GetCategoryByID(restaurantID, id)
+			if !exists {
+				http.Error(w, "Category not found", http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(translationsOrEmpty(category.Translations))
+		},
+	)
+
+	mux.HandleFunc(
+		"/category/{id}/translations/{language}", func(w http.ResponseWriter, r *http.Request) {
+			restaurantID, err := tenant.FromRequest(r)
+			if err != nil {
+				http.Error(w, err.Error(), tenant.StatusCode(err))
+				return
+			}
+
+			id, err := strconv.Atoi(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, "Invalid category ID", http.StatusBadRequest)
+				return
+			}
+
+			language, err := parseLanguage(r.PathValue("language"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet:
+				category, exists := categoryDB.GetCategoryByID(restaurantID, id)
+				if !exists {
+					http.Error(w, "Category not found", http.StatusNotFound)
+					return
+				}
+				translation, translated := category.Translations[language]
+				if !translated {
+					http.Error(w, "Translation not found", http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(translation)
+
+			case http.MethodPut:
+				translation, ok := decodeTranslation(w, r)
+				if !ok {
+					return
+				}
+
+				log.Printf("Translating category ID %d into %s", id, language)
+
+				if _, err := categoryDB.SetTranslation(restaurantID, id, language, translation); err != nil {
+					http.Error(w, err.Error(), translationErrorStatus(err))
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(
+					map[string]interface{}{
+						"message":     "translation saved successfully",
+						"language":    language,
+						"translation": translation,
+					},
+				)
+
+			case http.MethodDelete:
+				if _, err := categoryDB.RemoveTranslation(restaurantID, id, language); err != nil {
+					http.Error(w, err.Error(), translationErrorStatus(err))
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]string{"message": "translation deleted successfully"})
+
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			}
+		},
+	)
+}
+
+// requestLanguage negotiates the language of the response from the
+// Accept-Language header. Requests without the header get locale.Default.
+func requestLanguage(w http.ResponseWriter, r *http.Request) string {
+	w.Header().Add("Vary", "Accept-Language")
+	return locale.Negotiate(r.Header.Get("Accept-Language"), locale.Supported())
+}
+
+// parseLanguage checks that a translation is for a supported language other
+// than the default one, whose text lives on the product or category itself.
+func parseLanguage(tag string) (string, error) {
+	language, err := locale.Normalize(tag)
+	if err != nil {
+		return "", err
+	}
+	if language == locale.Default {
+		return "", fmt.Errorf("%s is the default language, edit the name and description directly", language)
+	}
+	for _, supported := range locale.Supported() {
+		if supported == language {
+			return language, nil
+		}
+	}
+	return "", fmt.Errorf("language %s is not supported", language)
+}
+
+// checkTranslations normalizes the language keys of translations given when
+// a product or category is created and validates every entry.
+func checkTranslations(translations map[string]model.Translation) (map[string]model.Translation, error) {
+	if len(translations) == 0 {
+		return nil, nil
+	}
+
+	checked := make(map[string]model.Translation, len(translations))
+	for tag, translation := range translations {
+		language, err := parseLanguage(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := checked[language]; exists {
+			return nil, fmt.Errorf("translations list %s twice", language)
+		}
+		if errs := validate.Struct(translation); errs != nil {
+			return nil, fmt.Errorf("translation %s: %v", language, errs)
+		}
+		checked[language] = translation
+	}
+	return checked, nil
+}
+
+func decodeTranslation(w http.ResponseWriter, r *http.Request) (model.Translation, bool) {
+	var translation model.Translation
+	if err := json.NewDecoder(r.Body).Decode(&translation); err != nil {
+		log.Printf("Error decoding translation: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return translation, false
+	}
+
+	if errs := validate.Struct(translation); errs != nil {
+		validate.WriteErrors(w, errs)
+		return translation, false
+	}
+	return translation, true
+}
+
+func localizeProducts(products []model.Product, language string) []model.Product {
+	localized := make([]model.Product, 0, len(products))
+	for _, product := range products {
+		localized = append(localized, product.Localized(language))
+	}
+	return localized
+}
+
+func translationsOrEmpty(translations map[string]model.Translation) map[string]model.Translation {
+	if translations == nil {
+		return map[string]model.Translation{}
+	}
+	return translations
+}
+
+func translationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrProductNotFound),
+		errors.Is(err, storage.ErrCategoryNotFound),
+		errors.Is(err, storage.ErrTranslationMissing):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
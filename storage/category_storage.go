@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"restaurant/model"
+	"sort"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+type CategoryStorage struct {
+	Categories []model.Category
+}
+
+var categoryStorage *CategoryStorage
+
+func init() {
+	categoryStorage = &CategoryStorage{
+		Categories: make([]model.Category, 0),
+	}
+	log.Println("Category storage initialized with empty category list")
+}
+
+func NewCategoryStorage() *CategoryStorage {
+	return categoryStorage
+}
+
+func (s *CategoryStorage) GetCategoryByID(restaurantID, id int) (*model.Category, bool) {
+	for i := range s.Categories {
+		if s.Categories[i].ID == id && s.Categories[i].RestaurantID == restaurantID {
+			return &s.Categories[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetCategoriesByRestaurant returns the restaurant's categories in menu order.
+func (s *CategoryStorage) GetCategoriesByRestaurant(restaurantID int) []model.Category {
+	categories := make([]model.Category, 0)
+	for _, category := range s.Categories {
+		if category.RestaurantID == restaurantID {
+			categories = append(categories, category)
+		}
+	}
+
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].Position != categories[j].Position {
+			return categories[i].Position < categories[j].Position
+		}
+		return categories[i].ID < categories[j].ID
+	})
+	return categories
+}
+
+func (s *CategoryStorage) AddCategory(category model.Category) {
+	s.Categories = append(s.Categories, category)
+	log.Printf("Category added: ID=%d, RestaurantID=%d, Name=%s", category.ID, category.RestaurantID, category.Name)
+}
+
+func (s *CategoryStorage) UpdateCategory(restaurantID, id int, category model.Category) bool {
+	for i := range s.Categories {
+		if s.Categories[i].ID == id && s.Categories[i].RestaurantID == restaurantID {
+			category.ID = id
+			category.RestaurantID = restaurantID
+			category.Translations = s.Categories[i].Translations
+			s.Categories[i] = category
+			log.Printf("Category updated: ID=%d, Name=%s", id, category.Name)
+			return true
+		}
+	}
+	return false
+}
+
+// SetTranslation adds or replaces the category's translation into language.
+func (s *CategoryStorage) SetTranslation(restaurantID, id int, language string, translation model.Translation) (*model.Category, error) {
+	category, exists := s.GetCategoryByID(restaurantID, id)
+	if !exists {
+		return nil, ErrCategoryNotFound
+	}
+
+	category.Translations = model.WithTranslation(category.Translations, language, translation)
+	log.Printf("Category translation set: ID=%d, Language=%s", id, language)
+	return category, nil
+}
+
+func (s *CategoryStorage) RemoveTranslation(restaurantID, id int, language string) (*model.Category, error) {
+	category, exists := s.GetCategoryByID(restaurantID, id)
+	if !exists {
+		return nil, ErrCategoryNotFound
+	}
+	if _, translated := category.Translations[language]; !translated {
+		return nil, ErrTranslationMissing
+	}
+
+	category.Translations = model.WithoutTranslation(category.Translations, language)
+	log.Printf("Category translation removed: ID=%d, Language=%s", id, language)
+	return category, nil
+}
+
+func (s *CategoryStorage) DeleteCategory(restaurantID, id int) bool {
+	for i := range s.Categories {
+		if s.Categories[i].ID == id && s.Categories[i].RestaurantID == restaurantID {
+			s.Categories = append(s.Categories[:i], s.Categories[i+1:]...)
+			log.Printf("Category deleted: ID=%d", id)
+			return true
+		}
+	}
+	return false
+}
+
+// NextCategoryID returns an ID that is unique across all restaurants.
+func (s *CategoryStorage) NextCategoryID() int {
+	next := 1
+	for _, category := range s.Categories {
+		if category.ID >= next {
+			next = category.ID + 1
+		}
+	}
+	return next
+}
+
+func (s *CategoryStorage) GetCategoryCount() int {
+	return len(s.Categories)
+}
+
+func (s *CategoryStorage) Snapshot() func() {
+	saved := make([]model.Category, len(s.Categories))
+	copy(saved, s.Categories)
+	return func() {
+		s.Categories = saved
+	}
+}
+
+func (s *CategoryStorage) Reset() {
+	s.Categories = make([]model.Category, 0)
+	log.Println("Category storage reset")
+}
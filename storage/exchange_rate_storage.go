@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"log"
+	"restaurant/model"
+	"sort"
+)
+
+type ExchangeRateStorage struct {
+	Rates []model.ExchangeRate
+}
+
+var exchangeRateStorage *ExchangeRateStorage
+
+func init() {
+	exchangeRateStorage = &ExchangeRateStorage{
+		Rates: make([]model.ExchangeRate, 0),
+	}
+	log.Println("Exchange rate storage initialized with empty rate table")
+}
+
+func NewExchangeRateStorage() *ExchangeRateStorage {
+	return exchangeRateStorage
+}
+
+func (s *ExchangeRateStorage) GetRate(restaurantID int, base, currency string) (*model.ExchangeRate, bool) {
+	for i := range s.Rates {
+		rate := &s.Rates[i]
+		if rate.RestaurantID == restaurantID && rate.Base == base && rate.Currency == currency {
+			return rate, true
+		}
+	}
+	return nil, false
+}
+
+// GetRatesByRestaurant returns the restaurant's rates ordered by base and
+// currency.
+func (s *ExchangeRateStorage) GetRatesByRestaurant(restaurantID int) []model.ExchangeRate {
+	rates := make([]model.ExchangeRate, 0)
+	for _, rate := range s.Rates {
+		if rate.RestaurantID == restaurantID {
+			rates = append(rates, rate)
+		}
+	}
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		if rates[i].Base != rates[j].Base {
+			return rates[i].Base < rates[j].Base
+		}
+		return rates[i].Currency < rates[j].Currency
+	})
+	return rates
+}
+
+// SetRate adds the rate or replaces the one for the same currency pair.
+func (s *ExchangeRateStorage) SetRate(rate model.ExchangeRate) model.ExchangeRate {
+	if existing, exists := s.GetRate(rate.RestaurantID, rate.Base, rate.Currency); exists {
+		*existing = rate
+	} else {
+		s.Rates = append(s.Rates, rate)
+	}
+	log.Printf("Exchange rate set: RestaurantID=%d, %s->%s=%s", rate.RestaurantID, rate.Base, rate.Currency, rate.Rate)
+	return rate
+}
+
+func (s *ExchangeRateStorage) DeleteRate(restaurantID int, base, currency string) bool {
+	for i, rate := range s.Rates {
+		if rate.RestaurantID == restaurantID && rate.Base == base && rate.Currency == currency {
+			s.Rates = append(s.Rates[:i], s.Rates[i+1:]...)
+			log.Printf("Exchange rate deleted: RestaurantID=%d, %s->%s", restaurantID, base, currency)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ExchangeRateStorage) Snapshot() func() {
+	saved := make([]model.ExchangeRate, len(s.Rates))
+	copy(saved, s.Rates)
+	return func() {
+		s.Rates = saved
+	}
+}
+
+func (s *ExchangeRateStorage) Reset() {
+	s.Rates = make([]model.ExchangeRate, 0)
+	log.Println("Exchange rate storage reset")
+}
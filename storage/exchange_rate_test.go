@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"restaurant/model"
+	"restaurant/money"
+	"testing"
+)
+
+func TestSetRateReplacesCurrencyPair(t *testing.T) {
+	rates := &ExchangeRateStorage{}
+	first, _ := money.ParseRate("0.90")
+	second, _ := money.ParseRate("0.92")
+
+	rates.SetRate(model.ExchangeRate{RestaurantID: 1, Base: "USD", Currency: "EUR", Rate: first})
+	rates.SetRate(model.ExchangeRate{RestaurantID: 2, Base: "USD", Currency: "EUR", Rate: first})
+	rates.SetRate(model.ExchangeRate{RestaurantID: 1, Base: "USD", Currency: "EUR", Rate: second})
+
+	if rate, exists := rates.GetRate(1, "USD", "EUR"); !exists || rate.Rate.String() != "0.92" {
+		t.Errorf("Expected the rate to be replaced with 0.92, but got %+v", rate)
+	}
+	if rate, exists := rates.GetRate(2, "USD", "EUR"); !exists || rate.Rate.String() != "0.9" {
+		t.Errorf("Expected the other restaurant to keep 0.9, but got %+v", rate)
+	}
+	if len(rates.GetRatesByRestaurant(1)) != 1 {
+		t.Errorf("Expected one rate for restaurant 1, but got %d", len(rates.GetRatesByRestaurant(1)))
+	}
+
+	if !rates.DeleteRate(1, "USD", "EUR") || rates.DeleteRate(1, "USD", "EUR") {
+		t.Errorf("Expected the rate to be deleted exactly once")
+	}
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"restaurant/model"
+)
+
+var (
+	ErrIngredientNotFound      = errors.New("ingredient not found")
+	ErrInsufficientIngredients = errors.New("insufficient ingredients")
+)
+
+type IngredientStorage struct {
+	Ingredients []model.Ingredient
+}
+
+var ingredientStorage *IngredientStorage
+
+func init() {
+	ingredientStorage = &IngredientStorage{
+		Ingredients: make([]model.Ingredient, 0),
+	}
+	log.Println("Ingredient storage initialized with empty ingredient list")
+}
+
+func NewIngredientStorage() *IngredientStorage {
+	return ingredientStorage
+}
+
+func (s *IngredientStorage) GetIngredientByID(restaurantID, id int) (*model.Ingredient, bool) {
+	for i := range s.Ingredients {
+		if s.Ingredients[i].ID == id && s.Ingredients[i].RestaurantID == restaurantID {
+			return &s.Ingredients[i], true
+		}
+	}
+	return nil, false
+}
+
+func (s *IngredientStorage) GetIngredientsByRestaurant(restaurantID int) []model.Ingredient {
+	ingredients := make([]model.Ingredient, 0)
+	for _, ingredient := range s.Ingredients {
+		if ingredient.RestaurantID == restaurantID {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	return ingredients
+}
+
+func (s *IngredientStorage) AddIngredient(ingredient model.Ingredient) {
+	s.Ingredients = append(s.Ingredients, ingredient)
+	log.Printf("Ingredient added: ID=%d, RestaurantID=%d, Name=%s", ingredient.ID, ingredient.RestaurantID, ingredient.Name)
+}
+
+// UpdateIngredient replaces the ingredient's details. Stock is kept; it only
+// changes through AdjustStock and ConsumeRecipe.
+func (s *IngredientStorage) UpdateIngredient(restaurantID, id int, ingredient model.Ingredient) bool {
+	for i := range s.Ingredients {
+		if s.Ingredients[i].ID == id && s.Ingredients[i].RestaurantID == restaurantID {
+			ingredient.ID = id
+			ingredient.RestaurantID = restaurantID
+			ingredient.Stock = s.Ingredients[i].Stock
+			s.Ingredients[i] = ingredient
+			log.Printf("Ingredient updated: ID=%d, Name=%s", id, ingredient.Name)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *IngredientStorage) DeleteIngredient(restaurantID, id int) bool {
+	for i := range s.Ingredients {
+		if s.Ingredients[i].ID == id && s.Ingredients[i].RestaurantID == restaurantID {
+			s.Ingredients = append(s.Ingredients[:i], s.Ingredients[i+1:]...)
+			log.Printf("Ingredient deleted: ID=%d", id)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *IngredientStorage) AdjustStock(restaurantID, id int, change float64) (*model.Ingredient, error) {
+	ingredient, exists := s.GetIngredientByID(restaurantID, id)
+	if !exists {
+		return nil, ErrIngredientNotFound
+	}
+	if ingredient.Stock+change < 0 {
+		return nil, ErrInsufficientIngredients
+	}
+
+	ingredient.Stock += change
+	log.Printf("Ingredient stock adjusted: ID=%d, Change=%.2f, Stock=%.2f", id, change, ingredient.Stock)
+	return ingredient, nil
+}
+
+// CanMake reports whether there is enough of every ingredient for the given
+// number of portions of a recipe.
+func (s *IngredientStorage) CanMake(restaurantID int, recipe []model.RecipeItem, portions int) bool {
+	for _, item := range recipe {
+		ingredient, exists := s.GetIngredientByID(restaurantID, item.IngredientID)
+		if !exists || ingredient.Stock < item.Quantity*float64(portions) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReturnIngredients puts back quantities taken by ConsumeRecipe. Ingredients
+// deleted in the meantime are skipped.
+func (s *IngredientStorage) ReturnIngredients(restaurantID int, items []model.RecipeItem) {
+	for _, item := range items {
+		if ingredient, exists := s.GetIngredientByID(restaurantID, item.IngredientID); exists {
+			ingredient.Stock += item.Quantity
+		}
+	}
+}
+
+// ConsumeRecipe deducts the ingredients for the given number of portions.
+// Nothing is deducted unless every ingredient is sufficient.
+func (s *IngredientStorage) ConsumeRecipe(restaurantID int, recipe []model.RecipeItem, portions int) error {
+	if !s.CanMake(restaurantID, recipe, portions) {
+		return ErrInsufficientIngredients
+	}
+
+	for _, item := range recipe {
+		ingredient, _ := s.GetIngredientByID(restaurantID, item.IngredientID)
+		ingredient.Stock -= item.Quantity * float64(portions)
+	}
+	return nil
+}
+
+// NextIngredientID returns an ID that is unique across all restaurants.
+func (s *IngredientStorage) NextIngredientID() int {
+	next := 1
+	for _, ingredient := range s.Ingredients {
+		if ingredient.ID >= next {
+			next = ingredient.ID + 1
+		}
+	}
+	return next
+}
+
+func (s *IngredientStorage) GetIngredientCount() int {
+	return len(s.Ingredients)
+}
+
+func (s *IngredientStorage) Snapshot() func() {
+	saved := make([]model.Ingredient, len(s.Ingredients))
+	copy(saved, s.Ingredients)
+	return func() {
+		s.Ingredients = saved
+	}
+}
+
+func (s *IngredientStorage) Reset() {
+	s.Ingredients = make([]model.Ingredient, 0)
+	log.Println("Ingredient storage reset")
+}
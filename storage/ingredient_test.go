@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"errors"
+	"restaurant/model"
+	"testing"
+)
+
+func TestConsumeRecipeIsAllOrNothing(t *testing.T) {
+	ingredients := &IngredientStorage{}
+	ingredients.AddIngredient(model.Ingredient{ID: 1, RestaurantID: 1, Name: "Bun", Unit: "pcs", Stock: 5})
+	ingredients.AddIngredient(model.Ingredient{ID: 2, RestaurantID: 1, Name: "Patty", Unit: "pcs", Stock: 1})
+
+	recipe := []model.RecipeItem{{IngredientID: 1, Quantity: 1}, {IngredientID: 2, Quantity: 1}}
+
+	if err := ingredients.ConsumeRecipe(1, recipe, 2); !errors.Is(err, ErrInsufficientIngredients) {
+		t.Errorf("Expected %v, but got %v", ErrInsufficientIngredients, err)
+	}
+
+	bun, _ := ingredients.GetIngredientByID(1, 1)
+	if bun.Stock != 5 {
+		t.Errorf("Expected bun stock to be untouched, but got %.2f", bun.Stock)
+	}
+
+	if err := ingredients.ConsumeRecipe(1, recipe, 1); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if bun.Stock != 4 {
+		t.Errorf("Expected bun stock 4, but got %.2f", bun.Stock)
+	}
+
+	if ingredients.CanMake(2, recipe, 1) {
+		t.Errorf("Expected ingredients of another restaurant to be unusable")
+	}
+}
+
+func TestRefreshIngredientShortagesSharedIngredient(t *testing.T) {
+	ingredients := &IngredientStorage{}
+	ingredients.AddIngredient(model.Ingredient{ID: 1, RestaurantID: 1, Name: "Cheese", Unit: "g", Stock: 150})
+
+	products := &ProductStorage{}
+	products.AddProduct(model.Product{ID: 1, RestaurantID: 1, Name: "Burger", Available: true, Recipe: []model.RecipeItem{{IngredientID: 1, Quantity: 30}}})
+	products.AddProduct(model.Product{ID: 2, RestaurantID: 1, Name: "Pizza", Available: true, Recipe: []model.RecipeItem{{IngredientID: 1, Quantity: 120}}})
+
+	canMake := func(recipe []model.RecipeItem) bool {
+		return ingredients.CanMake(1, recipe, 1)
+	}
+
+	if changed := products.RefreshIngredientShortages(1, canMake); len(changed) != 0 {
+		t.Errorf("Expected no changes, but got %v", changed)
+	}
+
+	if err := ingredients.ConsumeRecipe(1, []model.RecipeItem{{IngredientID: 1, Quantity: 30}}, 2); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	changed := products.RefreshIngredientShortages(1, canMake)
+	if len(changed) != 1 || changed[0] != 2 {
+		t.Errorf("Expected only pizza to change, but got %v", changed)
+	}
+
+	pizza, _ := products.GetProductByID(1, 2)
+	if !pizza.SoldOut || !pizza.IngredientShortage || pizza.Orderable() {
+		t.Errorf("Expected pizza to be sold out, but got %+v", pizza)
+	}
+
+	burger, _ := products.GetProductByID(1, 1)
+	if !burger.Orderable() {
+		t.Errorf("Expected burger to stay orderable, but got %+v", burger)
+	}
+
+	if _, err := ingredients.AdjustStock(1, 1, 500); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	products.RefreshIngredientShortages(1, canMake)
+	if !pizza.Orderable() {
+		t.Errorf("Expected pizza to be orderable after delivery, but got %+v", pizza)
+	}
+}
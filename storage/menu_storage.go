@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"log"
+	"restaurant/model"
+	"sort"
+)
+
+// MenuStorage keeps product changes staged for the next publish and the
+// published versions of every restaurant's menu.
+type MenuStorage struct {
+	Drafts   []model.DraftChange
+	Versions []model.MenuVersion
+}
+
+var menuStorage *MenuStorage
+
+func init() {
+	menuStorage = &MenuStorage{
+		Drafts:   make([]model.DraftChange, 0),
+		Versions: make([]model.MenuVersion, 0),
+	}
+	log.Println("Menu storage initialized with no drafts or versions")
+}
+
+func NewMenuStorage() *MenuStorage {
+	return menuStorage
+}
+
+// GetDrafts returns the restaurant's staged changes ordered by product ID.
+func (s *MenuStorage) GetDrafts(restaurantID int) []model.DraftChange {
+	drafts := make([]model.DraftChange, 0)
+	for _, draft := range s.Drafts {
+		if draft.RestaurantID == restaurantID {
+			drafts = append(drafts, draft)
+		}
+	}
+
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].ProductID < drafts[j].ProductID
+	})
+	return drafts
+}
+
+func (s *MenuStorage) GetDraft(restaurantID, productID int) (*model.DraftChange, bool) {
+	for i := range s.Drafts {
+		if s.Drafts[i].RestaurantID == restaurantID && s.Drafts[i].ProductID == productID {
+			return &s.Drafts[i], true
+		}
+	}
+	return nil, false
+}
+
+// StageDraft adds a change, replacing any change already staged for the
+// same product.
+func (s *MenuStorage) StageDraft(draft model.DraftChange) {
+	if existing, exists := s.GetDraft(draft.RestaurantID, draft.ProductID); exists {
+		*existing = draft
+	} else {
+		s.Drafts = append(s.Drafts, draft)
+	}
+	log.Printf("Draft staged: RestaurantID=%d, ProductID=%d, Action=%s", draft.RestaurantID, draft.ProductID, draft.Action)
+}
+
+func (s *MenuStorage) DiscardDraft(restaurantID, productID int) bool {
+	for i := range s.Drafts {
+		if s.Drafts[i].RestaurantID == restaurantID && s.Drafts[i].ProductID == productID {
+			s.Drafts = append(s.Drafts[:i], s.Drafts[i+1:]...)
+			log.Printf("Draft discarded: RestaurantID=%d, ProductID=%d", restaurantID, productID)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearDrafts discards every staged change of the restaurant and returns how
+// many there were.
+func (s *MenuStorage) ClearDrafts(restaurantID int) int {
+	kept := make([]model.DraftChange, 0, len(s.Drafts))
+	for _, draft := range s.Drafts {
+		if draft.RestaurantID != restaurantID {
+			kept = append(kept, draft)
+		}
+	}
+
+	cleared := len(s.Drafts) - len(kept)
+	s.Drafts = kept
+	log.Printf("Drafts cleared: RestaurantID=%d, Count=%d", restaurantID, cleared)
+	return cleared
+}
+
+// NextProductID returns an ID above every product staged for creation, so
+// new products in a draft do not collide with each other.
+func (s *MenuStorage) NextProductID() int {
+	next := 1
+	for _, draft := range s.Drafts {
+		if draft.ProductID >= next {
+			next = draft.ProductID + 1
+		}
+	}
+	return next
+}
+
+// GetVersions returns the restaurant's published versions, oldest first.
+func (s *MenuStorage) GetVersions(restaurantID int) []model.MenuVersion {
+	versions := make([]model.MenuVersion, 0)
+	for _, version := range s.Versions {
+		if version.RestaurantID == restaurantID {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+func (s *MenuStorage) GetVersion(restaurantID, version int) (*model.MenuVersion, bool) {
+	for i := range s.Versions {
+		if s.Versions[i].RestaurantID == restaurantID && s.Versions[i].Version == version {
+			return &s.Versions[i], true
+		}
+	}
+	return nil, false
+}
+
+// LiveVersion returns the number of the restaurant's latest published
+// version, or 0 if its menu has never been published.
+func (s *MenuStorage) LiveVersion(restaurantID int) int {
+	live := 0
+	for _, version := range s.Versions {
+		if version.RestaurantID == restaurantID && version.Version > live {
+			live = version.Version
+		}
+	}
+	return live
+}
+
+// AddVersion records a published version, numbering it after the
+// restaurant's live version.
+func (s *MenuStorage) AddVersion(version model.MenuVersion) model.MenuVersion {
+	version.Version = s.LiveVersion(version.RestaurantID) + 1
+	version.ProductCount = len(version.Products)
+	s.Versions = append(s.Versions, version)
+	log.Printf("Menu version published: RestaurantID=%d, Version=%d, Changes=%d", version.RestaurantID, version.Version, version.Changes)
+	return version
+}
+
+func (s *MenuStorage) Snapshot() func() {
+	drafts := make([]model.DraftChange, len(s.Drafts))
+	copy(drafts, s.Drafts)
+	versions := make([]model.MenuVersion, len(s.Versions))
+	copy(versions, s.Versions)
+	return func() {
+		s.Drafts = drafts
+		s.Versions = versions
+	}
+}
+
+func (s *MenuStorage) Reset() {
+	s.Drafts = make([]model.DraftChange, 0)
+	s.Versions = make([]model.MenuVersion, 0)
+	log.Println("Menu storage reset")
+}
@@ -22,9 +22,9 @@ func NewOrderStorage() *OrderStorage {
 	return orderStorage
 }
 
-func (s *OrderStorage) GetOrderByID(id int) (*model.Order, bool) {
+func (s *OrderStorage) GetOrderByID(restaurantID, id int) (*model.Order, bool) {
 	for i := range s.Orders {
-		if s.Orders[i].ID == id {
+		if s.Orders[i].ID == id && s.Orders[i].RestaurantID == restaurantID {
 			return &s.Orders[i], true
 		}
 	}
@@ -33,19 +33,59 @@ func (s *OrderStorage) GetOrderByID(id int) (*model.Order, bool) {
 
 func (s *OrderStorage) AddOrder(order model.Order) {
 	s.Orders = append(s.Orders, order)
-	log.Printf("Order added: ID=%d, UserID=%d, ProductID=%d", order.ID, order.UserID, order.ProductID)
+	log.Printf(
+		"Order added: ID=%d, RestaurantID=%d, UserID=%d, ProductID=%d",
+		order.ID,
+		order.RestaurantID,
+		order.UserID,
+		order.ProductID,
+	)
 }
 
-func (s *OrderStorage) GetOrdersByUserID(userID int) []model.Order {
+func (s *OrderStorage) GetOrdersByRestaurant(restaurantID int) []model.Order {
+	orders := make([]model.Order, 0)
+	for _, order := range s.Orders {
+		if order.RestaurantID == restaurantID {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+func (s *OrderStorage) GetOrdersByUserID(restaurantID, userID int) []model.Order {
 	var userOrders []model.Order
 	for _, order := range s.Orders {
-		if order.UserID == userID {
+		if order.UserID == userID && order.RestaurantID == restaurantID {
 			userOrders = append(userOrders, order)
 		}
 	}
 	return userOrders
 }
 
+// NextOrderID returns an ID that is unique across all restaurants.
+func (s *OrderStorage) NextOrderID() int {
+	next := 1
+	for _, order := range s.Orders {
+		if order.ID >= next {
+			next = order.ID + 1
+		}
+	}
+	return next
+}
+
 func (s *OrderStorage) GetOrderCount() int {
 	return len(s.Orders)
 }
+
+func (s *OrderStorage) Snapshot() func() {
+	saved := make([]model.Order, len(s.Orders))
+	copy(saved, s.Orders)
+	return func() {
+		s.Orders = saved
+	}
+}
+
+func (s *OrderStorage) Reset() {
+	s.Orders = make([]model.Order, 0)
+	log.Println("Order storage reset")
+}
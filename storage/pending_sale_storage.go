@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"log"
+	"restaurant/model"
+	"time"
+)
+
+// PendingSaleStorage keeps the sales the order service has reported but not
+// yet settled, so a sale whose order was never stored can be cancelled later.
+type PendingSaleStorage struct {
+	Sales []model.PendingSale
+}
+
+var pendingSaleStorage *PendingSaleStorage
+
+func init() {
+	pendingSaleStorage = &PendingSaleStorage{
+		Sales: make([]model.PendingSale, 0),
+	}
+	log.Println("Pending sale storage initialized with no pending sales")
+}
+
+func NewPendingSaleStorage() *PendingSaleStorage {
+	return pendingSaleStorage
+}
+
+func (s *PendingSaleStorage) AddPendingSale(sale model.PendingSale) {
+	s.Sales = append(s.Sales, sale)
+	log.Printf("Pending sale added: ID=%s, RestaurantID=%d", sale.SaleID, sale.RestaurantID)
+}
+
+func (s *PendingSaleStorage) find(saleID string) int {
+	for i := range s.Sales {
+		if s.Sales[i].SaleID == saleID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Settle removes a sale that became an order. It reports false when the sale
+// is unknown or already marked for cancellation, in which case the order
+// must not be kept.
+func (s *PendingSaleStorage) Settle(saleID string) bool {
+	i := s.find(saleID)
+	if i < 0 || s.Sales[i].CancelRequested {
+		return false
+	}
+	s.Sales = append(s.Sales[:i], s.Sales[i+1:]...)
+	return true
+}
+
+// RequestCancel marks a sale for cancellation.
+func (s *PendingSaleStorage) RequestCancel(saleID string) {
+	if i := s.find(saleID); i >= 0 {
+		s.Sales[i].CancelRequested = true
+	}
+}
+
+// Cancelled removes a sale after the product service cancelled it.
+func (s *PendingSaleStorage) Cancelled(saleID string) {
+	if i := s.find(saleID); i >= 0 {
+		s.Sales = append(s.Sales[:i], s.Sales[i+1:]...)
+		log.Printf("Pending sale cancelled: ID=%s", saleID)
+	}
+}
+
+// RecordAttempt counts a failed cancellation.
+func (s *PendingSaleStorage) RecordAttempt(saleID string) {
+	if i := s.find(saleID); i >= 0 {
+		s.Sales[i].Attempts++
+	}
+}
+
+// ToCancel returns the sales marked for cancellation together with those
+// created before staleBefore, which are marked as well: an order still
+// unsettled by then was abandoned.
+func (s *PendingSaleStorage) ToCancel(staleBefore time.Time) []model.PendingSale {
+	sales := make([]model.PendingSale, 0)
+	for i := range s.Sales {
+		if s.Sales[i].CreatedAt.Before(staleBefore) {
+			s.Sales[i].CancelRequested = true
+		}
+		if s.Sales[i].CancelRequested {
+			sales = append(sales, s.Sales[i])
+		}
+	}
+	return sales
+}
+
+func (s *PendingSaleStorage) Snapshot() func() {
+	saved := make([]model.PendingSale, len(s.Sales))
+	copy(saved, s.Sales)
+	return func() {
+		s.Sales = saved
+	}
+}
+
+func (s *PendingSaleStorage) Reset() {
+	s.Sales = make([]model.PendingSale, 0)
+	log.Println("Pending sale storage reset")
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"restaurant/model"
+	"testing"
+	"time"
+)
+
+func TestPendingSaleSettleAndCancel(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sales := &PendingSaleStorage{}
+	sales.AddPendingSale(model.PendingSale{SaleID: "settled", RestaurantID: 1, CreatedAt: now})
+	sales.AddPendingSale(model.PendingSale{SaleID: "failed", RestaurantID: 1, CreatedAt: now})
+	sales.AddPendingSale(model.PendingSale{SaleID: "abandoned", RestaurantID: 1, CreatedAt: now.Add(-time.Hour)})
+
+	if !sales.Settle("settled") {
+		t.Errorf("Expected a pending sale to settle")
+	}
+	if sales.Settle("settled") {
+		t.Errorf("Expected a settled sale not to settle twice")
+	}
+
+	sales.RequestCancel("failed")
+	if sales.Settle("failed") {
+		t.Errorf("Expected a sale marked for cancellation not to settle")
+	}
+
+	toCancel := sales.ToCancel(now.Add(-time.Minute))
+	if len(toCancel) != 2 || toCancel[0].SaleID != "failed" || toCancel[1].SaleID != "abandoned" {
+		t.Fatalf("Expected the failed and abandoned sales to be cancelled, but got %+v", toCancel)
+	}
+	if sales.Settle("abandoned") {
+		t.Errorf("Expected an abandoned sale picked for cancellation not to settle")
+	}
+
+	sales.RecordAttempt("failed")
+	sales.Cancelled("abandoned")
+	if len(sales.Sales) != 1 || sales.Sales[0].Attempts != 1 {
+		t.Errorf("Expected only the failed sale with one attempt to remain, but got %+v", sales.Sales)
+	}
+}
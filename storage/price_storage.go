@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"restaurant/model"
+	"restaurant/money"
+	"sort"
+	"time"
+)
+
+var (
+	ErrPriceChangeNotFound   = errors.New("price change not found")
+	ErrPriceChangeNotPending = errors.New("price change is not pending")
+)
+
+type PriceStorage struct {
+	Changes []model.PriceChange
+}
+
+var priceStorage *PriceStorage
+
+func init() {
+	priceStorage = &PriceStorage{
+		Changes: make([]model.PriceChange, 0),
+	}
+	log.Println("Price storage initialized with empty price history")
+}
+
+func NewPriceStorage() *PriceStorage {
+	return priceStorage
+}
+
+func (s *PriceStorage) AddChange(change model.PriceChange) model.PriceChange {
+	change.ID = len(s.Changes) + 1
+	s.Changes = append(s.Changes, change)
+	log.Printf(
+		"Price change added: ProductID=%d, NewPrice=%s, Status=%s",
+		change.ProductID,
+		change.NewPrice,
+		change.Status,
+	)
+	return change
+}
+
+func (s *PriceStorage) GetChangeByID(restaurantID, id int) (*model.PriceChange, bool) {
+	for i := range s.Changes {
+		if s.Changes[i].ID == id && s.Changes[i].RestaurantID == restaurantID {
+			return &s.Changes[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetChangesByProduct returns every change of the product, pending and
+// cancelled ones included, ordered by EffectiveAt.
+func (s *PriceStorage) GetChangesByProduct(restaurantID, productID int) []model.PriceChange {
+	changes := make([]model.PriceChange, 0)
+	for _, change := range s.Changes {
+		if change.RestaurantID == restaurantID && change.ProductID == productID {
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].EffectiveAt.Before(changes[j].EffectiveAt)
+	})
+	return changes
+}
+
+// Cancel withdraws a pending change.
+func (s *PriceStorage) Cancel(restaurantID, id int) (*model.PriceChange, error) {
+	change, exists := s.GetChangeByID(restaurantID, id)
+	if !exists {
+		return nil, ErrPriceChangeNotFound
+	}
+	if change.Status != model.PriceChangePending {
+		return nil, ErrPriceChangeNotPending
+	}
+
+	change.Status = model.PriceChangeCancelled
+	log.Printf("Price change cancelled: ID=%d", id)
+	return change, nil
+}
+
+// Due returns the pending changes of all restaurants whose time has come,
+// oldest first.
+func (s *PriceStorage) Due(now time.Time) []model.PriceChange {
+	due := make([]model.PriceChange, 0)
+	for _, change := range s.Changes {
+		if change.Status == model.PriceChangePending && !change.EffectiveAt.After(now) {
+			due = append(due, change)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].EffectiveAt.Before(due[j].EffectiveAt)
+	})
+	return due
+}
+
+// MarkApplied records that a pending change took effect at appliedAt,
+// replacing the old price.
+func (s *PriceStorage) MarkApplied(restaurantID, id int, oldPrice money.Money, appliedAt time.Time) (*model.PriceChange, error) {
+	change, exists := s.GetChangeByID(restaurantID, id)
+	if !exists {
+		return nil, ErrPriceChangeNotFound
+	}
+	if change.Status != model.PriceChangePending {
+		return nil, ErrPriceChangeNotPending
+	}
+
+	scheduledFor := change.EffectiveAt
+	change.ScheduledFor = &scheduledFor
+	change.EffectiveAt = appliedAt
+	change.OldPrice = oldPrice
+	change.Status = model.PriceChangeApplied
+	log.Printf("Price change applied: ID=%d, ProductID=%d, Price=%s", id, change.ProductID, change.NewPrice)
+	return change, nil
+}
+
+// PriceAt returns the product's price at t given its current price. The last
+// change applied at or before t wins; before the first recorded change the
+// product had that change's old price.
+func (s *PriceStorage) PriceAt(restaurantID, productID int, t time.Time, current money.Money) money.Money {
+	price := current
+	var earliestAfter *model.PriceChange
+	var latestBefore *model.PriceChange
+
+	for i := range s.Changes {
+		change := &s.Changes[i]
+		if change.RestaurantID != restaurantID || change.ProductID != productID || change.Status != model.PriceChangeApplied {
+			continue
+		}
+		if change.EffectiveAt.After(t) {
+			if earliestAfter == nil || change.EffectiveAt.Before(earliestAfter.EffectiveAt) {
+				earliestAfter = change
+			}
+		} else if latestBefore == nil || !change.EffectiveAt.Before(latestBefore.EffectiveAt) {
+			latestBefore = change
+		}
+	}
+
+	switch {
+	case latestBefore != nil:
+		price = latestBefore.NewPrice
+	case earliestAfter != nil:
+		price = earliestAfter.OldPrice
+	}
+	return price
+}
+
+func (s *PriceStorage) Snapshot() func() {
+	saved := make([]model.PriceChange, len(s.Changes))
+	copy(saved, s.Changes)
+	return func() {
+		s.Changes = saved
+	}
+}
+
+func (s *PriceStorage) Reset() {
+	s.Changes = make([]model.PriceChange, 0)
+	log.Println("Price storage reset")
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"restaurant/model"
+	"restaurant/money"
+	"testing"
+	"time"
+)
+
+func TestPriceAt(t *testing.T) {
+	prices := &PriceStorage{}
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	prices.AddChange(model.PriceChange{RestaurantID: 1, ProductID: 1, OldPrice: money.MustParse("10.00", "USD"), NewPrice: money.MustParse("12.00", "USD"), Status: model.PriceChangeApplied, EffectiveAt: start})
+	prices.AddChange(model.PriceChange{RestaurantID: 1, ProductID: 1, OldPrice: money.MustParse("12.00", "USD"), NewPrice: money.MustParse("11.00", "USD"), Status: model.PriceChangeApplied, EffectiveAt: start.Add(48 * time.Hour)})
+	prices.AddChange(model.PriceChange{RestaurantID: 1, ProductID: 1, NewPrice: money.MustParse("99.00", "USD"), Status: model.PriceChangeCancelled, EffectiveAt: start.Add(24 * time.Hour)})
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected string
+	}{
+		{name: "before history", at: start.Add(-time.Hour), expected: "10.00 USD"},
+		{name: "at first change", at: start, expected: "12.00 USD"},
+		{name: "cancelled change ignored", at: start.Add(30 * time.Hour), expected: "12.00 USD"},
+		{name: "after last change", at: start.Add(72 * time.Hour), expected: "11.00 USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if price := prices.PriceAt(1, 1, tt.at, money.MustParse("11.00", "USD")); price.String() != tt.expected {
+				t.Errorf("Expected price %s, but got %s", tt.expected, price)
+			}
+		})
+	}
+
+	if price := prices.PriceAt(1, 2, start, money.MustParse("7.00", "USD")); price.String() != "7.00 USD" {
+		t.Errorf("Expected product without history to keep its current price, but got %s", price)
+	}
+}
+
+func TestApplyDuePriceChange(t *testing.T) {
+	prices := &PriceStorage{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	due := prices.AddChange(model.PriceChange{RestaurantID: 1, ProductID: 1, NewPrice: money.MustParse("9.00", "USD"), Status: model.PriceChangePending, EffectiveAt: now.Add(-time.Minute)})
+	prices.AddChange(model.PriceChange{RestaurantID: 1, ProductID: 1, NewPrice: money.MustParse("8.00", "USD"), Status: model.PriceChangePending, EffectiveAt: now.Add(time.Hour)})
+
+	if changes := prices.Due(now); len(changes) != 1 || changes[0].ID != due.ID {
+		t.Fatalf("Expected only change %d to be due, but got %+v", due.ID, changes)
+	}
+
+	applied, err := prices.MarkApplied(1, due.ID, money.MustParse("10.00", "USD"), now)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if applied.OldPrice.String() != "10.00 USD" || !applied.EffectiveAt.Equal(now) || applied.ScheduledFor == nil || !applied.ScheduledFor.Equal(now.Add(-time.Minute)) {
+		t.Errorf("Expected change applied at %s, but got %+v", now, applied)
+	}
+
+	if _, err := prices.Cancel(1, due.ID); !errors.Is(err, ErrPriceChangeNotPending) {
+		t.Errorf("Expected %v, but got %v", ErrPriceChangeNotPending, err)
+	}
+	if len(prices.Due(now)) != 0 {
+		t.Errorf("Expected no more due changes")
+	}
+}
@@ -1,8 +1,22 @@
 package storage
 
 import (
+	"errors"
 	"log"
 	"restaurant/model"
+	"restaurant/money"
+	"sort"
+	"time"
+)
+
+var (
+	ErrProductNotFound    = errors.New("product not found")
+	ErrProductUnavailable = errors.New("product is unavailable")
+	ErrProductArchived    = errors.New("product is archived")
+	ErrProductNotArchived = errors.New("product is not archived")
+	ErrStockNotTracked    = errors.New("stock is not tracked for this product")
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrTranslationMissing = errors.New("translation not found")
 )
 
 type ProductStorage struct {
@@ -22,24 +36,61 @@ func NewProductStorage() *ProductStorage {
 	return productStorage
 }
 
-func (s *ProductStorage) GetProductByID(id int) (*model.Product, bool) {
+func (s *ProductStorage) GetProductByID(restaurantID, id int) (*model.Product, bool) {
+	for i := range s.Products {
+		if s.Products[i].ID == id && s.Products[i].RestaurantID == restaurantID {
+			return &s.Products[i], true
+		}
+	}
+	return nil, false
+}
+
+// GetProductBySKU looks up a product by its stock keeping unit. SKUs are
+// unique within a restaurant.
+func (s *ProductStorage) GetProductBySKU(restaurantID int, sku string) (*model.Product, bool) {
 	for i := range s.Products {
-		if s.Products[i].ID == id {
+		if s.Products[i].SKU == sku && s.Products[i].RestaurantID == restaurantID {
 			return &s.Products[i], true
 		}
 	}
 	return nil, false
 }
 
+// GetProductsByRestaurant returns the restaurant's products that are not
+// archived.
+func (s *ProductStorage) GetProductsByRestaurant(restaurantID int) []model.Product {
+	products := make([]model.Product, 0)
+	for _, product := range s.Products {
+		if product.RestaurantID == restaurantID && !product.Archived() {
+			products = append(products, product)
+		}
+	}
+	return products
+}
+
 func (s *ProductStorage) AddProduct(product model.Product) {
+	if product.Version == 0 {
+		product.Version = 1
+	}
+	product.RefreshSoldOut()
 	s.Products = append(s.Products, product)
-	log.Printf("Product added: ID=%d, Name=%s", product.ID, product.Name)
+	log.Printf("Product added: ID=%d, RestaurantID=%d, Name=%s", product.ID, product.RestaurantID, product.Name)
 }
 
-func (s *ProductStorage) UpdateProduct(id int, product model.Product) bool {
+func (s *ProductStorage) UpdateProduct(restaurantID, id int, product model.Product) bool {
 	for i := range s.Products {
-		if s.Products[i].ID == id {
-			product.ID = id 
+		if s.Products[i].ID == id && s.Products[i].RestaurantID == restaurantID {
+			product.ID = id
+			product.RestaurantID = restaurantID
+			product.Popularity = s.Products[i].Popularity
+			product.Stock = s.Products[i].Stock
+			product.Recipe = s.Products[i].Recipe
+			product.Image = s.Products[i].Image
+			product.Translations = s.Products[i].Translations
+			product.IngredientShortage = s.Products[i].IngredientShortage
+			product.ArchivedAt = s.Products[i].ArchivedAt
+			product.Version = s.Products[i].Version + 1
+			product.RefreshSoldOut()
 			s.Products[i] = product
 			log.Printf("Product updated: ID=%d, Name=%s", id, product.Name)
 			return true
@@ -48,9 +99,126 @@ func (s *ProductStorage) UpdateProduct(id int, product model.Product) bool {
 	return false
 }
 
-func (s *ProductStorage) DeleteProduct(id int) bool {
+// SetImage replaces the product's image and returns the previous one so its
+// files can be removed.
+func (s *ProductStorage) SetImage(restaurantID, id int, image *model.ProductImage) (*model.ProductImage, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	previous := product.Image
+	product.Image = image
+	product.Version++
+	log.Printf("Product image set: ID=%d", id)
+	return previous, nil
+}
+
+// SetRecipe replaces the product's recipe. Ingredient shortage is
+// re-evaluated separately by RefreshIngredientShortages.
+func (s *ProductStorage) SetRecipe(restaurantID, id int, recipe []model.RecipeItem) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	product.Recipe = recipe
+	product.Version++
+	log.Printf("Product recipe set: ID=%d, Ingredients=%d", id, len(recipe))
+	return product, nil
+}
+
+// SetTranslation adds or replaces the product's translation into language.
+func (s *ProductStorage) SetTranslation(restaurantID, id int, language string, translation model.Translation) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	product.Translations = model.WithTranslation(product.Translations, language, translation)
+	product.Version++
+	log.Printf("Product translation set: ID=%d, Language=%s", id, language)
+	return product, nil
+}
+
+func (s *ProductStorage) RemoveTranslation(restaurantID, id int, language string) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+	if _, translated := product.Translations[language]; !translated {
+		return nil, ErrTranslationMissing
+	}
+
+	product.Translations = model.WithoutTranslation(product.Translations, language)
+	product.Version++
+	log.Printf("Product translation removed: ID=%d, Language=%s", id, language)
+	return product, nil
+}
+
+// SetPrice replaces the product's price and returns the previous one.
+func (s *ProductStorage) SetPrice(restaurantID, id int, price money.Money) (money.Money, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return money.Money{}, ErrProductNotFound
+	}
+
+	previous := product.Price
+	product.Price = price
+	product.Version++
+	log.Printf("Product price set: ID=%d, Price=%s", id, price)
+	return previous, nil
+}
+
+// GetArchivedProducts returns the restaurant's archived products.
+func (s *ProductStorage) GetArchivedProducts(restaurantID int) []model.Product {
+	products := make([]model.Product, 0)
+	for _, product := range s.Products {
+		if product.RestaurantID == restaurantID && product.Archived() {
+			products = append(products, product)
+		}
+	}
+	return products
+}
+
+// ArchiveProduct takes the product off the menu while keeping it available
+// for lookups by ID, so orders that reference it still resolve.
+func (s *ProductStorage) ArchiveProduct(restaurantID, id int, at time.Time) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+	if product.Archived() {
+		return nil, ErrProductArchived
+	}
+
+	product.ArchivedAt = &at
+	product.Version++
+	log.Printf("Product archived: ID=%d", id)
+	return product, nil
+}
+
+// RestoreProduct puts an archived product back on the menu.
+func (s *ProductStorage) RestoreProduct(restaurantID, id int) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+	if !product.Archived() {
+		return nil, ErrProductNotArchived
+	}
+
+	product.ArchivedAt = nil
+	product.Version++
+	log.Printf("Product restored: ID=%d", id)
+	return product, nil
+}
+
+// DeleteProduct removes the product for good. Handlers archive products
+// instead; this is kept for maintenance and tests.
+func (s *ProductStorage) DeleteProduct(restaurantID, id int) bool {
 	for i := range s.Products {
-		if s.Products[i].ID == id {
+		if s.Products[i].ID == id && s.Products[i].RestaurantID == restaurantID {
 			s.Products = append(s.Products[:i], s.Products[i+1:]...)
 			log.Printf("Product deleted: ID=%d", id)
 			return true
@@ -59,6 +227,258 @@ func (s *ProductStorage) DeleteProduct(id int) bool {
 	return false
 }
 
+// GetProductsByCategory returns the restaurant's products assigned to a category.
+func (s *ProductStorage) GetProductsByCategory(restaurantID, categoryID int) []model.Product {
+	products := make([]model.Product, 0)
+	for _, product := range s.Products {
+		if product.RestaurantID == restaurantID && !product.Archived() && product.InCategory(categoryID) {
+			products = append(products, product)
+		}
+	}
+	return products
+}
+
+// RemoveCategory unassigns a deleted category from every product of the restaurant.
+func (s *ProductStorage) RemoveCategory(restaurantID, categoryID int) {
+	for i := range s.Products {
+		if s.Products[i].RestaurantID != restaurantID || !s.Products[i].InCategory(categoryID) {
+			continue
+		}
+
+		remaining := make([]int, 0, len(s.Products[i].CategoryIDs))
+		for _, id := range s.Products[i].CategoryIDs {
+			if id != categoryID {
+				remaining = append(remaining, id)
+			}
+		}
+		s.Products[i].CategoryIDs = remaining
+		s.Products[i].Version++
+	}
+}
+
+// AdjustStock changes tracked stock by change units and marks the product
+// sold out when stock reaches zero. Stock never goes negative.
+func (s *ProductStorage) AdjustStock(restaurantID, id, change int) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+	if !product.TrackStock {
+		return nil, ErrStockNotTracked
+	}
+	if product.Stock+change < 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	product.Stock += change
+	product.Version++
+	product.RefreshSoldOut()
+	log.Printf("Product stock adjusted: ID=%d, Change=%d, Stock=%d", id, change, product.Stock)
+	return product, nil
+}
+
+// RecordSale deducts sold units from tracked stock and adds them to the
+// product's popularity.
+func (s *ProductStorage) RecordSale(restaurantID, id, quantity int) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+	if !product.Orderable() {
+		return nil, ErrProductUnavailable
+	}
+
+	if product.TrackStock {
+		if _, err := s.AdjustStock(restaurantID, id, -quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	product.Popularity += quantity
+	product.Version++
+	log.Printf("Product sale recorded: ID=%d, Quantity=%d", id, quantity)
+	return product, nil
+}
+
+// CancelSale undoes RecordSale: the units leave the product's popularity and,
+// with restock, go back into tracked stock. The product does not have to be
+// orderable any more.
+func (s *ProductStorage) CancelSale(restaurantID, id, quantity int, restock bool) (*model.Product, error) {
+	product, exists := s.GetProductByID(restaurantID, id)
+	if !exists {
+		return nil, ErrProductNotFound
+	}
+
+	if restock && product.TrackStock {
+		if _, err := s.AdjustStock(restaurantID, id, quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	product.Popularity = max(product.Popularity-quantity, 0)
+	product.Version++
+	log.Printf("Product sale cancelled: ID=%d, Quantity=%d", id, quantity)
+	return product, nil
+}
+
+// RefreshIngredientShortages re-evaluates, for every product of the
+// restaurant with a recipe, whether canMake still allows one portion. It
+// returns the IDs of products whose sold-out state changed.
+func (s *ProductStorage) RefreshIngredientShortages(restaurantID int, canMake func(recipe []model.RecipeItem) bool) []int {
+	changed := make([]int, 0)
+	for i := range s.Products {
+		product := &s.Products[i]
+		if product.RestaurantID != restaurantID {
+			continue
+		}
+
+		wasSoldOut, wasShort := product.SoldOut, product.IngredientShortage
+		product.IngredientShortage = len(product.Recipe) > 0 && !canMake(product.Recipe)
+		product.RefreshSoldOut()
+
+		if product.IngredientShortage != wasShort {
+			product.Version++
+		}
+
+		if product.SoldOut != wasSoldOut {
+			changed = append(changed, product.ID)
+			log.Printf("Product availability changed: ID=%d, SoldOut=%v", product.ID, product.SoldOut)
+		}
+	}
+	return changed
+}
+
+// UsesIngredient reports whether any product of the restaurant has the
+// ingredient in its recipe.
+func (s *ProductStorage) UsesIngredient(restaurantID, ingredientID int) bool {
+	for _, product := range s.Products {
+		if product.RestaurantID == restaurantID && product.UsesIngredient(ingredientID) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBundleContaining returns a bundle of the restaurant, archived ones
+// included, that offers the product in one of its slots.
+func (s *ProductStorage) GetBundleContaining(restaurantID, productID int) (*model.Product, bool) {
+	for i := range s.Products {
+		product := &s.Products[i]
+		if product.RestaurantID != restaurantID || product.Bundle == nil {
+			continue
+		}
+		for _, slot := range product.Bundle.Slots {
+			if slot.Offers(productID) {
+				return product, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// GetLowStockProducts returns the restaurant's tracked products at or below
+// their low-stock threshold.
+func (s *ProductStorage) GetLowStockProducts(restaurantID int) []model.Product {
+	products := make([]model.Product, 0)
+	for _, product := range s.Products {
+		if product.RestaurantID == restaurantID && !product.Archived() && product.LowStock() {
+			products = append(products, product)
+		}
+	}
+	return products
+}
+
+// GetMenuSnapshot returns copies of all of the restaurant's products,
+// archived ones included, ordered by ID.
+func (s *ProductStorage) GetMenuSnapshot(restaurantID int) []model.Product {
+	products := make([]model.Product, 0)
+	for _, product := range s.Products {
+		if product.RestaurantID == restaurantID {
+			products = append(products, product)
+		}
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+	return products
+}
+
+// RestoreMenu puts the restaurant's products back into the state of a menu
+// snapshot. Stock, popularity, images and ingredient shortage are left as
+// they are; products created after the snapshot are archived at. It returns
+// the IDs of the products it changed.
+func (s *ProductStorage) RestoreMenu(restaurantID int, snapshot []model.Product, at time.Time) []int {
+	saved := make(map[int]model.Product, len(snapshot))
+	for _, product := range snapshot {
+		saved[product.ID] = product
+	}
+
+	changed := make([]int, 0)
+	for i := range s.Products {
+		live := &s.Products[i]
+		if live.RestaurantID != restaurantID {
+			continue
+		}
+
+		product, exists := saved[live.ID]
+		delete(saved, live.ID)
+		if !exists {
+			if !live.Archived() {
+				archivedAt := at
+				live.ArchivedAt = &archivedAt
+				live.Version++
+				changed = append(changed, live.ID)
+			}
+			continue
+		}
+
+		product.Popularity = live.Popularity
+		product.Stock = live.Stock
+		product.Image = live.Image
+		product.IngredientShortage = live.IngredientShortage
+		product.Version = live.Version + 1
+		product.RefreshSoldOut()
+		*live = product
+		changed = append(changed, live.ID)
+	}
+
+	// Products removed from the store for good come back as they were.
+	for _, product := range snapshot {
+		if _, missing := saved[product.ID]; missing {
+			s.Products = append(s.Products, product)
+			changed = append(changed, product.ID)
+		}
+	}
+
+	log.Printf("Menu restored: RestaurantID=%d, Changed=%d", restaurantID, len(changed))
+	return changed
+}
+
+// NextProductID returns an ID that is unique across all restaurants.
+func (s *ProductStorage) NextProductID() int {
+	next := 1
+	for _, product := range s.Products {
+		if product.ID >= next {
+			next = product.ID + 1
+		}
+	}
+	return next
+}
+
 func (s *ProductStorage) GetProductCount() int {
 	return len(s.Products)
 }
+
+func (s *ProductStorage) Snapshot() func() {
+	saved := make([]model.Product, len(s.Products))
+	copy(saved, s.Products)
+	return func() {
+		s.Products = saved
+	}
+}
+
+func (s *ProductStorage) Reset() {
+	s.Products = make([]model.Product, 0)
+	log.Println("Product storage reset")
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+	"restaurant/model"
+	"restaurant/money"
+	"testing"
+	"time"
+)
+
+func TestProductVersionIncreasesOnChange(t *testing.T) {
+	products := &ProductStorage{}
+	products.AddProduct(model.Product{ID: 1, RestaurantID: 1, Name: "Burger", Price: money.MustParse("10.00", "USD"), Available: true})
+
+	product, _ := products.GetProductByID(1, 1)
+	if product.Version != 1 {
+		t.Fatalf("Expected new product to have version 1, but got %d", product.Version)
+	}
+
+	products.UpdateProduct(1, 1, model.Product{Name: "Cheeseburger", Price: money.MustParse("11.00", "USD"), Available: true, Version: 99})
+	if product, _ := products.GetProductByID(1, 1); product.Version != 2 {
+		t.Errorf("Expected version 2 after update, ignoring the version sent, but got %d", product.Version)
+	}
+
+	products.SetPrice(1, 1, money.MustParse("12.00", "USD"))
+	products.RecordSale(1, 1, 1)
+	if product, _ := products.GetProductByID(1, 1); product.Version != 4 {
+		t.Errorf("Expected version 4 after a price change and a sale, but got %d", product.Version)
+	}
+}
+
+func TestUpdateProductKeepsArchiveState(t *testing.T) {
+	products := &ProductStorage{}
+	products.AddProduct(model.Product{ID: 1, RestaurantID: 1, Name: "Burger", Available: true})
+
+	archivedAt := time.Now()
+	products.UpdateProduct(1, 1, model.Product{Name: "Burger", Available: true, ArchivedAt: &archivedAt})
+	if product, _ := products.GetProductByID(1, 1); product.Archived() {
+		t.Errorf("Expected an update not to archive the product, but got archived_at %v", product.ArchivedAt)
+	}
+}
+
+func TestArchiveProductHidesItFromListings(t *testing.T) {
+	products := &ProductStorage{}
+	products.AddProduct(model.Product{ID: 1, RestaurantID: 1, Name: "Burger", CategoryIDs: []int{1}, Available: true})
+	products.AddProduct(model.Product{ID: 2, RestaurantID: 1, Name: "Pizza", CategoryIDs: []int{1}, Available: true})
+
+	archived, err := products.ArchiveProduct(1, 1, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if archived.Orderable() {
+		t.Errorf("Expected archived product not to be orderable")
+	}
+
+	if _, exists := products.GetProductByID(1, 1); !exists {
+		t.Errorf("Expected archived product to resolve by ID")
+	}
+	if listed := products.GetProductsByRestaurant(1); len(listed) != 1 || listed[0].ID != 2 {
+		t.Errorf("Expected only product 2 to be listed, but got %+v", listed)
+	}
+	if listed := products.GetProductsByCategory(1, 1); len(listed) != 1 {
+		t.Errorf("Expected archived product to be left out of its category, but got %+v", listed)
+	}
+	if listed := products.GetArchivedProducts(1); len(listed) != 1 || listed[0].ID != 1 {
+		t.Errorf("Expected product 1 to be archived, but got %+v", listed)
+	}
+
+	if _, err := products.ArchiveProduct(1, 1, time.Now()); !errors.Is(err, ErrProductArchived) {
+		t.Errorf("Expected %v, but got %v", ErrProductArchived, err)
+	}
+
+	if _, err := products.RestoreProduct(1, 1); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if _, err := products.RestoreProduct(1, 1); !errors.Is(err, ErrProductNotArchived) {
+		t.Errorf("Expected %v, but got %v", ErrProductNotArchived, err)
+	}
+	if len(products.GetProductsByRestaurant(1)) != 2 {
+		t.Errorf("Expected restored product to be listed again")
+	}
+}
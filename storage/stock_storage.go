@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"log"
+	"restaurant/model"
+	"time"
+)
+
+var ErrSaleNotFound = errors.New("sale not found")
+
+type StockStorage struct {
+	Adjustments []model.StockAdjustment
+	Sales       []model.Sale
+}
+
+var stockStorage *StockStorage
+
+func init() {
+	stockStorage = &StockStorage{
+		Adjustments: make([]model.StockAdjustment, 0),
+		Sales:       make([]model.Sale, 0),
+	}
+	log.Println("Stock storage initialized with empty adjustment history")
+}
+
+func NewStockStorage() *StockStorage {
+	return stockStorage
+}
+
+func (s *StockStorage) AddAdjustment(adjustment model.StockAdjustment) model.StockAdjustment {
+	adjustment.ID = len(s.Adjustments) + 1
+	s.Adjustments = append(s.Adjustments, adjustment)
+	log.Printf(
+		"Stock adjustment added: ProductID=%d, Change=%d, Reason=%s",
+		adjustment.ProductID,
+		adjustment.Change,
+		adjustment.Reason,
+	)
+	return adjustment
+}
+
+// GetAdjustmentsByProduct returns the product's adjustment history, oldest first.
+func (s *StockStorage) GetAdjustmentsByProduct(restaurantID, productID int) []model.StockAdjustment {
+	adjustments := make([]model.StockAdjustment, 0)
+	for _, adjustment := range s.Adjustments {
+		if adjustment.RestaurantID == restaurantID && adjustment.ProductID == productID {
+			adjustments = append(adjustments, adjustment)
+		}
+	}
+	return adjustments
+}
+
+func (s *StockStorage) GetSale(restaurantID int, id string) (*model.Sale, bool) {
+	for i := range s.Sales {
+		if s.Sales[i].ID == id && s.Sales[i].RestaurantID == restaurantID {
+			return &s.Sales[i], true
+		}
+	}
+	return nil, false
+}
+
+func (s *StockStorage) AddSale(sale model.Sale) {
+	s.Sales = append(s.Sales, sale)
+	log.Printf("Sale recorded: ID=%s, RestaurantID=%d, Items=%d", sale.ID, sale.RestaurantID, len(sale.Items))
+}
+
+// CancelSale marks a sale cancelled. Putting back what it took is up to the
+// caller.
+func (s *StockStorage) CancelSale(restaurantID int, id string, at time.Time) (*model.Sale, error) {
+	sale, exists := s.GetSale(restaurantID, id)
+	if !exists {
+		return nil, ErrSaleNotFound
+	}
+	sale.CancelledAt = at
+	log.Printf("Sale cancelled: ID=%s", id)
+	return sale, nil
+}
+
+func (s *StockStorage) Snapshot() func() {
+	saved := make([]model.StockAdjustment, len(s.Adjustments))
+	copy(saved, s.Adjustments)
+	savedSales := make([]model.Sale, len(s.Sales))
+	copy(savedSales, s.Sales)
+	return func() {
+		s.Adjustments = saved
+		s.Sales = savedSales
+	}
+}
+
+func (s *StockStorage) Reset() {
+	s.Adjustments = make([]model.StockAdjustment, 0)
+	s.Sales = make([]model.Sale, 0)
+	log.Println("Stock storage reset")
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"restaurant/model"
+	"testing"
+)
+
+func TestAdjustStockMarksSoldOut(t *testing.T) {
+	products := &ProductStorage{}
+	products.AddProduct(model.Product{ID: 1, RestaurantID: 1, Name: "Burger", Available: true, TrackStock: true, Stock: 2})
+
+	if _, err := products.RecordSale(1, 1, 3); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("Expected %v, but got %v", ErrInsufficientStock, err)
+	}
+
+	product, err := products.RecordSale(1, 1, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if product.Stock != 0 || !product.SoldOut || product.Orderable() {
+		t.Errorf("Expected product to be sold out, but got %+v", product)
+	}
+
+	if _, err := products.RecordSale(1, 1, 1); !errors.Is(err, ErrProductUnavailable) {
+		t.Errorf("Expected %v, but got %v", ErrProductUnavailable, err)
+	}
+
+	product, err = products.AdjustStock(1, 1, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if product.SoldOut || !product.Orderable() {
+		t.Errorf("Expected restocked product to be orderable, but got %+v", product)
+	}
+}
+
+func TestAdjustStockUntrackedProduct(t *testing.T) {
+	products := &ProductStorage{}
+	products.AddProduct(model.Product{ID: 1, RestaurantID: 1, Name: "Pizza", Available: true})
+
+	if _, err := products.AdjustStock(1, 1, 5); !errors.Is(err, ErrStockNotTracked) {
+		t.Errorf("Expected %v, but got %v", ErrStockNotTracked, err)
+	}
+
+	product, err := products.RecordSale(1, 1, 5)
+	if err != nil {
+		t.Fatalf("Expected untracked sale to succeed, but got %v", err)
+	}
+	if product.Popularity != 5 {
+		t.Errorf("Expected popularity 5, but got %d", product.Popularity)
+	}
+}
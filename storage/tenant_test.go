@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"restaurant/model"
+	"restaurant/money"
+	"testing"
+)
+
+func TestProductStorageIsolatesRestaurants(t *testing.T) {
+	products := &ProductStorage{Products: []model.Product{
+		{ID: 1, RestaurantID: 1, Name: "Burger", Price: money.MustParse("15.99", "USD")},
+		{ID: 2, RestaurantID: 2, Name: "Nasi Goreng", Price: money.MustParse("6.50", "USD")},
+	}}
+
+	menu := products.GetProductsByRestaurant(2)
+	if len(menu) != 1 || menu[0].ID != 2 {
+		t.Errorf("Expected restaurant 2 menu to contain only product 2, but got %+v", menu)
+	}
+
+	if _, exists := products.GetProductByID(2, 1); exists {
+		t.Errorf("Expected restaurant 2 not to read product 1 of restaurant 1")
+	}
+	if products.UpdateProduct(2, 1, model.Product{Name: "Hijacked"}) {
+		t.Errorf("Expected restaurant 2 not to update product 1 of restaurant 1")
+	}
+	if products.DeleteProduct(2, 1) {
+		t.Errorf("Expected restaurant 2 not to delete product 1 of restaurant 1")
+	}
+
+	if product, exists := products.GetProductByID(1, 1); !exists || product.Name != "Burger" {
+		t.Errorf("Expected product 1 to be unchanged, but got %+v", product)
+	}
+}
+
+func TestOrderStorageIsolatesRestaurants(t *testing.T) {
+	orders := &OrderStorage{Orders: []model.Order{
+		{ID: 1, RestaurantID: 1, UserID: 1, ProductID: 1, Quantity: 2},
+		{ID: 2, RestaurantID: 2, UserID: 1, ProductID: 2, Quantity: 1},
+	}}
+
+	if list := orders.GetOrdersByRestaurant(1); len(list) != 1 || list[0].ID != 1 {
+		t.Errorf("Expected restaurant 1 to see only order 1, but got %+v", list)
+	}
+	if _, exists := orders.GetOrderByID(1, 2); exists {
+		t.Errorf("Expected restaurant 1 not to read order 2 of restaurant 2")
+	}
+	if list := orders.GetOrdersByUserID(2, 1); len(list) != 1 || list[0].ID != 2 {
+		t.Errorf("Expected user 1 in restaurant 2 to have only order 2, but got %+v", list)
+	}
+}
+
+func TestUserStorageScopesUsernames(t *testing.T) {
+	users := &UserStorage{Users: []model.User{
+		{ID: 1, RestaurantID: 1, Username: "admin", PasswordHash: "admin123"},
+	}}
+
+	if users.UserExists(2, "admin") {
+		t.Errorf("Expected username admin to be free in restaurant 2")
+	}
+	if _, exists := users.GetUserByID(2, 1); exists {
+		t.Errorf("Expected restaurant 2 not to read user 1 of restaurant 1")
+	}
+	if next := users.NextUserID(); next != 2 {
+		t.Errorf("Expected next user ID 2, but got %d", next)
+	}
+}
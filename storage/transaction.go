@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// Tx is a unit of work that is either committed or rolled back as a whole.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// Transactor starts a new unit of work on a storage backend.
+type Transactor interface {
+	Begin() (Tx, error)
+}
+
+// Snapshotter is implemented by in-memory stores that can take part in a
+// MemoryTransactor. Snapshot captures the current state and returns a
+// function that restores it.
+type Snapshotter interface {
+	Snapshot() func()
+}
+
+// WithTransaction runs fn inside a transaction started by t. The transaction
+// is rolled back when fn returns an error or panics, and committed otherwise.
+func WithTransaction(t Transactor, fn func(tx Tx) error) (err error) {
+	tx, err := t.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// MemoryTransactor groups in-memory stores into a single unit of work.
+// Transactions are serialised, so only one can be open at a time.
+//
+// A rollback restores whole stores, so it would also undo anything written
+// to them while the transaction was open. The stores are not safe for
+// concurrent use either, so a service keeps one MemoryTransactor over all of
+// its stores, guards them with a lock of its own and only begins a
+// transaction while holding that lock exclusively.
+type MemoryTransactor struct {
+	mu     sync.Mutex
+	stores []Snapshotter
+}
+
+func NewMemoryTransactor(stores ...Snapshotter) *MemoryTransactor {
+	return &MemoryTransactor{stores: stores}
+}
+
+func (t *MemoryTransactor) Begin() (Tx, error) {
+	t.mu.Lock()
+
+	restores := make([]func(), 0, len(t.stores))
+	for _, store := range t.stores {
+		restores = append(restores, store.Snapshot())
+	}
+
+	return &memoryTx{transactor: t, restores: restores}, nil
+}
+
+type memoryTx struct {
+	transactor *MemoryTransactor
+	restores   []func()
+	done       bool
+}
+
+func (tx *memoryTx) Commit() error {
+	if tx.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	tx.done = true
+	tx.transactor.mu.Unlock()
+	return nil
+}
+
+func (tx *memoryTx) Rollback() error {
+	if tx.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	for i := len(tx.restores) - 1; i >= 0; i-- {
+		tx.restores[i]()
+	}
+	tx.done = true
+	tx.transactor.mu.Unlock()
+	return nil
+}
+
+// SQLTransactor starts transactions on a database/sql connection pool. The
+// Tx handed to WithTransaction is a *sql.Tx.
+type SQLTransactor struct {
+	DB *sql.DB
+}
+
+func NewSQLTransactor(db *sql.DB) *SQLTransactor {
+	return &SQLTransactor{DB: db}
+}
+
+func (t *SQLTransactor) Begin() (Tx, error) {
+	tx, err := t.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"restaurant/model"
+	"restaurant/money"
+	"testing"
+)
+
+func TestWithTransactionCommitsOnSuccess(t *testing.T) {
+	orders := &OrderStorage{Orders: make([]model.Order, 0)}
+	products := &ProductStorage{Products: []model.Product{{ID: 1, RestaurantID: 1, Name: "Burger", Price: money.MustParse("15.99", "USD")}}}
+
+	err := WithTransaction(NewMemoryTransactor(orders, products), func(tx Tx) error {
+		orders.AddOrder(model.Order{ID: 1, UserID: 1, ProductID: 1, Quantity: 2, TotalPrice: money.MustParse("31.98", "USD")})
+		products.UpdateProduct(1, 1, model.Product{Name: "Burger", Price: money.MustParse("16.99", "USD")})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if orders.GetOrderCount() != 1 {
+		t.Errorf("Expected 1 order, but got %d", orders.GetOrderCount())
+	}
+	if product, _ := products.GetProductByID(1, 1); product.Price.String() != "16.99 USD" {
+		t.Errorf("Expected price 16.99 USD, but got %s", product.Price)
+	}
+}
+
+func TestWithTransactionRollsBackPartialFailure(t *testing.T) {
+	orders := &OrderStorage{Orders: []model.Order{{ID: 1, UserID: 1, ProductID: 1, Quantity: 1, TotalPrice: money.MustParse("15.99", "USD")}}}
+	products := &ProductStorage{Products: []model.Product{{ID: 1, RestaurantID: 1, Name: "Burger", Price: money.MustParse("15.99", "USD")}}}
+	users := &UserStorage{Users: []model.User{{ID: 1, Username: "admin", PasswordHash: "admin123"}}}
+
+	failure := errors.New("payment declined")
+	err := WithTransaction(NewMemoryTransactor(orders, products, users), func(tx Tx) error {
+		orders.AddOrder(model.Order{ID: 2, UserID: 1, ProductID: 1, Quantity: 2, TotalPrice: money.MustParse("31.98", "USD")})
+		products.DeleteProduct(1, 1)
+		users.AddUser(model.User{ID: 2, Username: "user1", PasswordHash: "password123"})
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected error %v, but got %v", failure, err)
+	}
+
+	if orders.GetOrderCount() != 1 {
+		t.Errorf("Expected order count to be rolled back to 1, but got %d", orders.GetOrderCount())
+	}
+	if _, exists := products.GetProductByID(1, 1); !exists {
+		t.Errorf("Expected deleted product to be restored")
+	}
+	if users.GetUserCount() != 1 {
+		t.Errorf("Expected user count to be rolled back to 1, but got %d", users.GetUserCount())
+	}
+}
+
+func TestWithTransactionRollsBackOnPanic(t *testing.T) {
+	orders := &OrderStorage{Orders: make([]model.Order, 0)}
+	transactor := NewMemoryTransactor(orders)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected panic to be propagated")
+			}
+		}()
+
+		WithTransaction(transactor, func(tx Tx) error {
+			orders.AddOrder(model.Order{ID: 1, UserID: 1, ProductID: 1, Quantity: 1})
+			panic("stock service unavailable")
+		})
+	}()
+
+	if orders.GetOrderCount() != 0 {
+		t.Errorf("Expected no orders after panic, but got %d", orders.GetOrderCount())
+	}
+
+	// The transactor must be usable again after the panic released it.
+	if err := WithTransaction(transactor, func(tx Tx) error { return nil }); err != nil {
+		t.Errorf("Expected follow-up transaction to succeed, but got %v", err)
+	}
+}
+
+func TestWithTransactionSQLBackend(t *testing.T) {
+	db, err := sql.Open("fake", "")
+	if err != nil {
+		t.Fatalf("Error opening fake database: %v", err)
+	}
+	defer db.Close()
+
+	fakeState.reset()
+	if err := WithTransaction(NewSQLTransactor(db), func(tx Tx) error { return nil }); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if fakeState.commits != 1 || fakeState.rollbacks != 0 {
+		t.Errorf("Expected 1 commit and 0 rollbacks, but got %d and %d", fakeState.commits, fakeState.rollbacks)
+	}
+
+	fakeState.reset()
+	failure := errors.New("insert failed")
+	err = WithTransaction(NewSQLTransactor(db), func(tx Tx) error {
+		if _, ok := tx.(*sql.Tx); !ok {
+			t.Errorf("Expected *sql.Tx, but got %T", tx)
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected error %v, but got %v", failure, err)
+	}
+	if fakeState.commits != 0 || fakeState.rollbacks != 1 {
+		t.Errorf("Expected 0 commits and 1 rollback, but got %d and %d", fakeState.commits, fakeState.rollbacks)
+	}
+}
+
+// fakeDriver is a minimal database/sql driver that only records transaction
+// boundaries.
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeTx struct{}
+
+type fakeCounters struct {
+	commits   int
+	rollbacks int
+}
+
+var fakeState fakeCounters
+
+func init() {
+	sql.Register("fake", fakeDriver{})
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (fakeTx) Commit() error   { fakeState.commits++; return nil }
+func (fakeTx) Rollback() error { fakeState.rollbacks++; return nil }
+
+func (c *fakeCounters) reset() {
+	c.commits = 0
+	c.rollbacks = 0
+}
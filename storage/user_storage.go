@@ -22,34 +22,68 @@ func NewUserStorage() *UserStorage {
 	return userStorage
 }
 
-func (s *UserStorage) GetUserByID(id int) (*model.User, bool) {
+func (s *UserStorage) GetUserByID(restaurantID, id int) (*model.User, bool) {
 	for _, user := range s.Users {
-		if user.ID == id {
+		if user.ID == id && user.RestaurantID == restaurantID {
 			return &user, true
 		}
 	}
 	return nil, false
 }
 
-func (s *UserStorage) GetUserByUsername(username string) (*model.User, bool) {
+func (s *UserStorage) GetUserByUsername(restaurantID int, username string) (*model.User, bool) {
 	for _, user := range s.Users {
-		if user.Username == username {
+		if user.Username == username && user.RestaurantID == restaurantID {
 			return &user, true
 		}
 	}
 	return nil, false
 }
 
+func (s *UserStorage) GetUsersByRestaurant(restaurantID int) []model.User {
+	users := make([]model.User, 0)
+	for _, user := range s.Users {
+		if user.RestaurantID == restaurantID {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
 func (s *UserStorage) AddUser(user model.User) {
 	s.Users = append(s.Users, user)
-	log.Printf("User added: ID=%d, Username=%s", user.ID, user.Username)
+	log.Printf("User added: ID=%d, RestaurantID=%d, Username=%s", user.ID, user.RestaurantID, user.Username)
 }
 
-func (s *UserStorage) UserExists(username string) bool {
-	_, exists := s.GetUserByUsername(username)
+func (s *UserStorage) UserExists(restaurantID int, username string) bool {
+	_, exists := s.GetUserByUsername(restaurantID, username)
 	return exists
 }
 
+// NextUserID returns an ID that is unique across all restaurants.
+func (s *UserStorage) NextUserID() int {
+	next := 1
+	for _, user := range s.Users {
+		if user.ID >= next {
+			next = user.ID + 1
+		}
+	}
+	return next
+}
+
 func (s *UserStorage) GetUserCount() int {
 	return len(s.Users)
 }
+
+func (s *UserStorage) Snapshot() func() {
+	saved := make([]model.User, len(s.Users))
+	copy(saved, s.Users)
+	return func() {
+		s.Users = saved
+	}
+}
+
+func (s *UserStorage) Reset() {
+	s.Users = make([]model.User, 0)
+	log.Println("User storage reset")
+}
@@ -0,0 +1,139 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"restaurant/token"
+	"strconv"
+	"strings"
+)
+
+// Header names the restaurant of a request. Services send it on calls to each
+// other; clients may send it to /login and /register, which they call before
+// they have a token.
+const Header = "X-Restaurant-ID"
+
+// DefaultRestaurantID is used when a request to a public route names no
+// restaurant, which keeps single-restaurant deployments working unchanged.
+const DefaultRestaurantID = 1
+
+var (
+	ErrInvalidHeader = fmt.Errorf("invalid %s header", Header)
+	ErrMissingHeader = fmt.Errorf("service request without %s header", Header)
+	ErrMismatch      = errors.New("restaurant in header does not match token")
+	ErrNoToken       = errors.New("missing bearer token")
+	ErrNotService    = errors.New("service token required")
+)
+
+// FromRequest resolves the restaurant of a request to a protected route. It
+// requires a verified bearer token. A user token names the restaurant itself,
+// and an X-Restaurant-ID header sent along must agree with it. Only a service
+// token, which services use to call each other, lets the header name the
+// restaurant.
+func FromRequest(r *http.Request) (int, error) {
+	claims, err := bearer(r)
+	if err != nil {
+		return 0, err
+	}
+
+	headerID, err := fromHeader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if claims.Service {
+		if headerID == 0 {
+			return 0, ErrMissingHeader
+		}
+		return headerID, nil
+	}
+	if headerID != 0 && headerID != claims.RestaurantID {
+		return 0, ErrMismatch
+	}
+	return claims.RestaurantID, nil
+}
+
+// FromPublicRequest resolves the restaurant of a request to a public route.
+// A bearer token is honoured as by FromRequest; without one the
+// X-Restaurant-ID header names the restaurant, defaulting to
+// DefaultRestaurantID.
+func FromPublicRequest(r *http.Request) (int, error) {
+	if r.Header.Get("Authorization") != "" {
+		return FromRequest(r)
+	}
+
+	headerID, err := fromHeader(r)
+	if err != nil {
+		return 0, err
+	}
+	if headerID != 0 {
+		return headerID, nil
+	}
+	return DefaultRestaurantID, nil
+}
+
+// RequireService checks that a request carries a service token. Routes that
+// only other services and the admin tools may call use it.
+func RequireService(r *http.Request) error {
+	claims, err := bearer(r)
+	if err != nil {
+		return err
+	}
+	if !claims.Service {
+		return ErrNotService
+	}
+	return nil
+}
+
+// UserID returns the user of a request carrying a valid user token, or 0 for
+// anonymous and service-to-service requests.
+func UserID(r *http.Request) int {
+	claims, err := bearer(r)
+	if err != nil {
+		return 0
+	}
+	return claims.UserID
+}
+
+// Forward names the restaurant of an incoming request on an outgoing one and
+// authenticates the call with a service token. Should the token fail to
+// encode, the call goes out without it and is refused as unauthenticated.
+func Forward(outgoing *http.Request, restaurantID int) {
+	outgoing.Header.Set(Header, strconv.Itoa(restaurantID))
+	if issued, err := token.IssueService(); err == nil {
+		outgoing.Header.Set("Authorization", "Bearer "+issued)
+	}
+}
+
+// StatusCode maps an error returned by FromRequest to an HTTP status.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidHeader), errors.Is(err, ErrMissingHeader):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrMismatch), errors.Is(err, ErrNotService):
+		return http.StatusForbidden
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+func bearer(r *http.Request) (*token.Claims, error) {
+	value, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return nil, ErrNoToken
+	}
+	return token.Parse(value)
+}
+
+func fromHeader(r *http.Request) (int, error) {
+	value := r.Header.Get(Header)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidHeader
+	}
+	return id, nil
+}
@@ -0,0 +1,168 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"restaurant/token"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	restaurantTwoToken, err := token.Issue(3, 2)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v", err)
+	}
+	serviceToken, err := token.IssueService()
+	if err != nil {
+		t.Fatalf("Error issuing service token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		header        string
+		authorization string
+		expectedID    int
+		expectedCode  int
+	}{
+		{name: "no token", expectedCode: http.StatusUnauthorized},
+		{name: "header without token", header: "2", expectedCode: http.StatusUnauthorized},
+		{name: "token", authorization: "Bearer " + restaurantTwoToken, expectedID: 2},
+		{name: "token and matching header", header: "2", authorization: "Bearer " + restaurantTwoToken, expectedID: 2},
+		{name: "token and other header", header: "1", authorization: "Bearer " + restaurantTwoToken, expectedCode: http.StatusForbidden},
+		{name: "service token and header", header: "2", authorization: "Bearer " + serviceToken, expectedID: 2},
+		{name: "service token without header", authorization: "Bearer " + serviceToken, expectedCode: http.StatusBadRequest},
+		{name: "invalid header", header: "abc", authorization: "Bearer " + restaurantTwoToken, expectedCode: http.StatusBadRequest},
+		{name: "tampered token", authorization: "Bearer " + restaurantTwoToken + "x", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/product", nil)
+			if tt.header != "" {
+				request.Header.Set(Header, tt.header)
+			}
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+
+			restaurantID, err := FromRequest(request)
+			if tt.expectedCode != 0 {
+				if err == nil {
+					t.Fatalf("Expected error, but got restaurant %d", restaurantID)
+				}
+				if code := StatusCode(err); code != tt.expectedCode {
+					t.Errorf("Expected status code %d, but got %d", tt.expectedCode, code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if restaurantID != tt.expectedID {
+				t.Errorf("Expected restaurant %d, but got %d", tt.expectedID, restaurantID)
+			}
+		})
+	}
+}
+
+func TestFromPublicRequest(t *testing.T) {
+	restaurantTwoToken, err := token.Issue(3, 2)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		header        string
+		authorization string
+		expectedID    int
+		expectedCode  int
+	}{
+		{name: "default restaurant", expectedID: DefaultRestaurantID},
+		{name: "header", header: "2", expectedID: 2},
+		{name: "token", authorization: "Bearer " + restaurantTwoToken, expectedID: 2},
+		{name: "token and other header", header: "1", authorization: "Bearer " + restaurantTwoToken, expectedCode: http.StatusForbidden},
+		{name: "invalid header", header: "abc", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/login", nil)
+			if tt.header != "" {
+				request.Header.Set(Header, tt.header)
+			}
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+
+			restaurantID, err := FromPublicRequest(request)
+			if tt.expectedCode != 0 {
+				if code := StatusCode(err); err == nil || code != tt.expectedCode {
+					t.Errorf("Expected status code %d, but got restaurant %d and error %v", tt.expectedCode, restaurantID, err)
+				}
+				return
+			}
+
+			if err != nil || restaurantID != tt.expectedID {
+				t.Errorf("Expected restaurant %d, but got %d and error %v", tt.expectedID, restaurantID, err)
+			}
+		})
+	}
+}
+
+func TestRequireService(t *testing.T) {
+	userToken, err := token.Issue(3, 2)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v", err)
+	}
+	serviceToken, err := token.IssueService()
+	if err != nil {
+		t.Fatalf("Error issuing service token: %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/admin/restore", nil)
+	if err := RequireService(request); StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("Expected an anonymous request to be unauthorized, but got %v", err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+userToken)
+	if err := RequireService(request); StatusCode(err) != http.StatusForbidden {
+		t.Errorf("Expected a user token to be forbidden, but got %v", err)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+serviceToken)
+	if err := RequireService(request); err != nil {
+		t.Errorf("Expected a service token to be accepted, but got %v", err)
+	}
+}
+
+func TestForwardAuthenticatesCall(t *testing.T) {
+	outgoing := httptest.NewRequest(http.MethodGet, "/product/1", nil)
+	Forward(outgoing, 2)
+
+	restaurantID, err := FromRequest(outgoing)
+	if err != nil || restaurantID != 2 {
+		t.Errorf("Expected a forwarded request for restaurant 2, but got %d and error %v", restaurantID, err)
+	}
+	if userID := UserID(outgoing); userID != 0 {
+		t.Errorf("Expected a service request to have user 0, but got %d", userID)
+	}
+}
+
+func TestUserID(t *testing.T) {
+	issued, err := token.Issue(3, 2)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v", err)
+	}
+
+	request := httptest.NewRequest(http.MethodPut, "/product/1", nil)
+	if userID := UserID(request); userID != 0 {
+		t.Errorf("Expected anonymous request to have user 0, but got %d", userID)
+	}
+
+	request.Header.Set("Authorization", "Bearer "+issued)
+	if userID := UserID(request); userID != 3 {
+		t.Errorf("Expected user 3, but got %d", userID)
+	}
+}
@@ -0,0 +1,82 @@
+// Package thumbnail scales images down with area averaging, using only the
+// standard library.
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+)
+
+// Fit scales img down so that neither side exceeds max, keeping the aspect
+// ratio. Images that already fit are returned unchanged.
+func Fit(img image.Image, max int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= max && height <= max {
+		return img
+	}
+
+	newWidth, newHeight := max, max
+	if width > height {
+		newHeight = height * max / width
+	} else {
+		newWidth = width * max / height
+	}
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	return Resize(img, newWidth, newHeight)
+}
+
+// Resize scales img to exactly width by height. Every target pixel is the
+// average of the source pixels it covers, which avoids the aliasing of
+// nearest-neighbour sampling when shrinking.
+func Resize(img image.Image, width, height int) *image.NRGBA {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := bounds.Min.Y + (y+1)*srcHeight/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := bounds.Min.X + (x+1)*srcWidth/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					count++
+				}
+			}
+
+			// RGBA returns premultiplied 16-bit values; convert back to
+			// straight 8-bit alpha for NRGBA.
+			pixel := color.NRGBA{}
+			if a > 0 {
+				pixel.R = uint8(r * 0xff / a)
+				pixel.G = uint8(g * 0xff / a)
+				pixel.B = uint8(b * 0xff / a)
+				pixel.A = uint8(a / count >> 8)
+			}
+			dst.SetNRGBA(x, y, pixel)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,32 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFitKeepsAspectRatio(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+
+	bounds := Fit(src, 200).Bounds()
+	if bounds.Dx() != 200 || bounds.Dy() != 100 {
+		t.Errorf("Expected 200x100, but got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	small := image.NewNRGBA(image.Rect(0, 0, 50, 80))
+	if Fit(small, 200) != image.Image(small) {
+		t.Errorf("Expected an image that fits to be returned unchanged")
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	src.SetNRGBA(1, 0, color.NRGBA{B: 255, A: 255})
+
+	pixel := Resize(src, 1, 1).NRGBAAt(0, 0)
+	if pixel.R < 126 || pixel.R > 128 || pixel.B < 126 || pixel.B > 128 || pixel.A != 255 {
+		t.Errorf("Expected an even mix of red and blue, but got %+v", pixel)
+	}
+}
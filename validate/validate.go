@@ -0,0 +1,242 @@
+// Package validate checks request models against rules declared in their
+// `validate` struct tags and reports every violation by field.
+//
+// Rules are separated by commas:
+//
+//	required   strings must not be blank, other values must not be zero
+//	min=N      numbers must be at least N; strings and slices need N characters or items
+//	max=N      the upper bound counterpart of min
+//	gt=N       numbers must be greater than N
+//	oneof=a b  strings must be one of the listed values
+//
+// Nested structs, pointers to structs and slices of structs are checked as
+// well, with fields named by their JSON path such as options[0].name. Types
+// implementing Number, such as money.Money, take the numeric rules.
+//
+// A rule that cannot be checked, being unknown, missing its number or applied
+// to a field of the wrong kind, fails like a broken one, with a message
+// naming the mistake, so a bad tag rejects requests instead of crashing the
+// service.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Number is implemented by types that validate like numbers.
+type Number interface {
+	Float64() float64
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists every field that failed validation.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	return strings.Join(e.Messages(), "; ")
+}
+
+// Messages returns the errors as "field message" strings.
+func (e Errors) Messages() []string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		messages = append(messages, fieldError.Field+" "+fieldError.Message)
+	}
+	return messages
+}
+
+// Struct validates v, a struct or a pointer to one. It returns nil when
+// every rule holds.
+func Struct(v interface{}) Errors {
+	var errs Errors
+	walk(reflect.ValueOf(v), "", &errs)
+	return errs
+}
+
+// WriteErrors answers 422 Unprocessable Entity with the field errors.
+func WriteErrors(w http.ResponseWriter, errs Errors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(
+		map[string]interface{}{
+			"message": "validation failed",
+			"errors":  errs,
+		},
+	)
+}
+
+func walk(value reflect.Value, path string, errs *Errors) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		structType := value.Type()
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := fieldName(field)
+			if name == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+
+			if rules := field.Tag.Get("validate"); rules != "" {
+				check(value.Field(i), name, rules, errs)
+			}
+			walk(value.Field(i), name, errs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			walk(value.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func check(value reflect.Value, field, rules string, errs *Errors) {
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		message, err := apply(value, name, param)
+		if err != nil {
+			message = "has an invalid validation rule: " + err.Error()
+		}
+		if message != "" {
+			*errs = append(*errs, FieldError{Field: field, Rule: name, Message: message})
+		}
+	}
+}
+
+// apply returns the message for a failed rule, or "" when it holds. It
+// returns an error for a rule that cannot be checked.
+func apply(value reflect.Value, rule, param string) (string, error) {
+	switch rule {
+	case "required":
+		if value.Kind() == reflect.String {
+			if strings.TrimSpace(value.String()) == "" {
+				return "is required", nil
+			}
+		} else if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+			return "is required", nil
+		}
+
+	case "min", "max":
+		limit, err := parseParam(rule, param)
+		if err != nil {
+			return "", err
+		}
+		if size, unit, counted := length(value); counted {
+			if (rule == "min" && float64(size) < limit) || (rule == "max" && float64(size) > limit) {
+				return fmt.Sprintf("must have at %s %s %s", bound(rule), param, unit), nil
+			}
+			return "", nil
+		}
+		number, err := numeric(value, rule)
+		if err != nil {
+			return "", err
+		}
+		if (rule == "min" && number < limit) || (rule == "max" && number > limit) {
+			return fmt.Sprintf("must be at %s %s", bound(rule), param), nil
+		}
+
+	case "gt":
+		limit, err := parseParam(rule, param)
+		if err != nil {
+			return "", err
+		}
+		number, err := numeric(value, rule)
+		if err != nil {
+			return "", err
+		}
+		if number <= limit {
+			return "must be greater than " + param, nil
+		}
+
+	case "oneof":
+		if value.Kind() != reflect.String {
+			return "", fmt.Errorf("rule %q does not apply to %s", rule, value.Kind())
+		}
+		allowed := strings.Fields(param)
+		for _, candidate := range allowed {
+			if value.String() == candidate {
+				return "", nil
+			}
+		}
+		return "must be one of " + strings.Join(allowed, ", "), nil
+
+	default:
+		return "", fmt.Errorf("unknown rule %q", rule)
+	}
+	return "", nil
+}
+
+func bound(rule string) string {
+	if rule == "min" {
+		return "least"
+	}
+	return "most"
+}
+
+func length(value reflect.Value) (int, string, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), "characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return value.Len(), "items", true
+	}
+	return 0, "", false
+}
+
+func numeric(value reflect.Value, rule string) (float64, error) {
+	if value.CanInterface() {
+		if number, ok := value.Interface().(Number); ok {
+			return number.Float64(), nil
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	}
+	return 0, fmt.Errorf("rule %q does not apply to %s", rule, value.Kind())
+}
+
+func parseParam(rule, param string) (float64, error) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("rule %q needs a number, got %q", rule, param)
+	}
+	return limit, nil
+}
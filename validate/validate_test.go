@@ -0,0 +1,111 @@
+package validate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Name     string  `json:"name" validate:"required,max=5"`
+	Quantity int     `json:"quantity" validate:"gt=0"`
+	Price    float64 `json:"price" validate:"min=0"`
+}
+
+type request struct {
+	Title   string   `json:"title" validate:"required,min=3"`
+	Kind    string   `json:"kind" validate:"oneof=dine_in takeaway"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Items   []item   `json:"items" validate:"required"`
+	Primary *item    `json:"primary"`
+	ignored string
+}
+
+func TestStructValid(t *testing.T) {
+	valid := request{
+		Title: "Lunch",
+		Kind:  "takeaway",
+		Items: []item{{Name: "Soup", Quantity: 1}},
+	}
+	if errs := Struct(valid); errs != nil {
+		t.Errorf("Expected no errors, but got %v", errs)
+	}
+	if errs := Struct(&valid); errs != nil {
+		t.Errorf("Expected no errors for a pointer, but got %v", errs)
+	}
+}
+
+func TestStructReportsEveryField(t *testing.T) {
+	invalid := request{
+		Title:   " ",
+		Kind:    "delivery",
+		Tags:    []string{"a", "b", "c"},
+		Items:   []item{{Name: "Noodles", Quantity: 0, Price: -1}},
+		Primary: &item{Quantity: 1},
+	}
+
+	var fields []string
+	for _, fieldError := range Struct(invalid) {
+		fields = append(fields, fieldError.Field+":"+fieldError.Rule)
+	}
+
+	expected := []string{
+		"title:required",
+		"title:min",
+		"kind:oneof",
+		"tags:max",
+		"items[0].name:max",
+		"items[0].quantity:gt",
+		"items[0].price:min",
+		"primary.name:required",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v, but got %v", expected, fields)
+	}
+}
+
+func TestStructRequiredSlice(t *testing.T) {
+	errs := Struct(request{Title: "Lunch", Kind: "takeaway", Items: []item{}})
+	if len(errs) != 1 || errs[0].Field != "items" || errs[0].Message != "is required" {
+		t.Errorf("Expected items to be required, but got %v", errs)
+	}
+}
+
+func TestStructReportsInvalidRules(t *testing.T) {
+	type misconfigured struct {
+		Name  string `json:"name" validate:"unique"`
+		Count int    `json:"count" validate:"min=few"`
+		Kind  int    `json:"kind" validate:"oneof=1 2"`
+		Note  bool   `json:"note" validate:"gt=0"`
+	}
+
+	errs := Struct(misconfigured{})
+
+	var fields []string
+	for _, fieldError := range errs {
+		if !strings.HasPrefix(fieldError.Message, "has an invalid validation rule") {
+			t.Errorf("Expected %s to report an invalid rule, but got %q", fieldError.Field, fieldError.Message)
+		}
+		fields = append(fields, fieldError.Field+":"+fieldError.Rule)
+	}
+
+	expected := []string{"name:unique", "count:min", "kind:oneof", "note:gt"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v, but got %v", expected, fields)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	WriteErrors(recorder, Errors{{Field: "name", Rule: "required", Message: "is required"}})
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, but got %d", http.StatusUnprocessableEntity, recorder.Code)
+	}
+	expected := `{"errors":[{"field":"name","rule":"required","message":"is required"}],"message":"validation failed"}` + "\n"
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("Expected body %s, but got %s", expected, body)
+	}
+}